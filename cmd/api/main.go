@@ -1,70 +1,61 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"net/http"
+	"log/slog"
 	"os"
-	"surfe/internal/handlers"
-	"surfe/internal/repository"
-	"surfe/internal/services"
-
-	_ "surfe/docs" // This will be generated
-
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/log"
-	echoSwagger "github.com/swaggo/echo-swagger"
+	"os/signal"
+	"surfe/internal/config"
+	"surfe/internal/logging"
+	"surfe/internal/server"
+	"syscall"
 )
 
 // @title Surfe API
 // @version 1.0
 // @description API for user actions and referrals
 // @host localhost:8000
-// @BasePath /api/v1
+// @BasePath /api
 func main() {
-	if err := run(); err != nil {
-		log.Error(err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := run(ctx, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
+	if err != nil {
+		slog.Error("server stopped", slog.Any("error", err))
+	}
+	stop()
+	os.Exit(exitCode(err))
 }
 
-func run() error {
-	e := echo.New()
-
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
-
-	e.HideBanner = true
-
-	// Swagger documentation endpoint
-	e.GET("/swagger/*", echoSwagger.WrapHandler)
-
-	userRepo, err := repository.NewUserRepository("users.json")
+func run(ctx context.Context, args []string) error {
+	cfg, err := config.Load(args, os.LookupEnv)
 	if err != nil {
-		return fmt.Errorf("failed to create user repository: %v", err)
+		return fmt.Errorf("%w: %w", errUsage, err)
 	}
 
-	actionsRepo, err := repository.NewActionRepository("actions.json")
+	logger, err := logging.New(os.Stdout, cfg.Log.Level)
 	if err != nil {
-		return fmt.Errorf("failed to create action repository: %v", err)
+		return fmt.Errorf("%w: %w", errUsage, err)
 	}
+	slog.SetDefault(logger)
+	ctx = logging.NewContext(ctx, logger)
 
-	userService := services.NewUserService(userRepo, actionsRepo)
-	actionsService := services.NewActionService(actionsRepo)
-
-	userHandler := handlers.NewUserHandler(userService)
-	actionHandler := handlers.NewActionHandler(actionsService)
-
-	api := e.Group("/api")
-	v1 := api.Group("/v1")
-	v1.GET("/users/:id", userHandler.GetUserByID)
-	v1.GET("/users/:id/actions/count", userHandler.GetUserActionCount)
-	v1.GET("/actions/:type/next", actionHandler.GetNextActionProbabilities)
-	v1.GET("/actions/referral", actionHandler.GetReferralIndex)
-
-	if err := e.Start(":8000"); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("failed to start server: %v", err)
+	srv, err := server.New(ctx, cfg, logger)
+	if err != nil {
+		return err
 	}
+	defer srv.Close()
 
-	return nil
+	flushers := []flusher{srv.Actions, srv.Users}
+	if srv.Workspaces != nil {
+		flushers = append(flushers, srv.Workspaces)
+	}
+	return serve(ctx, srv.Echo, cfg.Server.Addr, cfg.Server.ShutdownTimeout, flushers...)
 }
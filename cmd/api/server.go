@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"surfe/internal/logging"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	exitOK = iota
+	exitFailure
+	exitUsage
+	exitDrainTimeout
+	exitFlushFailure
+)
+
+var (
+	errUsage        = errors.New("invalid usage")
+	errDrainTimeout = errors.New("in-flight requests were not drained before the shutdown deadline")
+	errFlush        = errors.New("failed to flush repository")
+)
+
+// flusher is implemented by repositories that hold pending writes.
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// serve runs the server until ctx is cancelled, then stops accepting
+// connections, waits up to shutdownTimeout for in-flight requests and finally
+// flushes the repositories, allowing them another shutdownTimeout. Requests
+// still running at the deadline are cut off and errDrainTimeout is returned.
+func serve(ctx context.Context, e *echo.Echo, addr string, shutdownTimeout time.Duration, flushers ...flusher) error {
+	logger := logging.FromContext(ctx)
+	logger.Info("server starting", slog.String("addr", addr))
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- e.Start(addr)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to start server: %v", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down, draining requests", slog.String("timeout", shutdownTimeout.String()))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %v", errDrainTimeout, err))
+		if err := e.Close(); err != nil {
+			logger.Error("failed to close server", slog.Any("error", err))
+		}
+	}
+	<-serveErr
+
+	// Draining may have used up shutdownCtx; pending writes get their own
+	// deadline rather than being dropped.
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelFlush()
+	for _, f := range flushers {
+		if err := f.Flush(flushCtx); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %v", errFlush, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// exitCode maps the result of run to the process exit status.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, errDrainTimeout):
+		return exitDrainTimeout
+	case errors.Is(err, errFlush):
+		return exitFlushFailure
+	}
+	return exitFailure
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubFlusher struct {
+	flushed atomic.Int32
+	err     error
+}
+
+func (f *stubFlusher) Flush(ctx context.Context) error {
+	f.flushed.Add(1)
+	return f.err
+}
+
+// startServer serves e on a random local port until the returned cancel
+// function is called. release unblocks the /slow route.
+func startServer(t *testing.T, shutdownTimeout time.Duration, flushers ...flusher) (url string, started chan struct{}, release chan struct{}, cancel context.CancelFunc, result chan error) {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+
+	started = make(chan struct{})
+	release = make(chan struct{})
+	e.GET("/slow", func(c echo.Context) error {
+		close(started)
+		<-release
+		return c.String(http.StatusOK, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Listener = listener
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result = make(chan error, 1)
+	go func() {
+		result <- serve(ctx, e, listener.Addr().String(), shutdownTimeout, flushers...)
+	}()
+	return "http://" + listener.Addr().String(), started, release, cancel, result
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	repo := &stubFlusher{}
+	url, started, release, cancel, result := startServer(t, 5*time.Second, repo)
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			t.Error(err)
+		}
+		response <- resp
+	}()
+	<-started
+
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	_, err := http.Get(url + "/slow")
+	assert.Error(t, err, "new connections must be refused while draining")
+	assert.Equal(t, int32(0), repo.flushed.Load(), "repositories must not be flushed before requests drain")
+
+	close(release)
+	resp := <-response
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	assert.NoError(t, <-result)
+	assert.Equal(t, int32(1), repo.flushed.Load())
+}
+
+func TestServe_DrainTimeout(t *testing.T) {
+	repo := &stubFlusher{}
+	url, started, release, cancel, result := startServer(t, 50*time.Millisecond, repo)
+	defer close(release)
+
+	go http.Get(url + "/slow")
+	<-started
+
+	cancel()
+	err := <-result
+
+	assert.ErrorIs(t, err, errDrainTimeout)
+	assert.Equal(t, exitDrainTimeout, exitCode(err))
+	assert.Equal(t, int32(1), repo.flushed.Load(), "repositories must be flushed even after a forced shutdown")
+}
+
+func TestServe_FlushFailure(t *testing.T) {
+	repo := &stubFlusher{err: errors.New("disk full")}
+	_, _, _, cancel, result := startServer(t, time.Second, repo)
+
+	cancel()
+	err := <-result
+
+	assert.ErrorIs(t, err, errFlush)
+	assert.Equal(t, exitFlushFailure, exitCode(err))
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitUsage, exitCode(errUsage))
+	assert.Equal(t, exitFailure, exitCode(errors.New("failed to start server")))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"surfe/internal/config"
+	"surfe/internal/generate"
+	"surfe/internal/repository"
+	"surfe/internal/server"
+)
+
+// dataset names the data the in-process server serves: existing files, or
+// a dataset generated into a temporary directory.
+type dataset struct {
+	usersPath   string
+	actionsPath string
+	generate    generate.Config
+}
+
+// inProcess is an API server running in this process on a loopback port.
+type inProcess struct {
+	url   string
+	users IDRange
+
+	srv     *server.Server
+	http    *http.Server
+	cancel  context.CancelFunc
+	tempDir string
+}
+
+// startInProcess starts the API over ds. The server is configured like the
+// API, from the SURFE_* variables looked up through lookupEnv, except for
+// the data paths, so the same variables switch features such as the cache
+// off.
+func startInProcess(ctx context.Context, ds dataset, lookupEnv func(string) (string, bool), logger *slog.Logger) (*inProcess, error) {
+	cfg, err := config.Load(nil, lookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &inProcess{cancel: cancel}
+	if ds.usersPath == "" {
+		p.tempDir, err = os.MkdirTemp("", "surfe-loadtest-")
+		if err != nil {
+			p.stop()
+			return nil, err
+		}
+		ds.usersPath = filepath.Join(p.tempDir, "users.json")
+		ds.actionsPath = filepath.Join(p.tempDir, "actions.json")
+		if err := writeDataset(ds); err != nil {
+			p.stop()
+			return nil, fmt.Errorf("failed to generate data: %w", err)
+		}
+	}
+	cfg.Data.UsersPath = ds.usersPath
+	cfg.Data.ActionsPath = ds.actionsPath
+
+	p.srv, err = server.New(ctx, cfg, logger)
+	if err != nil {
+		p.stop()
+		return nil, err
+	}
+	for _, d := range []repository.Dataset{p.srv.Users, p.srv.Actions} {
+		if status := d.Status(ctx); !status.Loaded {
+			p.stop()
+			return nil, fmt.Errorf("%s not loaded from %s: %s", status.Name, status.Source, status.Error)
+		}
+	}
+	if p.users, err = userRange(ctx, p.srv.Users); err != nil {
+		p.stop()
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		p.stop()
+		return nil, err
+	}
+	p.url = "http://" + listener.Addr().String()
+	p.http = &http.Server{Handler: p.srv.Echo}
+	go p.http.Serve(listener)
+	return p, nil
+}
+
+// stop shuts the server down and removes any generated data.
+func (p *inProcess) stop() {
+	if p.http != nil {
+		p.http.Close()
+	}
+	if p.srv != nil {
+		p.srv.Close()
+	}
+	p.cancel()
+	if p.tempDir != "" {
+		os.RemoveAll(p.tempDir)
+	}
+}
+
+func writeDataset(ds dataset) error {
+	users, err := os.Create(ds.usersPath)
+	if err != nil {
+		return err
+	}
+	defer users.Close()
+	actions, err := os.Create(ds.actionsPath)
+	if err != nil {
+		return err
+	}
+	defer actions.Close()
+
+	if _, err := generate.Write(users, actions, ds.generate); err != nil {
+		return err
+	}
+	return errors.Join(users.Close(), actions.Close())
+}
+
+// userRange returns the range spanned by the IDs of the loaded users.
+func userRange(ctx context.Context, repo repository.UserRepository) (IDRange, error) {
+	users, err := repo.GetAll(ctx)
+	if err != nil {
+		return IDRange{}, err
+	}
+	if len(users) == 0 {
+		return IDRange{}, errors.New("no users to send requests for")
+	}
+	r := IDRange{Min: users[0].ID, Max: users[0].ID}
+	for _, u := range users[1:] {
+		r.Min = min(r.Min, u.ID)
+		r.Max = max(r.Max, u.ID)
+	}
+	return r, nil
+}
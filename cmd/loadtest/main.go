@@ -0,0 +1,165 @@
+// Command loadtest drives the API with a weighted mix of requests at a target
+// rate and reports latency percentiles, throughput and error rates per
+// endpoint. It runs against a server at -url, or starts the API in-process
+// over existing or generated data.
+//
+// Usage:
+//
+//	loadtest [flags]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"surfe/internal/generate"
+	"surfe/internal/logging"
+	"syscall"
+)
+
+const (
+	exitOK = iota
+	exitFailure
+	exitUsage
+)
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.LookupEnv)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "loadtest: %v\n", err)
+	}
+	stop()
+	os.Exit(exitCode(err))
+}
+
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	}
+	return exitFailure
+}
+
+// run parses args, runs the load test and writes the report to stdout.
+// Progress goes to stderr. The in-process server's configuration is looked
+// up through lookupEnv. Interrupting ctx ends the test early with a report of
+// what was sent so far.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, lookupEnv func(string) (string, bool)) error {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: loadtest [flags]\n\nSends a weighted mix of API requests at a target rate and reports latency,\nthroughput and errors per endpoint.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	defaults := DefaultScenario()
+	scenarioPath := fs.String("scenario", "", "path to a YAML scenario file (default: a mix of user, action count, next action and referral index requests)")
+	baseURL := fs.String("url", "", "base URL of the server to test; when empty the API is started in-process")
+	duration := fs.Duration("duration", defaults.Duration, "how long to send requests for; overrides the scenario")
+	rps := fs.Float64("rps", defaults.RPS, "target requests per second; overrides the scenario")
+	concurrency := fs.Int("concurrency", defaults.Concurrency, "maximum requests in flight; overrides the scenario")
+	timeout := fs.Duration("timeout", defaults.Timeout, "per-request timeout; overrides the scenario")
+	seed := fs.Uint64("seed", 1, "random seed for the request mix and generated data")
+	format := fs.String("format", formatTable, "report format (table, json)")
+
+	gen := generate.DefaultConfig()
+	var ds dataset
+	fs.StringVar(&ds.usersPath, "users", "", "in-process: users JSON file to serve instead of generated data (requires -actions)")
+	fs.StringVar(&ds.actionsPath, "actions", "", "in-process: actions JSON file to serve instead of generated data (requires -users)")
+	fs.IntVar(&gen.Users, "user-count", gen.Users, "in-process: number of users to generate")
+	fs.IntVar(&gen.Actions, "action-count", gen.Actions, "in-process: number of actions to generate")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, fs.Arg(0))
+	}
+	if *format != formatTable && *format != formatJSON {
+		return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+	}
+	if (ds.usersPath == "") != (ds.actionsPath == "") {
+		return fmt.Errorf("%w: -users and -actions must be given together", errUsage)
+	}
+
+	s := defaults
+	if *scenarioPath != "" {
+		var err error
+		if s, err = LoadScenario(*scenarioPath); err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+	}
+	// Flags given on the command line win over the scenario file.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "duration":
+			s.Duration = *duration
+		case "rps":
+			s.RPS = *rps
+		case "concurrency":
+			s.Concurrency = *concurrency
+		case "timeout":
+			s.Timeout = *timeout
+		}
+	})
+	if err := s.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	users := s.Users
+	target := strings.TrimSuffix(*baseURL, "/")
+	if target == "" {
+		gen.Seed = *seed
+		ds.generate = gen
+		if ds.usersPath == "" {
+			if err := gen.Validate(); err != nil {
+				return fmt.Errorf("%w: %v", errUsage, err)
+			}
+			fmt.Fprintf(stderr, "generating %d users and %d actions\n", gen.Users, gen.Actions)
+		}
+
+		// Only problems are worth reporting from the server.
+		logger, err := logging.New(stderr, "warn")
+		if err != nil {
+			return err
+		}
+		ctx = logging.NewContext(ctx, logger)
+		server, err := startInProcess(ctx, ds, lookupEnv, logger)
+		if err != nil {
+			return err
+		}
+		defer server.stop()
+		target = server.url
+		if users.isZero() {
+			users = server.users
+		}
+	}
+	if users.isZero() {
+		users = defaultUsers
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		MaxIdleConns:        s.Concurrency,
+		MaxIdleConnsPerHost: s.Concurrency,
+	}}
+	defer client.CloseIdleConnections()
+
+	fmt.Fprintf(stderr, "sending %g rps to %s for %s with up to %d in flight\n", s.RPS, target, s.Duration, s.Concurrency)
+	report := runScenario(ctx, client, target, s, newPicker(s, users, *seed))
+	return report.write(stdout, *format)
+}
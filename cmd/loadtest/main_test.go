@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		expectedError    string
+		expectedExitCode int
+	}{
+		{
+			name:             "unknown format",
+			args:             []string{"-format", "csv"},
+			expectedError:    `unknown format "csv"`,
+			expectedExitCode: exitUsage,
+		},
+		{
+			name:             "users without actions",
+			args:             []string{"-users", "users.json"},
+			expectedError:    "-users and -actions must be given together",
+			expectedExitCode: exitUsage,
+		},
+		{
+			name:             "invalid scenario",
+			args:             []string{"-rps", "0"},
+			expectedError:    "rps: must be positive, got 0",
+			expectedExitCode: exitUsage,
+		},
+		{
+			name:             "missing scenario file",
+			args:             []string{"-scenario", "missing.yaml"},
+			expectedError:    "scenario file",
+			expectedExitCode: exitUsage,
+		},
+		{
+			name:             "invalid generator configuration",
+			args:             []string{"-user-count", "0"},
+			expectedError:    "invalid generator configuration",
+			expectedExitCode: exitUsage,
+		},
+		{
+			name:             "missing data files",
+			args:             []string{"-users", "missing-users.json", "-actions", "missing-actions.json"},
+			expectedError:    "users not loaded from missing-users.json",
+			expectedExitCode: exitFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			err := run(context.Background(), tt.args, &stdout, &stderr, func(string) (string, bool) { return "", false })
+
+			assert.ErrorContains(t, err, tt.expectedError)
+			assert.Equal(t, tt.expectedExitCode, exitCode(err))
+			assert.Empty(t, stdout.String())
+		})
+	}
+}
+
+func TestRun_InProcess(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-user-count", "50", "-action-count", "500", "-duration", "200ms", "-rps", "100", "-format", "json"}
+
+	err := run(context.Background(), args, &stdout, &stderr, func(string) (string, bool) { return "", false })
+
+	assert.NoError(t, err)
+	var report Report
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Len(t, report.Endpoints, 4)
+	assert.Equal(t, 20, report.Total.Requests+report.Total.Dropped)
+	assert.Zero(t, report.Total.Errors, "generated users all exist: %v", report.Statuses)
+	assert.Contains(t, stderr.String(), "generating 50 users and 500 actions")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// Report summarises a run. Latencies are in milliseconds.
+type Report struct {
+	TargetRPS float64        `json:"targetRps"`
+	Elapsed   float64        `json:"elapsedSeconds"`
+	Endpoints []Endpoint     `json:"endpoints"`
+	Total     Endpoint       `json:"total"`
+	Statuses  map[string]int `json:"statuses"`
+}
+
+// Endpoint summarises the requests of one name, or of the whole run.
+// Requests dropped at the concurrency limit are not part of Requests.
+type Endpoint struct {
+	Name       string  `json:"name"`
+	Requests   int     `json:"requests"`
+	Dropped    int     `json:"dropped"`
+	Errors     int     `json:"errors"`
+	ErrorRate  float64 `json:"errorRate"`
+	Throughput float64 `json:"throughput"`
+	P50        float64 `json:"p50Ms"`
+	P90        float64 `json:"p90Ms"`
+	P99        float64 `json:"p99Ms"`
+	Max        float64 `json:"maxMs"`
+}
+
+func (rec *recorder) report(dropped map[string]int, targetRPS float64, elapsed time.Duration) *Report {
+	r := &Report{
+		TargetRPS: targetRPS,
+		Elapsed:   elapsed.Seconds(),
+		Statuses:  make(map[string]int),
+	}
+	total := &samples{}
+	totalDropped := 0
+	for _, name := range rec.order {
+		e := rec.endpoints[name]
+		r.Endpoints = append(r.Endpoints, summarise(name, e, dropped[name], elapsed))
+		total.latencies = append(total.latencies, e.latencies...)
+		total.errors += e.errors
+		totalDropped += dropped[name]
+		for status, count := range e.statuses {
+			r.Statuses[status] += count
+		}
+	}
+	r.Total = summarise("total", total, totalDropped, elapsed)
+	return r
+}
+
+func summarise(name string, s *samples, dropped int, elapsed time.Duration) Endpoint {
+	e := Endpoint{Name: name, Requests: len(s.latencies), Dropped: dropped, Errors: s.errors}
+	if e.Requests == 0 {
+		return e
+	}
+	e.ErrorRate = float64(e.Errors) / float64(e.Requests)
+	e.Throughput = float64(e.Requests) / elapsed.Seconds()
+
+	latencies := slices.Clone(s.latencies)
+	slices.Sort(latencies)
+	e.P50 = milliseconds(percentile(latencies, 50))
+	e.P90 = milliseconds(percentile(latencies, 90))
+	e.P99 = milliseconds(percentile(latencies, 99))
+	e.Max = milliseconds(latencies[len(latencies)-1])
+	return e
+}
+
+// percentile returns the nearest-rank pth percentile of sorted, which must be
+// in ascending order and not empty.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(float64(len(sorted))*p/100+0.5) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func (r *Report) write(w io.Writer, format string) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case formatTable:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "ENDPOINT\tREQUESTS\tDROPPED\tERRORS\tERROR RATE\tRPS\tP50 MS\tP90 MS\tP99 MS\tMAX MS")
+		for _, e := range append(r.Endpoints, r.Total) {
+			fmt.Fprintln(writer, strings.Join([]string{
+				e.Name, strconv.Itoa(e.Requests), strconv.Itoa(e.Dropped), strconv.Itoa(e.Errors),
+				strconv.FormatFloat(e.ErrorRate*100, 'f', 2, 64) + "%",
+				strconv.FormatFloat(e.Throughput, 'f', 1, 64),
+				formatMilliseconds(e.P50), formatMilliseconds(e.P90), formatMilliseconds(e.P99), formatMilliseconds(e.Max),
+			}, "\t"))
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+
+		statuses := "none"
+		if len(r.Statuses) > 0 {
+			var counts []string
+			for _, status := range slices.Sorted(maps.Keys(r.Statuses)) {
+				counts = append(counts, fmt.Sprintf("%s=%d", status, r.Statuses[status]))
+			}
+			statuses = strings.Join(counts, " ")
+		}
+		_, err := fmt.Fprintf(w, "\n%.1fs at a target of %s rps; statuses: %s\n",
+			r.Elapsed, strconv.FormatFloat(r.TargetRPS, 'f', -1, 64), statuses)
+		return err
+	}
+	return fmt.Errorf("%w: unknown format %q", errUsage, format)
+}
+
+func formatMilliseconds(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 2, 64)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// result is the outcome of one request. status is 0 when no response came
+// back.
+type result struct {
+	name    string
+	status  int
+	latency time.Duration
+}
+
+// runScenario sends the scenario's requests to baseURL at the target rate
+// until the duration is over or ctx is cancelled, and waits for the requests
+// in flight.
+//
+// The load is open: arrivals follow the schedule rather than waiting for
+// responses, so a slow server shows up as growing latency and, once the
+// concurrency limit is reached, as dropped requests instead of a quietly
+// lower rate.
+func runScenario(ctx context.Context, client *http.Client, baseURL string, s *Scenario, p *picker) *Report {
+	// inFlight holds a token for every request being sent.
+	inFlight := make(chan struct{}, s.Concurrency)
+	results := make(chan result, s.Concurrency)
+	var sending sync.WaitGroup
+
+	rec := newRecorder(s.Requests)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for r := range results {
+			rec.add(r)
+		}
+	}()
+
+	// Drops are counted here, by the dispatcher, and the rest by the
+	// collector, so neither needs a lock.
+	dropped := make(map[string]int)
+	start := time.Now()
+	end := start.Add(s.Duration)
+	interval := time.Duration(float64(time.Second) / s.RPS)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+schedule:
+	for i := 0; ; i++ {
+		// Scheduling from the start time rather than the last send keeps
+		// the rate from drifting when the dispatcher falls behind.
+		at := start.Add(time.Duration(i) * interval)
+		if !at.Before(end) {
+			break
+		}
+		timer.Reset(time.Until(at))
+		select {
+		case <-ctx.Done():
+			break schedule
+		case <-timer.C:
+		}
+
+		name, path := p.next()
+		select {
+		case inFlight <- struct{}{}:
+			sending.Add(1)
+			go func() {
+				defer sending.Done()
+				results <- send(client, s.Timeout, baseURL+path, name)
+				<-inFlight
+			}()
+		default:
+			dropped[name]++
+		}
+	}
+	// The last interval counts towards the run even though nothing is sent
+	// in it.
+	timer.Reset(time.Until(end))
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	elapsed := time.Since(start)
+
+	sending.Wait()
+	close(results)
+	<-collected
+
+	return rec.report(dropped, s.RPS, elapsed)
+}
+
+// send makes one request. Requests are not tied to the run's context, so
+// those in flight when the run ends still complete and are counted.
+func send(client *http.Client, timeout time.Duration, url, name string) result {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	r := result{name: name}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return r
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		// The body is part of the response time and must be drained for
+		// the connection to be reused.
+		_, err = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	r.latency = time.Since(start)
+	if err == nil {
+		r.status = resp.StatusCode
+	}
+	return r
+}
+
+// recorder gathers results per request name.
+type recorder struct {
+	order     []string
+	endpoints map[string]*samples
+}
+
+type samples struct {
+	latencies []time.Duration
+	statuses  map[string]int
+	errors    int
+}
+
+func newRecorder(requests []Request) *recorder {
+	rec := &recorder{endpoints: make(map[string]*samples)}
+	for _, r := range requests {
+		rec.order = append(rec.order, r.Name)
+		rec.endpoints[r.Name] = &samples{statuses: make(map[string]int)}
+	}
+	return rec
+}
+
+func (rec *recorder) add(r result) {
+	e := rec.endpoints[r.name]
+	e.latencies = append(e.latencies, r.latency)
+	status := statusLabel(r.status)
+	e.statuses[status]++
+	if !successful(r.status) {
+		e.errors++
+	}
+}
+
+// successful reports whether status counts as a success. Conditional
+// requests are answered 304, so redirects count too.
+func successful(status int) bool {
+	return status >= 200 && status < 400
+}
+
+func statusLabel(status int) string {
+	if status == 0 {
+		return "failed"
+	}
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunScenario(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/slow":
+			<-release
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	tests := []struct {
+		name             string
+		requests         []Request
+		concurrency      int
+		expectedStatuses map[string]int
+		expectedErrors   int
+		expectDropped    bool
+	}{
+		{
+			name:             "successes",
+			requests:         []Request{{Name: "ok", Path: "/ok", Weight: 1}},
+			concurrency:      4,
+			expectedStatuses: map[string]int{"200": 20},
+		},
+		{
+			name:             "server errors",
+			requests:         []Request{{Name: "fail", Path: "/fail", Weight: 1}},
+			concurrency:      4,
+			expectedStatuses: map[string]int{"500": 20},
+			expectedErrors:   20,
+		},
+		{
+			name:             "timeouts and drops at the concurrency limit",
+			requests:         []Request{{Name: "slow", Path: "/slow", Weight: 1}},
+			concurrency:      1,
+			expectedStatuses: map[string]int{"failed": 1},
+			expectedErrors:   1,
+			expectDropped:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scenario{
+				// 20 requests, one every 10ms.
+				Duration:    200 * time.Millisecond,
+				RPS:         100,
+				Concurrency: tt.concurrency,
+				Timeout:     300 * time.Millisecond,
+				Requests:    tt.requests,
+			}
+
+			report := runScenario(context.Background(), server.Client(), server.URL, s, newPicker(s, defaultUsers, 1))
+
+			assert.Equal(t, tt.expectedStatuses, report.Statuses)
+			assert.Equal(t, tt.expectedErrors, report.Total.Errors)
+			assert.Equal(t, tt.expectDropped, report.Total.Dropped > 0)
+			assert.Equal(t, 20, report.Total.Requests+report.Total.Dropped)
+			assert.Len(t, report.Endpoints, 1)
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p        float64
+		sorted   []time.Duration
+		expected time.Duration
+	}{
+		{p: 50, sorted: latencies, expected: 50 * time.Millisecond},
+		{p: 99, sorted: latencies, expected: 99 * time.Millisecond},
+		{p: 100, sorted: latencies, expected: 100 * time.Millisecond},
+		{p: 0, sorted: latencies, expected: time.Millisecond},
+		{p: 99, sorted: latencies[:1], expected: time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, percentile(tt.sorted, tt.p), "p%v of %d", tt.p, len(tt.sorted))
+	}
+}
+
+func TestReport_Write(t *testing.T) {
+	rec := newRecorder([]Request{{Name: "user"}, {Name: "referral-index"}})
+	for _, r := range []result{
+		{name: "user", status: 200, latency: 2 * time.Millisecond},
+		{name: "user", status: 404, latency: 4 * time.Millisecond},
+		{name: "referral-index", status: 200, latency: 10 * time.Millisecond},
+		{name: "referral-index", latency: 30 * time.Millisecond},
+	} {
+		rec.add(r)
+	}
+	report := rec.report(map[string]int{"referral-index": 1}, 4, 2*time.Second)
+
+	var out bytes.Buffer
+	err := report.write(&out, formatTable)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ""+
+		"ENDPOINT        REQUESTS  DROPPED  ERRORS  ERROR RATE  RPS  P50 MS  P90 MS  P99 MS  MAX MS\n"+
+		"user            2         0        1       50.00%      1.0  2.00    4.00    4.00    4.00\n"+
+		"referral-index  2         1        1       50.00%      1.0  10.00   30.00   30.00   30.00\n"+
+		"total           4         1        2       50.00%      2.0  4.00    30.00   30.00   30.00\n"+
+		"\n2.0s at a target of 4 rps; statuses: 200=2 404=1 failed=1\n", out.String())
+}
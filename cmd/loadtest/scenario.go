@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"surfe/internal/actiontypes"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Path placeholders, replaced on every request.
+const (
+	userPlaceholder = "{user}"
+	typePlaceholder = "{type}"
+)
+
+// Scenario describes the traffic to send: how much, for how long, and the
+// weighted mix of requests it is made of.
+type Scenario struct {
+	Duration time.Duration `yaml:"duration"`
+	// RPS is the target arrival rate. Requests are sent on schedule whether
+	// or not earlier ones have answered; when Concurrency requests are
+	// already in flight the request is dropped and counted as such.
+	RPS         float64       `yaml:"rps"`
+	Concurrency int           `yaml:"concurrency"`
+	Timeout     time.Duration `yaml:"timeout"`
+	// Users is the range {user} is drawn from. When it is left out, the
+	// in-process server's users are used, or 1-1000 against a remote URL.
+	Users IDRange `yaml:"users"`
+	// ActionTypes are the values {type} is drawn from; the built-in action
+	// types when empty.
+	ActionTypes []string  `yaml:"actionTypes"`
+	Requests    []Request `yaml:"requests"`
+}
+
+// Request is one kind of request in a scenario's mix.
+type Request struct {
+	Name string `yaml:"name"`
+	// Path is relative to the base URL and may contain {user} and {type}.
+	Path   string `yaml:"path"`
+	Weight int    `yaml:"weight"`
+}
+
+// IDRange is an inclusive range of user IDs.
+type IDRange struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+func (r IDRange) isZero() bool {
+	return r.Min == 0 && r.Max == 0
+}
+
+// defaultUsers is the user range used against a remote server when the
+// scenario does not give one.
+var defaultUsers = IDRange{Min: 1, Max: 1000}
+
+// DefaultScenario returns the scenario used when no file is given: a mix
+// weighted towards the cheap user lookups, with the two routes that scan
+// every action making up a fifth of the traffic.
+func DefaultScenario() *Scenario {
+	return &Scenario{
+		Duration:    30 * time.Second,
+		RPS:         100,
+		Concurrency: 32,
+		Timeout:     10 * time.Second,
+		Requests: []Request{
+			{Name: "user", Path: "/api/v1/users/{user}", Weight: 4},
+			{Name: "user-action-count", Path: "/api/v1/users/{user}/actions/count", Weight: 4},
+			{Name: "next-actions", Path: "/api/v1/actions/{type}/next", Weight: 1},
+			{Name: "referral-index", Path: "/api/v1/actions/referral", Weight: 1},
+		},
+	}
+}
+
+// LoadScenario reads a scenario from a YAML file. Fields the file leaves out
+// keep the values of DefaultScenario, except requests, which replace the
+// default mix when given.
+func LoadScenario(filePath string) (*Scenario, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("scenario file: %v", err)
+	}
+	defer file.Close()
+
+	s := DefaultScenario()
+	s.Requests = nil
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(s); err != nil {
+		return nil, fmt.Errorf("scenario file %s: %v", filePath, err)
+	}
+	if len(s.Requests) == 0 {
+		s.Requests = DefaultScenario().Requests
+	}
+	return s, nil
+}
+
+// Validate reports every problem with the scenario at once.
+func (s *Scenario) Validate() error {
+	var errs []error
+	if s.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("duration: must be positive, got %s", s.Duration))
+	}
+	if s.RPS <= 0 {
+		errs = append(errs, fmt.Errorf("rps: must be positive, got %s", strconv.FormatFloat(s.RPS, 'f', -1, 64)))
+	}
+	if s.Concurrency <= 0 {
+		errs = append(errs, fmt.Errorf("concurrency: must be positive, got %d", s.Concurrency))
+	}
+	if s.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("timeout: must be positive, got %s", s.Timeout))
+	}
+	if !s.Users.isZero() && (s.Users.Min <= 0 || s.Users.Max < s.Users.Min) {
+		errs = append(errs, fmt.Errorf("users: need 0 < min <= max, got %d-%d", s.Users.Min, s.Users.Max))
+	}
+	if len(s.Requests) == 0 {
+		errs = append(errs, errors.New("requests: at least one request is required"))
+	}
+	names := make(map[string]bool)
+	for i, r := range s.Requests {
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("requests[%d].name: must not be empty", i))
+		} else if names[r.Name] {
+			errs = append(errs, fmt.Errorf("requests[%d].name: %q is used twice", i, r.Name))
+		}
+		names[r.Name] = true
+		if !strings.HasPrefix(r.Path, "/") {
+			errs = append(errs, fmt.Errorf("requests[%d].path: must start with /, got %q", i, r.Path))
+		}
+		if r.Weight <= 0 {
+			errs = append(errs, fmt.Errorf("requests[%d].weight: must be positive, got %d", i, r.Weight))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid scenario: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// picker draws requests from a scenario in proportion to their weights and
+// fills in their placeholders. It is not safe for concurrent use.
+type picker struct {
+	rng         *rand.Rand
+	requests    []Request
+	cumulative  []int
+	users       IDRange
+	actionTypes []string
+}
+
+func newPicker(s *Scenario, users IDRange, seed uint64) *picker {
+	p := &picker{
+		rng:         rand.New(rand.NewPCG(seed, 0x10ad)),
+		requests:    s.Requests,
+		users:       users,
+		actionTypes: s.ActionTypes,
+	}
+	total := 0
+	for _, r := range s.Requests {
+		total += r.Weight
+		p.cumulative = append(p.cumulative, total)
+	}
+	if len(p.actionTypes) == 0 {
+		for _, t := range actiontypes.Default().All() {
+			p.actionTypes = append(p.actionTypes, t.Name)
+		}
+	}
+	return p
+}
+
+// next returns the name of the drawn request and its path.
+func (p *picker) next() (string, string) {
+	n := p.rng.IntN(p.cumulative[len(p.cumulative)-1])
+	i := 0
+	for p.cumulative[i] <= n {
+		i++
+	}
+	r := p.requests[i]
+
+	path := r.Path
+	if strings.Contains(path, userPlaceholder) {
+		user := p.users.Min + p.rng.IntN(p.users.Max-p.users.Min+1)
+		path = strings.ReplaceAll(path, userPlaceholder, strconv.Itoa(user))
+	}
+	if strings.Contains(path, typePlaceholder) {
+		path = strings.ReplaceAll(path, typePlaceholder, p.actionTypes[p.rng.IntN(len(p.actionTypes))])
+	}
+	return r.Name, path
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadScenario(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		expectedScenario func() *Scenario
+		expectedError    string
+	}{
+		{
+			name: "overrides the defaults it sets",
+			content: `
+duration: 1m
+rps: 250
+users: {min: 10, max: 20}
+actionTypes: [WELCOME]
+requests:
+  - name: user
+    path: /api/v1/users/{user}
+    weight: 1
+`,
+			expectedScenario: func() *Scenario {
+				s := DefaultScenario()
+				s.Duration = time.Minute
+				s.RPS = 250
+				s.Users = IDRange{Min: 10, Max: 20}
+				s.ActionTypes = []string{"WELCOME"}
+				s.Requests = []Request{{Name: "user", Path: "/api/v1/users/{user}", Weight: 1}}
+				return s
+			},
+		},
+		{
+			name:             "keeps the default mix without requests",
+			content:          "concurrency: 4\n",
+			expectedScenario: func() *Scenario { s := DefaultScenario(); s.Concurrency = 4; return s },
+		},
+		{
+			name:          "unknown field",
+			content:       "rate: 10\n",
+			expectedError: "field rate not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scenario.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := LoadScenario(path)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScenario(), s)
+		})
+	}
+}
+
+func TestScenario_Validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		modify         func(s *Scenario)
+		expectedErrors []string
+	}{
+		{
+			name:   "default",
+			modify: func(s *Scenario) {},
+		},
+		{
+			name: "invalid rate and users",
+			modify: func(s *Scenario) {
+				s.RPS = 0
+				s.Concurrency = -1
+				s.Users = IDRange{Min: 5, Max: 2}
+			},
+			expectedErrors: []string{
+				"rps: must be positive, got 0",
+				"concurrency: must be positive, got -1",
+				"users: need 0 < min <= max, got 5-2",
+			},
+		},
+		{
+			name: "invalid requests",
+			modify: func(s *Scenario) {
+				s.Requests = []Request{
+					{Name: "user", Path: "/api/v1/users/1", Weight: 1},
+					{Name: "user", Path: "api/v1/users/2", Weight: 0},
+				}
+			},
+			expectedErrors: []string{
+				`requests[1].name: "user" is used twice`,
+				`requests[1].path: must start with /, got "api/v1/users/2"`,
+				"requests[1].weight: must be positive, got 0",
+			},
+		},
+		{
+			name:           "no requests",
+			modify:         func(s *Scenario) { s.Requests = nil },
+			expectedErrors: []string{"requests: at least one request is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultScenario()
+			tt.modify(s)
+
+			err := s.Validate()
+
+			if len(tt.expectedErrors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, "invalid scenario")
+			for _, expected := range tt.expectedErrors {
+				assert.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
+
+func TestPicker(t *testing.T) {
+	s := DefaultScenario()
+	s.ActionTypes = []string{"WELCOME", "CONNECT_CRM"}
+	draw := func() map[string]int {
+		p := newPicker(s, IDRange{Min: 3, Max: 5}, 7)
+		counts := make(map[string]int)
+		for range 10000 {
+			name, path := p.next()
+			counts[name]++
+			assert.NotContains(t, path, "{")
+			if strings.HasPrefix(path, "/api/v1/users/") {
+				assert.Contains(t, []string{"3", "4", "5"}, strings.Split(path, "/")[4], path)
+			}
+			if strings.HasPrefix(path, "/api/v1/actions/") && name == "next-actions" {
+				assert.Contains(t, s.ActionTypes, strings.Split(path, "/")[4], path)
+			}
+		}
+		return counts
+	}
+
+	counts := draw()
+
+	assert.Equal(t, counts, draw(), "the same seed draws the same requests")
+	// The default mix is weighted 4:4:1:1.
+	assert.InDelta(t, 4000, counts["user"], 200)
+	assert.InDelta(t, 4000, counts["user-action-count"], 200)
+	assert.InDelta(t, 1000, counts["next-actions"], 100)
+	assert.InDelta(t, 1000, counts["referral-index"], 100)
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"surfe/internal/models"
+	"surfe/internal/validate"
+	"time"
+)
+
+// defaultActivationType is the activation used by referrals quality when no
+// -activation is given, as in the API.
+const defaultActivationType = "CONNECT_CRM"
+
+// execFunc runs a command against the data with its positional arguments.
+type execFunc func(ctx context.Context, d *data, args []string) (*output, error)
+
+type command struct {
+	// name is the command and its subcommand, if any, e.g. "user get".
+	name    string
+	args    []string
+	summary string
+	// setup defines the command's own flags on fs and returns the function
+	// that runs it once they are parsed.
+	setup func(fs *flag.FlagSet) execFunc
+}
+
+func (c *command) synopsis() string {
+	var b strings.Builder
+	for _, arg := range c.args {
+		b.WriteString(" <" + arg + ">")
+	}
+	return b.String()
+}
+
+// noFlags is the setup of commands without flags of their own.
+func noFlags(exec execFunc) func(fs *flag.FlagSet) execFunc {
+	return func(fs *flag.FlagSet) execFunc { return exec }
+}
+
+var commands = []*command{
+	{
+		name:    "validate",
+		summary: "Check the users and actions files for inconsistent records",
+		setup:   noFlags(validateData),
+	},
+	{
+		name:    "generate",
+		summary: "Write a synthetic users and actions dataset",
+		setup:   generateCommand,
+	},
+	{
+		name:    "user get",
+		args:    []string{"id"},
+		summary: "Show a user",
+		setup:   noFlags(userGet),
+	},
+	{
+		name:    "user action-count",
+		args:    []string{"id"},
+		summary: "Count the actions of a user",
+		setup:   noFlags(userActionCount),
+	},
+	{
+		name:    "action-types list",
+		summary: "List the known action types",
+		setup:   noFlags(actionTypesList),
+	},
+	{
+		name:    "actions next",
+		args:    []string{"type"},
+		summary: "Show the probabilities of the actions that follow an action type",
+		setup:   noFlags(actionsNext),
+	},
+	{
+		name:    "referrals index",
+		summary: "Count the users each user referred, directly or indirectly",
+		setup:   noFlags(referralsIndex),
+	},
+	{
+		name:    "referrals tree",
+		args:    []string{"id"},
+		summary: "Show the referral tree below a user",
+		setup: func(fs *flag.FlagSet) execFunc {
+			depth := fs.Int("depth", -1, "maximum depth below the user, or -1 for no limit")
+			return func(ctx context.Context, d *data, args []string) (*output, error) {
+				return referralsTree(ctx, d, args, *depth)
+			}
+		},
+	},
+	{
+		name:    "referrals quality",
+		summary: "Show how many referred users of each referrer activated",
+		setup: func(fs *flag.FlagSet) execFunc {
+			activation := fs.String("activation", defaultActivationType, "comma-separated action types that count as activation")
+			return func(ctx context.Context, d *data, args []string) (*output, error) {
+				return referralsQuality(ctx, d, *activation)
+			}
+		},
+	},
+}
+
+// findCommand returns the command named by the leading words of args and
+// the arguments that follow its name.
+func findCommand(args []string) (*command, []string) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):]
+		}
+	}
+	return nil, nil
+}
+
+// commandsIn returns the subcommands of group.
+func commandsIn(group string) []*command {
+	var matching []*command
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd.name, group+" ") {
+			matching = append(matching, cmd)
+		}
+	}
+	return matching
+}
+
+func parseID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid user ID %q", errUsage, arg)
+	}
+	return id, nil
+}
+
+func userGet(ctx context.Context, d *data, args []string) (*output, error) {
+	id, err := parseID(args[0])
+	if err != nil {
+		return nil, err
+	}
+	service, err := d.userService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := service.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &output{
+		value:   user,
+		columns: []string{"id", "name", "created_at"},
+		rows:    [][]string{{strconv.Itoa(user.ID), user.Name, formatTime(user.CreatedAt)}},
+	}, nil
+}
+
+func userActionCount(ctx context.Context, d *data, args []string) (*output, error) {
+	id, err := parseID(args[0])
+	if err != nil {
+		return nil, err
+	}
+	service, err := d.userService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	count, err := service.GetUserActionCount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &output{
+		value:   models.ActionCount{Count: count},
+		columns: []string{"user_id", "count"},
+		rows:    [][]string{{strconv.Itoa(id), strconv.Itoa(count)}},
+	}, nil
+}
+
+func actionTypesList(ctx context.Context, d *data, args []string) (*output, error) {
+	// Types that only appear in the data are listed too, as by the API.
+	service, err := d.actionService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	types, err := service.GetActionTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := &output{
+		value:   types,
+		columns: []string{"name", "category", "targets_user", "description"},
+	}
+	for _, t := range types {
+		out.rows = append(out.rows, []string{t.Name, t.Category, strconv.FormatBool(t.TargetsUser), t.Description})
+	}
+	return out, nil
+}
+
+func actionsNext(ctx context.Context, d *data, args []string) (*output, error) {
+	service, err := d.actionService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	probabilities, err := service.GetNextActionProbabilities(ctx, strings.ToUpper(args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	next := make([]string, 0, len(probabilities))
+	for actionType := range probabilities {
+		next = append(next, actionType)
+	}
+	// Most likely first.
+	sort.Slice(next, func(i, j int) bool {
+		if probabilities[next[i]] != probabilities[next[j]] {
+			return probabilities[next[i]] > probabilities[next[j]]
+		}
+		return next[i] < next[j]
+	})
+
+	out := &output{
+		value:   models.ActionProbability(probabilities),
+		columns: []string{"next_action", "probability"},
+	}
+	for _, actionType := range next {
+		out.rows = append(out.rows, []string{actionType, formatFloat(probabilities[actionType])})
+	}
+	return out, nil
+}
+
+func referralsIndex(ctx context.Context, d *data, args []string) (*output, error) {
+	service, err := d.actionService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index, err := service.GetReferralIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int, 0, len(index))
+	for userID := range index {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Ints(userIDs)
+
+	out := &output{
+		value:   index,
+		columns: []string{"user_id", "referrals"},
+	}
+	for _, userID := range userIDs {
+		out.rows = append(out.rows, []string{strconv.Itoa(userID), strconv.Itoa(index[userID])})
+	}
+	return out, nil
+}
+
+func referralsTree(ctx context.Context, d *data, args []string, depth int) (*output, error) {
+	root, err := parseID(args[0])
+	if err != nil {
+		return nil, err
+	}
+	service, err := d.referralService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	network, err := service.GetReferralNetwork(ctx, &root, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	referrals := make(map[int]models.ReferralEdge, len(network.Edges))
+	for _, edge := range network.Edges {
+		referrals[edge.Target] = edge
+	}
+	out := &output{
+		value:   network,
+		columns: []string{"id", "name", "referred_by", "referred_at"},
+	}
+	for _, node := range network.Nodes {
+		row := []string{strconv.Itoa(node.ID), node.Name, "", ""}
+		if edge, found := referrals[node.ID]; found && node.ID != root {
+			row[2] = strconv.Itoa(edge.Source)
+			row[3] = formatTime(edge.ReferredAt)
+		}
+		out.rows = append(out.rows, row)
+	}
+	return out, nil
+}
+
+func referralsQuality(ctx context.Context, d *data, activation string) (*output, error) {
+	var activationTypes []string
+	for _, actionType := range strings.Split(activation, ",") {
+		if actionType = strings.TrimSpace(actionType); actionType != "" {
+			activationTypes = append(activationTypes, strings.ToUpper(actionType))
+		}
+	}
+	if len(activationTypes) == 0 {
+		return nil, fmt.Errorf("%w: -activation must name at least one action type", errUsage)
+	}
+
+	service, err := d.actionService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	quality, err := service.GetReferralQuality(ctx, activationTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &output{
+		value:   quality,
+		columns: []string{"referrer_id", "referred", "activated", "activation_rate", "median_activation_seconds"},
+	}
+	for _, q := range quality {
+		median := ""
+		if q.MedianActivationSeconds != nil {
+			median = formatFloat(*q.MedianActivationSeconds)
+		}
+		out.rows = append(out.rows, []string{
+			strconv.Itoa(q.ReferrerID), strconv.Itoa(q.Referred), strconv.Itoa(q.Activated),
+			formatFloat(q.ActivationRate), median,
+		})
+	}
+	return out, nil
+}
+
+func validateData(ctx context.Context, d *data, args []string) (*output, error) {
+	types, err := d.actionTypes()
+	if err != nil {
+		return nil, err
+	}
+	report, err := validate.Files(*d.usersPath, *d.actionsPath, types, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	out := &output{
+		value:   report,
+		columns: []string{"severity", "rule", "dataset", "record_id", "message"},
+	}
+	for _, f := range report.Findings {
+		out.rows = append(out.rows, []string{string(f.Severity), f.Rule, f.Dataset, strconv.Itoa(f.RecordID), f.Message})
+	}
+	if !report.OK() {
+		return out, fmt.Errorf("%w: %d error(s), %d warning(s) in %d users and %d actions",
+			errInvalidData, report.Errors, report.Warnings, report.Users, report.Actions)
+	}
+	return out, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"surfe/internal/actiontypes"
+	"surfe/internal/config"
+	"surfe/internal/repository"
+	"surfe/internal/services"
+	"sync"
+)
+
+// data opens the data files named by the command's flags on first use, so
+// each command only needs the files it reads.
+type data struct {
+	usersPath       *string
+	actionsPath     *string
+	actionTypesPath *string
+
+	types   *actiontypes.Registry
+	users   repository.UserRepository
+	actions repository.ActionRepository
+}
+
+// newData defines the data file flags on fs. Their defaults come from the
+// same environment variables and built-in values as the API server's.
+func newData(fs *flag.FlagSet, getenv func(string) string) *data {
+	defaults := config.Default()
+	fromEnv := func(name, fallback string) string {
+		if value := getenv(config.EnvName(name)); value != "" {
+			return value
+		}
+		return fallback
+	}
+	return &data{
+		usersPath:       fs.String("users", fromEnv("users", defaults.Data.UsersPath), "path to the users JSON file"),
+		actionsPath:     fs.String("actions", fromEnv("actions", defaults.Data.ActionsPath), "path to the actions JSON file"),
+		actionTypesPath: fs.String("action-types", fromEnv("action-types", defaults.Data.ActionTypesPath), "path to the action types JSON file"),
+	}
+}
+
+func (d *data) actionTypes() (*actiontypes.Registry, error) {
+	if d.types != nil {
+		return d.types, nil
+	}
+	if *d.actionTypesPath == "" {
+		d.types = actiontypes.Default()
+		return d.types, nil
+	}
+	types, err := actiontypes.Load(*d.actionTypesPath)
+	if err != nil {
+		return nil, err
+	}
+	d.types = types
+	return d.types, nil
+}
+
+func (d *data) userRepo(ctx context.Context) (repository.UserRepository, error) {
+	if d.users != nil {
+		return d.users, nil
+	}
+	users := repository.OpenUserRepository(*d.usersPath)
+	if err := users.Load(ctx); err != nil {
+		return nil, err
+	}
+	d.users = users
+	return d.users, nil
+}
+
+// actionRepo loads the actions, registering the types they use with the
+// action type registry.
+func (d *data) actionRepo(ctx context.Context) (repository.ActionRepository, error) {
+	if d.actions != nil {
+		return d.actions, nil
+	}
+	types, err := d.actionTypes()
+	if err != nil {
+		return nil, err
+	}
+	actions := repository.OpenActionRepository(*d.actionsPath, types)
+	if err := actions.Load(ctx); err != nil {
+		return nil, err
+	}
+	d.actions = actions
+	return d.actions, nil
+}
+
+func (d *data) userService(ctx context.Context) (services.UserService, error) {
+	users, err := d.userRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := d.actionRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewUserService(users, actions), nil
+}
+
+func (d *data) actionService(ctx context.Context) (services.ActionService, error) {
+	actions, err := d.actionRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Only RecordAction reads the users, which the CLI never calls, so they
+	// are left unloaded.
+	return services.NewActionService(repository.OpenUserRepository(*d.usersPath), actions, d.types, new(sync.RWMutex)), nil
+}
+
+func (d *data) referralService(ctx context.Context) (services.ReferralService, error) {
+	users, err := d.userRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := d.actionRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewReferralService(users, actions, d.types), nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"surfe/internal/generate"
+	"time"
+)
+
+// generateCommand writes a synthetic dataset to the -users and -actions
+// paths.
+func generateCommand(fs *flag.FlagSet) execFunc {
+	cfg := generate.DefaultConfig()
+	fs.IntVar(&cfg.Users, "user-count", cfg.Users, "number of users to generate")
+	fs.IntVar(&cfg.Actions, "action-count", cfg.Actions, "number of actions to generate")
+	fs.Uint64Var(&cfg.Seed, "seed", cfg.Seed, "random seed; the same seed and flags produce the same files")
+	fs.Func("start", "first signup date, as YYYY-MM-DD or RFC 3339 (default "+cfg.Start.Format(time.DateOnly)+")", dateFlag(&cfg.Start))
+	fs.Func("end", "date activity stops, as YYYY-MM-DD or RFC 3339 (default "+cfg.End.Format(time.DateOnly)+")", dateFlag(&cfg.End))
+	fs.Float64Var(&cfg.ReferredShare, "referred-share", cfg.ReferredShare, "fraction of users referred by another user")
+	fs.IntVar(&cfg.MaxDepth, "max-depth", cfg.MaxDepth, "maximum depth of the referral trees")
+	fs.Float64Var(&cfg.Preferential, "preferential", cfg.Preferential, "0 picks referrers uniformly; 1 favours users who already referred many, making wider trees")
+	force := fs.Bool("force", false, "overwrite existing files")
+
+	return func(ctx context.Context, d *data, args []string) (*output, error) {
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		var summary *generate.Summary
+		err := writeFiles(*d.usersPath, *d.actionsPath, *force, func(users, actions io.Writer) error {
+			var err error
+			summary, err = generate.Write(users, actions, cfg)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &output{
+			value:   summary,
+			columns: []string{"users", "actions", "referrals", "trees", "max_depth"},
+			rows: [][]string{{
+				strconv.Itoa(summary.Users), strconv.Itoa(summary.Actions), strconv.Itoa(summary.Referrals),
+				strconv.Itoa(summary.Trees), strconv.Itoa(summary.MaxDepth),
+			}},
+		}, nil
+	}
+}
+
+func dateFlag(t *time.Time) func(string) error {
+	return func(value string) error {
+		for _, layout := range []string{time.DateOnly, time.RFC3339} {
+			if parsed, err := time.Parse(layout, value); err == nil {
+				*t = parsed.UTC()
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid date %q", value)
+	}
+}
+
+// writeFiles calls write with temporary files next to usersPath and
+// actionsPath and renames them into place once it succeeds, so a failed run
+// leaves no partial dataset behind. Existing files are only replaced if
+// force is set.
+func writeFiles(usersPath, actionsPath string, force bool, write func(users, actions io.Writer) error) error {
+	if !force {
+		for _, path := range []string{usersPath, actionsPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%w: %s already exists (use -force to overwrite)", errUsage, path)
+			}
+		}
+	}
+
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+	for _, path := range []string{usersPath, actionsPath} {
+		file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	if err := write(files[0], files[1]); err != nil {
+		return err
+	}
+	var errs []error
+	for i, path := range []string{usersPath, actionsPath} {
+		if err := files[i].Close(); err != nil {
+			errs = append(errs, err)
+		} else if err := os.Chmod(files[i].Name(), 0644); err != nil {
+			errs = append(errs, err)
+		} else if err := os.Rename(files[i].Name(), path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
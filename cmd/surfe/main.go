@@ -0,0 +1,147 @@
+// Command surfe answers the same questions as the API straight from the JSON
+// data files, without running a server. It uses the API's services and file
+// repositories, so its answers match the API's.
+//
+// Usage:
+//
+//	surfe <command> [subcommand] [flags] [arguments]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"surfe/internal/logging"
+	"syscall"
+)
+
+const (
+	exitOK = iota
+	exitFailure
+	exitUsage
+	exitInvalidData
+)
+
+var (
+	errUsage       = errors.New("invalid usage")
+	errInvalidData = errors.New("invalid data")
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Getenv)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "surfe: %v\n", err)
+	}
+	stop()
+	os.Exit(exitCode(err))
+}
+
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, errInvalidData):
+		return exitInvalidData
+	}
+	return exitFailure
+}
+
+// run executes the command named by args, writing its result to stdout and
+// usage messages to stderr. Data file defaults are looked up through getenv.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, getenv func(string) string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		printUsage(stderr, "")
+		if len(args) == 0 {
+			return fmt.Errorf("%w: no command given", errUsage)
+		}
+		return flag.ErrHelp
+	}
+
+	// Only problems with the data are worth reporting alongside the output.
+	logger, err := logging.New(stderr, "warn")
+	if err != nil {
+		return err
+	}
+	ctx = logging.NewContext(ctx, logger)
+
+	cmd, rest := findCommand(args)
+	if cmd == nil {
+		printUsage(stderr, args[0])
+		return fmt.Errorf("%w: unknown command %q", errUsage, strings.Join(args[:min(len(args), 2)], " "))
+	}
+
+	fs := flag.NewFlagSet("surfe "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: surfe %s [flags]%s\n\n%s\n\nFlags:\n", cmd.name, cmd.synopsis(), cmd.summary)
+		fs.PrintDefaults()
+	}
+	d := newData(fs, getenv)
+	format := fs.String("format", formatTable, "output format (table, json, csv)")
+	exec := cmd.setup(fs)
+
+	positional, err := parseArgs(fs, rest)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if !validFormat(*format) {
+		return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+	}
+	if len(positional) != len(cmd.args) {
+		fs.Usage()
+		return fmt.Errorf("%w: surfe %s takes %d argument(s), got %d", errUsage, cmd.name, len(cmd.args), len(positional))
+	}
+
+	// A command may return output along with an error, such as the findings
+	// of a failed validation.
+	out, err := exec(ctx, d, positional)
+	if out != nil {
+		if writeErr := out.write(stdout, *format); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+// parseArgs parses flags wherever they appear among args, so they may follow
+// the positional arguments, and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// printUsage lists the commands, or only those in group when it names one.
+func printUsage(w io.Writer, group string) {
+	listed := commands
+	if matching := commandsIn(group); len(matching) > 0 {
+		listed = matching
+	}
+	fmt.Fprintf(w, "Usage: surfe <command> [subcommand] [flags] [arguments]\n\nCommands:\n")
+	for _, cmd := range listed {
+		fmt.Fprintf(w, "  %-30s %s\n", cmd.name+cmd.synopsis(), cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'surfe <command> [subcommand] -h' for the flags of a command.\n")
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testUsers = `[
+	{"id": 1, "name": "Ada", "createdAt": "2024-01-01T00:00:00Z"},
+	{"id": 2, "name": "Grace", "createdAt": "2024-01-02T00:00:00Z"},
+	{"id": 3, "name": "Linus", "createdAt": "2024-01-03T00:00:00Z"}
+]`
+
+const testActions = `[
+	{"id": 1, "type": "WELCOME", "userId": 1, "createdAt": "2024-01-01T00:00:00Z"},
+	{"id": 2, "type": "REFER_USER", "userId": 1, "targetUser": 2, "createdAt": "2024-01-02T00:00:00Z"},
+	{"id": 3, "type": "REFER_USER", "userId": 2, "targetUser": 3, "createdAt": "2024-01-03T00:00:00Z"},
+	{"id": 4, "type": "WELCOME", "userId": 2, "createdAt": "2024-01-04T00:00:00Z"},
+	{"id": 5, "type": "CONNECT_CRM", "userId": 2, "createdAt": "2024-01-05T00:00:00Z"}
+]`
+
+// writeData writes the test data files and returns the environment that
+// points surfe at them.
+func writeData(t *testing.T) map[string]string {
+	dir := t.TempDir()
+	usersPath := filepath.Join(dir, "users.json")
+	actionsPath := filepath.Join(dir, "actions.json")
+	if err := os.WriteFile(usersPath, []byte(testUsers), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(actionsPath, []byte(testActions), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{"SURFE_USERS": usersPath, "SURFE_ACTIONS": actionsPath}
+}
+
+func TestRun(t *testing.T) {
+	env := writeData(t)
+
+	tests := []struct {
+		name             string
+		args             []string
+		expectedOutput   string
+		expectedError    string
+		expectedExitCode int
+	}{
+		{
+			name: "user get",
+			args: []string{"user", "get", "2"},
+			expectedOutput: "ID  NAME   CREATED_AT\n" +
+				"2   Grace  2024-01-02T00:00:00Z\n",
+		},
+		{
+			name:           "user get json",
+			args:           []string{"user", "get", "-format", "json", "2"},
+			expectedOutput: "{\n  \"id\": 2,\n  \"name\": \"Grace\",\n  \"createdAt\": \"2024-01-02T00:00:00Z\"\n}\n",
+		},
+		{
+			name:           "user action-count csv",
+			args:           []string{"user", "action-count", "2", "-format", "csv"},
+			expectedOutput: "user_id,count\n2,3\n",
+		},
+		{
+			name: "actions next",
+			args: []string{"actions", "next", "welcome"},
+			expectedOutput: "NEXT_ACTION  PROBABILITY\n" +
+				"CONNECT_CRM  0.5\n" +
+				"REFER_USER   0.5\n",
+		},
+		{
+			name:           "referrals index json",
+			args:           []string{"referrals", "index", "-format", "json"},
+			expectedOutput: "{\n  \"1\": 2,\n  \"2\": 1,\n  \"3\": 0\n}\n",
+		},
+		{
+			name: "referrals tree",
+			args: []string{"referrals", "tree", "1"},
+			expectedOutput: "ID  NAME   REFERRED_BY  REFERRED_AT\n" +
+				"1   Ada                 \n" +
+				"2   Grace  1            2024-01-02T00:00:00Z\n" +
+				"3   Linus  2            2024-01-03T00:00:00Z\n",
+		},
+		{
+			name:           "referrals tree with depth",
+			args:           []string{"referrals", "tree", "-format", "csv", "-depth", "1", "1"},
+			expectedOutput: "id,name,referred_by,referred_at\n1,Ada,,\n2,Grace,1,2024-01-02T00:00:00Z\n",
+		},
+		{
+			name:           "referrals quality",
+			args:           []string{"referrals", "quality", "-format", "csv"},
+			expectedOutput: "referrer_id,referred,activated,activation_rate,median_activation_seconds\n1,1,1,1,259200\n2,1,0,0,\n",
+		},
+		{
+			name:             "user not found",
+			args:             []string{"user", "get", "9"},
+			expectedError:    "user 9: not found",
+			expectedExitCode: exitFailure,
+		},
+		{
+			name:             "unknown action type",
+			args:             []string{"actions", "next", "JUMP"},
+			expectedError:    "action type JUMP: not found",
+			expectedExitCode: exitFailure,
+		},
+		{
+			name:             "missing data file",
+			args:             []string{"user", "get", "-users", "missing.json", "1"},
+			expectedError:    "missing.json",
+			expectedExitCode: exitFailure,
+		},
+		{
+			name:             "invalid ID",
+			args:             []string{"user", "get", "ada"},
+			expectedError:    `invalid user ID "ada"`,
+			expectedExitCode: exitUsage,
+		},
+		{
+			name:             "missing argument",
+			args:             []string{"user", "get"},
+			expectedError:    "surfe user get takes 1 argument(s), got 0",
+			expectedExitCode: exitUsage,
+		},
+		{
+			name:             "unknown format",
+			args:             []string{"referrals", "index", "-format", "xml"},
+			expectedError:    `unknown format "xml"`,
+			expectedExitCode: exitUsage,
+		},
+		{
+			name:             "unknown command",
+			args:             []string{"user", "delete", "1"},
+			expectedError:    `unknown command "user delete"`,
+			expectedExitCode: exitUsage,
+		},
+		{
+			name:             "no command",
+			args:             nil,
+			expectedError:    "no command given",
+			expectedExitCode: exitUsage,
+		},
+		{
+			name: "help",
+			args: []string{"help"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := run(context.Background(), tt.args, &stdout, &stderr, envFunc(env))
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			}
+			assert.Equal(t, tt.expectedExitCode, exitCode(err))
+			assert.Equal(t, tt.expectedOutput, stdout.String())
+		})
+	}
+}
+
+func envFunc(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+func TestRun_Validate(t *testing.T) {
+	env := writeData(t)
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"validate", "-format", "csv"}, &stdout, &stderr, envFunc(env))
+	assert.NoError(t, err)
+	assert.Equal(t, "severity,rule,dataset,record_id,message\n", stdout.String())
+
+	actionsPath := filepath.Join(t.TempDir(), "actions.json")
+	if err := os.WriteFile(actionsPath, []byte(`[
+		{"id": 1, "type": "REFER_USER", "userId": 1, "createdAt": "2024-01-02T00:00:00Z"},
+		{"id": 2, "type": "WELCOME", "userId": 4, "createdAt": "2024-01-02T00:00:00Z"}
+	]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	err = run(context.Background(), []string{"validate", "-actions", actionsPath}, &stdout, &stderr, envFunc(env))
+
+	assert.EqualError(t, err, "invalid data: 2 error(s), 0 warning(s) in 3 users and 2 actions")
+	assert.Equal(t, exitInvalidData, exitCode(err))
+	assert.Equal(t, "SEVERITY  RULE            DATASET  RECORD_ID  MESSAGE\n"+
+		"error     missing-target  actions  1          REFER_USER action has no targetUser\n"+
+		"error     unknown-user    actions  2          userId 4 is not a known user\n", stdout.String())
+}
+
+func TestRun_Generate(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{
+		"SURFE_USERS":   filepath.Join(dir, "users.json"),
+		"SURFE_ACTIONS": filepath.Join(dir, "actions.json"),
+	}
+	args := []string{"generate", "-user-count", "50", "-action-count", "400", "-seed", "3", "-format", "csv"}
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), args, &stdout, &stderr, envFunc(env))
+	assert.NoError(t, err)
+	assert.Regexp(t, `^users,actions,referrals,trees,max_depth\n50,400,\d+,\d+,\d+\n$`, stdout.String())
+	first, err := os.ReadFile(env["SURFE_ACTIONS"])
+	assert.NoError(t, err)
+
+	// The generated files are valid and can be queried.
+	stdout.Reset()
+	assert.NoError(t, run(context.Background(), []string{"validate", "-format", "csv"}, &stdout, &stderr, envFunc(env)))
+	assert.Equal(t, "severity,rule,dataset,record_id,message\n", stdout.String())
+	assert.NoError(t, run(context.Background(), []string{"referrals", "index"}, &stdout, &stderr, envFunc(env)))
+
+	err = run(context.Background(), args, &stdout, &stderr, envFunc(env))
+	assert.ErrorContains(t, err, "already exists (use -force to overwrite)")
+	assert.Equal(t, exitUsage, exitCode(err))
+
+	assert.NoError(t, run(context.Background(), append(args, "-force"), &stdout, &stderr, envFunc(env)))
+	second, err := os.ReadFile(env["SURFE_ACTIONS"])
+	assert.NoError(t, err)
+	assert.Equal(t, first, second, "the same seed produces the same data")
+
+	err = run(context.Background(), []string{"generate", "-user-count", "10", "-action-count", "5", "-force"}, &stdout, &stderr, envFunc(env))
+	assert.ErrorContains(t, err, "actions: must be at least the number of users (10), got 5")
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 2, "no temporary files are left behind")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+func validFormat(format string) bool {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return true
+	}
+	return false
+}
+
+// output is a command's result. JSON output encodes value, which has the
+// same shape as the API's response; table and CSV output list rows.
+type output struct {
+	value   any
+	columns []string
+	rows    [][]string
+}
+
+func (o *output) write(w io.Writer, format string) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(o.value)
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		writer.Write(o.columns)
+		writer.WriteAll(o.rows)
+		return writer.Error()
+	case formatTable:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.ToUpper(strings.Join(o.columns, "\t")))
+		for _, row := range o.rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	}
+	return fmt.Errorf("%w: unknown format %q", errUsage, format)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,115 @@
+// Package actiontypes keeps the catalogue of known action types, with their
+// descriptions and categories, and records which types refer to another user
+// through Action.TargetUser.
+package actiontypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+	"sync"
+)
+
+// UncategorizedCategory is the category given to types that are only known
+// from the data.
+const UncategorizedCategory = "uncategorized"
+
+var defaultTypes = []models.ActionType{
+	{Name: "WELCOME", Description: "User completed the welcome flow", Category: "onboarding"},
+	{Name: "CONNECT_CRM", Description: "User connected a CRM", Category: "integration"},
+	{Name: "ADD_CONTACT", Description: "User added a contact", Category: "contacts"},
+	{Name: "EDIT_CONTACT", Description: "User edited a contact", Category: "contacts"},
+	{Name: "VIEW_CONTACTS", Description: "User viewed their contacts", Category: "contacts"},
+	{Name: "REFER_USER", Description: "User referred the target user", Category: "growth", TargetsUser: true},
+}
+
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]models.ActionType
+}
+
+// New returns a registry holding the given types. Names must be non-empty,
+// upper case and unique.
+func New(types []models.ActionType) (*Registry, error) {
+	r := &Registry{types: make(map[string]models.ActionType, len(types))}
+	for _, t := range types {
+		if t.Name == "" || t.Name != strings.ToUpper(t.Name) {
+			return nil, fmt.Errorf("%w: action type name %q must be non-empty and upper case", apperrors.ErrInvalidArgument, t.Name)
+		}
+		if _, found := r.types[t.Name]; found {
+			return nil, fmt.Errorf("%w: duplicate action type %q", apperrors.ErrInvalidArgument, t.Name)
+		}
+		r.types[t.Name] = t
+	}
+	return r, nil
+}
+
+// Default returns a registry holding the built-in action types.
+func Default() *Registry {
+	r, err := New(defaultTypes)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Load reads a registry from a JSON file holding an array of action types.
+func Load(filePath string) (*Registry, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrUnavailable, err)
+	}
+	defer file.Close()
+
+	var types []models.ActionType
+	if err := json.NewDecoder(file).Decode(&types); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON data in %s: %v", apperrors.ErrUnavailable, filePath, err)
+	}
+	return New(types)
+}
+
+// Observe registers a type seen in the data. Unknown types are added as
+// uncategorized types without TargetUser semantics.
+func (r *Registry) Observe(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.types[name]; !found {
+		r.types[name] = models.ActionType{Name: name, Category: UncategorizedCategory}
+	}
+}
+
+// Lookup returns the type with the given name.
+func (r *Registry) Lookup(name string) (models.ActionType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, found := r.types[name]
+	return t, found
+}
+
+// TargetsUser reports whether actions of the given type refer to another
+// user through Action.TargetUser.
+func (r *Registry) TargetsUser(name string) bool {
+	t, found := r.Lookup(name)
+	return found && t.TargetsUser
+}
+
+// All returns every registered type ordered by name.
+func (r *Registry) All() []models.ActionType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]models.ActionType, 0, len(r.types))
+	for _, t := range r.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name < types[j].Name
+	})
+	return types
+}
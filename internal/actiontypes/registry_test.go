@@ -0,0 +1,98 @@
+package actiontypes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name          string
+		types         []models.ActionType
+		expectedError bool
+	}{
+		{
+			name: "valid types",
+			types: []models.ActionType{
+				{Name: "LOGIN", Category: "session"},
+				{Name: "INVITE", Category: "growth", TargetsUser: true},
+			},
+			expectedError: false,
+		},
+		{
+			name:          "lower case name",
+			types:         []models.ActionType{{Name: "login"}},
+			expectedError: true,
+		},
+		{
+			name:          "empty name",
+			types:         []models.ActionType{{Name: ""}},
+			expectedError: true,
+		},
+		{
+			name:          "duplicate name",
+			types:         []models.ActionType{{Name: "LOGIN"}, {Name: "LOGIN"}},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := New(tt.types)
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, len(tt.types), len(registry.All()))
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "action_types.json")
+	if err := os.WriteFile(filePath, []byte(`[
+		{"name": "INVITE", "description": "User invited someone", "category": "growth", "targetsUser": true},
+		{"name": "LOGIN", "description": "User logged in", "category": "session"}
+	]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := Load(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ActionType{
+		{Name: "INVITE", Description: "User invited someone", Category: "growth", TargetsUser: true},
+		{Name: "LOGIN", Description: "User logged in", Category: "session"},
+	}, registry.All())
+	assert.True(t, registry.TargetsUser("INVITE"))
+	assert.False(t, registry.TargetsUser("REFER_USER"))
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+}
+
+func TestRegistry_Observe(t *testing.T) {
+	registry := Default()
+
+	registry.Observe("LOGIN")
+	registry.Observe("REFER_USER")
+
+	login, found := registry.Lookup("LOGIN")
+	assert.True(t, found)
+	assert.Equal(t, models.ActionType{Name: "LOGIN", Category: UncategorizedCategory}, login)
+
+	referral, found := registry.Lookup("REFER_USER")
+	assert.True(t, found)
+	assert.Equal(t, "growth", referral.Category)
+	assert.True(t, referral.TargetsUser)
+
+	_, found = registry.Lookup("LOGOUT")
+	assert.False(t, found)
+}
@@ -0,0 +1,15 @@
+// Package apperrors defines the domain errors shared by the repository,
+// service and handler layers. Lower layers wrap these sentinels with context
+// using fmt.Errorf and %w; handlers use errors.Is to pick a response status.
+package apperrors
+
+import "errors"
+
+var (
+	// ErrNotFound reports that the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidArgument reports that the caller supplied an invalid value.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrUnavailable reports that a backing data source cannot be used.
+	ErrUnavailable = errors.New("unavailable")
+)
@@ -0,0 +1,75 @@
+// Package audit keeps the audit trail: an append-only file with one JSON
+// event per line, such as each user erasure. Every event is synced to disk
+// before Record returns, so a recorded event survives a crash.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"surfe/internal/logging"
+	"surfe/internal/models"
+	"surfe/internal/services"
+	"sync"
+)
+
+// Log appends events to a file. It is safe for concurrent use.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewLog returns a log that appends to the file at path, creating it on the
+// first event.
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// Record appends event, filling in the ID of the request ctx belongs to.
+func (l *Log) Record(ctx context.Context, event models.AuditEvent) error {
+	if event.RequestID == "" {
+		event.RequestID = logging.RequestID(ctx)
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return fmt.Errorf("audit log: %v", err)
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return fmt.Errorf("audit log %s: %v", l.path, err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("audit log %s: %v", l.path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("audit log %s: %v", l.path, err)
+	}
+	return nil
+}
+
+// Workspace returns a trail that records events to l on behalf of workspace
+// id.
+func (l *Log) Workspace(id string) services.AuditTrail {
+	return &workspaceTrail{log: l, id: id}
+}
+
+type workspaceTrail struct {
+	log *Log
+	id  string
+}
+
+func (t *workspaceTrail) Record(ctx context.Context, event models.AuditEvent) error {
+	event.Workspace = t.id
+	return t.log.Record(ctx, event)
+}
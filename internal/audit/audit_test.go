@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"surfe/internal/logging"
+	"surfe/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readEvents(t *testing.T, path string) []models.AuditEvent {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var events []models.AuditEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event models.AuditEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.NoError(t, scanner.Err())
+	return events
+}
+
+func TestLog_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(path)
+	erasedAt := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	erasure := &models.Erasure{UserID: 7, ErasedAt: erasedAt, ActionsDeleted: 3, ActionsAnonymised: 1}
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	require.NoError(t, log.Record(ctx, models.AuditEvent{Time: erasedAt, Event: "user.erased", Erasure: erasure}))
+	require.NoError(t, log.Workspace("acme").Record(context.Background(), models.AuditEvent{Time: erasedAt, Event: "user.erased", Erasure: erasure}))
+
+	assert.Equal(t, []models.AuditEvent{
+		{Time: erasedAt, Event: "user.erased", RequestID: "req-1", Erasure: erasure},
+		{Time: erasedAt, Event: "user.erased", Workspace: "acme", Erasure: erasure},
+	}, readEvents(t, path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+}
+
+func TestLog_Record_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(path)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, log.Record(context.Background(), models.AuditEvent{Event: "user.erased", Erasure: &models.Erasure{UserID: i}}))
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, readEvents(t, path), 20)
+}
+
+func TestLog_Record_Unwritable(t *testing.T) {
+	log := NewLog(filepath.Join(t.TempDir(), "missing", "audit.jsonl"))
+
+	err := log.Record(context.Background(), models.AuditEvent{Event: "user.erased"})
+
+	assert.ErrorContains(t, err, "audit log:")
+}
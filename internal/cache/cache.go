@@ -0,0 +1,206 @@
+// Package cache memoizes the results of expensive service calls. Each entry
+// is tagged with the data version it was computed from and is recomputed
+// once the data changes; the least recently used entries are evicted when
+// the cache is full. Concurrent misses for the same call share a single
+// computation.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sort"
+	"surfe/internal/services"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// resultKey annotates service spans with whether the result came from the
+// cache.
+const resultKey = attribute.Key("surfe.cache")
+
+type Cache struct {
+	versions services.VersionService
+	size     int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	calls   map[string]*call
+	stats   map[string]*Stats
+}
+
+type entry struct {
+	key     string
+	version string
+	value   any
+}
+
+// call is a computation in progress that later requests for the same key
+// wait for instead of starting their own.
+type call struct {
+	version string
+	done    chan struct{}
+	value   any
+	err     error
+}
+
+// Stats counts the lookups of one service method.
+type Stats struct {
+	Method string
+	Hits   uint64
+	Misses uint64
+}
+
+// New returns a cache holding up to size entries, invalidated whenever the
+// version reported by versions changes.
+func New(versions services.VersionService, size int) *Cache {
+	return &Cache{
+		versions: versions,
+		size:     size,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		calls:    make(map[string]*call),
+		stats:    make(map[string]*Stats),
+	}
+}
+
+// Len returns the number of cached entries, including stale ones that have
+// not been evicted yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns the hit and miss counts of every method looked up so far,
+// ordered by method.
+func (c *Cache) Stats() []Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make([]Stats, 0, len(c.stats))
+	for _, s := range c.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+// get returns the result of method for params, computing it with compute
+// unless an entry for the current data version is cached or the same
+// computation is already running. Results are not cached while the data is
+// not loaded, and errors are never cached.
+func (c *Cache) get(ctx context.Context, method, params string, compute func(context.Context) (any, error)) (any, error) {
+	version := c.versions.Version(ctx)
+	if version == nil {
+		c.record(ctx, method, false)
+		return compute(ctx)
+	}
+	key := method + "\x00" + params
+
+	for {
+		c.mu.Lock()
+		if el, found := c.entries[key]; found {
+			e := el.Value.(*entry)
+			if e.version == version.Tag {
+				c.lru.MoveToFront(el)
+				c.recordLocked(ctx, method, true)
+				c.mu.Unlock()
+				return e.value, nil
+			}
+		}
+
+		if running, found := c.calls[key]; found && running.version == version.Tag {
+			c.mu.Unlock()
+			select {
+			case <-running.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			// The request that started the computation gave up; start
+			// over rather than failing a request that still has time.
+			if isContextError(running.err) && ctx.Err() == nil {
+				continue
+			}
+			c.record(ctx, method, running.err == nil)
+			return running.value, running.err
+		}
+
+		running := &call{version: version.Tag, done: make(chan struct{})}
+		c.calls[key] = running
+		c.recordLocked(ctx, method, false)
+		c.mu.Unlock()
+
+		c.run(ctx, key, running, compute)
+		return running.value, running.err
+	}
+}
+
+// run computes the result of running and caches it on success. Waiting
+// requests are released even if compute panics.
+func (c *Cache) run(ctx context.Context, key string, running *call, compute func(context.Context) (any, error)) {
+	defer func() {
+		c.mu.Lock()
+		if c.calls[key] == running {
+			delete(c.calls, key)
+		}
+		if running.err == nil {
+			c.storeLocked(key, running.version, running.value)
+		}
+		c.mu.Unlock()
+		close(running.done)
+	}()
+
+	// Overwritten when compute returns; left in place if it panics.
+	running.err = errors.New("cache: computation did not return")
+	running.value, running.err = compute(ctx)
+}
+
+func (c *Cache) storeLocked(key, version string, value any) {
+	if c.size <= 0 {
+		return
+	}
+	if el, found := c.entries[key]; found {
+		e := el.Value.(*entry)
+		e.version, e.value = version, value
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&entry{key: key, version: version, value: value})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+func (c *Cache) record(ctx context.Context, method string, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recordLocked(ctx, method, hit)
+}
+
+func (c *Cache) recordLocked(ctx context.Context, method string, hit bool) {
+	s, found := c.stats[method]
+	if !found {
+		s = &Stats{Method: method}
+		c.stats[method] = s
+	}
+	result := "miss"
+	if hit {
+		s.Hits++
+		result = "hit"
+	} else {
+		s.Misses++
+	}
+	trace.SpanFromContext(ctx).SetAttributes(resultKey.String(result))
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"surfe/internal/models"
+	"surfe/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubVersionService reports a version that tests can change.
+type stubVersionService struct {
+	mu  sync.Mutex
+	tag string
+}
+
+func (s *stubVersionService) Version(ctx context.Context) *models.DataVersion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tag == "" {
+		return nil
+	}
+	return &models.DataVersion{Tag: s.tag}
+}
+
+func (s *stubVersionService) set(tag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tag = tag
+}
+
+// stubActionService counts the calls that reach it. When release is set,
+// GetReferralIndex blocks until it is closed or the context is done.
+type stubActionService struct {
+	services.ActionService
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (s *stubActionService) GetNextActionProbabilities(ctx context.Context, actionType string) (map[string]float64, error) {
+	s.calls.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return map[string]float64{actionType: 1}, nil
+}
+
+func (s *stubActionService) GetNextActions(ctx context.Context, actionType string) (*models.NextActions, error) {
+	s.calls.Add(1)
+	return &models.NextActions{ActionType: actionType}, nil
+}
+
+func (s *stubActionService) GetReferralIndex(ctx context.Context) (map[int]int, error) {
+	s.calls.Add(1)
+	if s.release != nil {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return map[int]int{1: 2}, nil
+}
+
+func TestActionService_GetNextActionProbabilities(t *testing.T) {
+	versions := &stubVersionService{tag: "v1"}
+	next := &stubActionService{}
+	c := New(versions, 2)
+	service := c.ActionService(next)
+	ctx := context.Background()
+
+	lookup := func(actionType string) {
+		t.Helper()
+		result, err := service.GetNextActionProbabilities(ctx, actionType)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{actionType: 1}, result)
+	}
+
+	lookup("WELCOME")
+	lookup("WELCOME")
+	assert.Equal(t, int32(1), next.calls.Load(), "second lookup is a hit")
+
+	lookup("REFER_USER")
+	lookup("ADD_CONTACT")
+	assert.Equal(t, 2, c.Len())
+	lookup("WELCOME")
+	assert.Equal(t, int32(4), next.calls.Load(), "least recently used entry is evicted")
+
+	versions.set("v2")
+	lookup("ADD_CONTACT")
+	assert.Equal(t, int32(5), next.calls.Load(), "data change invalidates the entry")
+
+	versions.set("")
+	lookup("ADD_CONTACT")
+	lookup("ADD_CONTACT")
+	assert.Equal(t, int32(7), next.calls.Load(), "nothing is cached while data is not loaded")
+
+	assert.Equal(t, []Stats{{Method: "GetNextActionProbabilities", Hits: 1, Misses: 7}}, c.Stats())
+}
+
+func TestActionService_GetNextActions(t *testing.T) {
+	next := &stubActionService{}
+	c := New(&stubVersionService{tag: "v1"}, 10)
+	service := c.ActionService(next)
+
+	for range 2 {
+		result, err := service.GetNextActions(context.Background(), "WELCOME")
+		require.NoError(t, err)
+		assert.Equal(t, &models.NextActions{ActionType: "WELCOME"}, result)
+	}
+	// The rounded probabilities are cached apart from the counts.
+	_, err := service.GetNextActionProbabilities(context.Background(), "WELCOME")
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), next.calls.Load())
+	assert.ElementsMatch(t, []Stats{
+		{Method: "GetNextActions", Hits: 1, Misses: 1},
+		{Method: "GetNextActionProbabilities", Misses: 1},
+	}, c.Stats())
+}
+
+func TestActionService_ErrorsNotCached(t *testing.T) {
+	next := &stubActionService{err: errors.New("boom")}
+	service := New(&stubVersionService{tag: "v1"}, 10).ActionService(next)
+
+	for i := 0; i < 2; i++ {
+		_, err := service.GetNextActionProbabilities(context.Background(), "WELCOME")
+		assert.EqualError(t, err, "boom")
+	}
+	assert.Equal(t, int32(2), next.calls.Load())
+}
+
+func TestActionService_SingleFlight(t *testing.T) {
+	next := &stubActionService{release: make(chan struct{})}
+	c := New(&stubVersionService{tag: "v1"}, 10)
+	service := c.ActionService(next)
+
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make([]map[int]int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := service.GetReferralIndex(context.Background())
+			assert.NoError(t, err)
+			results[i] = result
+		}(i)
+	}
+
+	// Wait for every caller to be either computing or waiting.
+	assert.Eventually(t, func() bool {
+		stats := c.Stats()
+		return len(stats) == 1 && stats[0].Misses == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), next.calls.Load())
+	for _, result := range results {
+		assert.Equal(t, map[int]int{1: 2}, result)
+	}
+	assert.Equal(t, []Stats{{Method: "GetReferralIndex", Hits: callers - 1, Misses: 1}}, c.Stats())
+}
+
+func TestActionService_SingleFlightLeaderCanceled(t *testing.T) {
+	next := &stubActionService{release: make(chan struct{})}
+	c := New(&stubVersionService{tag: "v1"}, 10)
+	service := c.ActionService(next)
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := service.GetReferralIndex(leaderCtx)
+		leaderErr <- err
+	}()
+	assert.Eventually(t, func() bool { return next.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	followerResult := make(chan map[int]int, 1)
+	go func() {
+		result, err := service.GetReferralIndex(context.Background())
+		assert.NoError(t, err)
+		followerResult <- result
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancelLeader()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+
+	// The follower starts its own computation instead of failing.
+	assert.Eventually(t, func() bool { return next.calls.Load() == 2 }, time.Second, time.Millisecond)
+	close(next.release)
+	assert.Equal(t, map[int]int{1: 2}, <-followerResult)
+}
+
+func TestActionService_WaiterCanceled(t *testing.T) {
+	next := &stubActionService{release: make(chan struct{})}
+	defer close(next.release)
+	service := New(&stubVersionService{tag: "v1"}, 10).ActionService(next)
+
+	go service.GetReferralIndex(context.Background())
+	assert.Eventually(t, func() bool { return next.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := service.GetReferralIndex(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestActionService_SizeZero(t *testing.T) {
+	next := &stubActionService{}
+	c := New(&stubVersionService{tag: "v1"}, 0)
+	service := c.ActionService(next)
+
+	for i := 0; i < 2; i++ {
+		_, err := service.GetReferralIndex(context.Background())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), next.calls.Load())
+	assert.Equal(t, 0, c.Len())
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"surfe/internal/models"
+	"surfe/internal/services"
+)
+
+// actionService caches the action service calls that scan every action. The
+// remaining methods are passed through to the embedded service. Cached
+// results are shared between callers, which must not modify them.
+type actionService struct {
+	services.ActionService
+	cache *Cache
+}
+
+// ActionService caches the results of an action service.
+func (c *Cache) ActionService(next services.ActionService) services.ActionService {
+	return &actionService{
+		ActionService: next,
+		cache:         c,
+	}
+}
+
+func (s *actionService) GetNextActionProbabilities(ctx context.Context, actionType string) (map[string]float64, error) {
+	value, err := s.cache.get(ctx, "GetNextActionProbabilities", actionType, func(ctx context.Context) (any, error) {
+		return s.ActionService.GetNextActionProbabilities(ctx, actionType)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(map[string]float64), nil
+}
+
+func (s *actionService) GetNextActions(ctx context.Context, actionType string) (*models.NextActions, error) {
+	value, err := s.cache.get(ctx, "GetNextActions", actionType, func(ctx context.Context) (any, error) {
+		return s.ActionService.GetNextActions(ctx, actionType)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*models.NextActions), nil
+}
+
+func (s *actionService) GetReferralIndex(ctx context.Context) (map[int]int, error) {
+	value, err := s.cache.get(ctx, "GetReferralIndex", "", func(ctx context.Context) (any, error) {
+		return s.ActionService.GetReferralIndex(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(map[int]int), nil
+}
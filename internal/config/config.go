@@ -0,0 +1,368 @@
+// Package config loads the API server configuration. Settings are resolved
+// from, in increasing order of precedence, built-in defaults, an optional YAML
+// file, SURFE_* environment variables and command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to the upper-cased option name to form the name of
+// the environment variable that sets it, e.g. SURFE_ADDR for -addr.
+const EnvPrefix = "SURFE_"
+
+// BackendFile loads users and actions from JSON files.
+const BackendFile = "file"
+
+// Trace exporters.
+const (
+	TracingNone   = "none"
+	TracingOTLP   = "otlp"
+	TracingStdout = "stdout"
+	TracingFile   = "file"
+)
+
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Data       DataConfig       `yaml:"data"`
+	Log        LogConfig        `yaml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Workspaces WorkspacesConfig `yaml:"workspaces"`
+	Audit      AuditConfig      `yaml:"audit"`
+	Features   FeatureConfig    `yaml:"features"`
+}
+
+type ServerConfig struct {
+	Addr         string        `yaml:"addr"`
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	IdleTimeout  time.Duration `yaml:"idleTimeout"`
+	// ShutdownTimeout bounds how long in-flight requests are drained for
+	// after a termination signal.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// RequestTimeout bounds lookups such as a single user; ReportTimeout
+	// bounds the routes that scan every action, such as the referral index.
+	RequestTimeout time.Duration `yaml:"requestTimeout"`
+	ReportTimeout  time.Duration `yaml:"reportTimeout"`
+}
+
+type DataConfig struct {
+	Backend     string `yaml:"backend"`
+	UsersPath   string `yaml:"usersPath"`
+	ActionsPath string `yaml:"actionsPath"`
+	// ActionTypesPath is optional; the built-in action types are used when
+	// it is empty.
+	ActionTypesPath string `yaml:"actionTypesPath"`
+	// ReloadInterval is how often missing data files are retried.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+type TracingConfig struct {
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP/HTTP collector URL. When empty the standard
+	// OTEL_EXPORTER_OTLP_* environment variables apply.
+	Endpoint string `yaml:"endpoint"`
+	// FilePath receives one JSON span per line with the file exporter.
+	FilePath string `yaml:"filePath"`
+}
+
+type CacheConfig struct {
+	// Size is how many analytics results are kept; 0 disables caching.
+	Size int `yaml:"size"`
+}
+
+type WorkspacesConfig struct {
+	// Dir holds one directory per workspace, named by its ID; workspaces
+	// are disabled when it is empty.
+	Dir string `yaml:"dir"`
+	// MaxLoaded bounds how many workspaces are held in memory at once.
+	MaxLoaded int `yaml:"maxLoaded"`
+	// IdleTimeout is how long an unused workspace stays loaded; 0 keeps it
+	// until it makes room for another.
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+}
+
+type AuditConfig struct {
+	// LogPath is the audit trail every user erasure is appended to, for the
+	// server's dataset and every workspace. User erasure is disabled when
+	// it is empty.
+	LogPath string `yaml:"logPath"`
+}
+
+type FeatureConfig struct {
+	Swagger         bool `yaml:"swagger"`
+	ActionRecording bool `yaml:"actionRecording"`
+	Metrics         bool `yaml:"metrics"`
+	// RequestValidation rejects API requests that do not match the OpenAPI
+	// description before they reach the handlers.
+	RequestValidation bool `yaml:"requestValidation"`
+}
+
+// Default returns the configuration used when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8000",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+			RequestTimeout:  5 * time.Second,
+			ReportTimeout:   25 * time.Second,
+		},
+		Data: DataConfig{
+			Backend:        BackendFile,
+			UsersPath:      "users.json",
+			ActionsPath:    "actions.json",
+			ReloadInterval: 5 * time.Second,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			Exporter: TracingNone,
+		},
+		Cache: CacheConfig{
+			Size: 1024,
+		},
+		Workspaces: WorkspacesConfig{
+			MaxLoaded:   16,
+			IdleTimeout: 30 * time.Minute,
+		},
+		Features: FeatureConfig{
+			Swagger:         true,
+			ActionRecording: true,
+			Metrics:         true,
+		},
+	}
+}
+
+type option struct {
+	name  string
+	usage string
+	field func(c *Config) interface{}
+}
+
+var options = []option{
+	{"addr", "listen address", func(c *Config) interface{} { return &c.Server.Addr }},
+	{"read-timeout", "maximum duration for reading a request", func(c *Config) interface{} { return &c.Server.ReadTimeout }},
+	{"write-timeout", "maximum duration for writing a response", func(c *Config) interface{} { return &c.Server.WriteTimeout }},
+	{"idle-timeout", "maximum keep-alive idle time", func(c *Config) interface{} { return &c.Server.IdleTimeout }},
+	{"shutdown-timeout", "maximum duration for draining requests on shutdown", func(c *Config) interface{} { return &c.Server.ShutdownTimeout }},
+	{"request-timeout", "maximum duration for lookup routes", func(c *Config) interface{} { return &c.Server.RequestTimeout }},
+	{"report-timeout", "maximum duration for routes that scan every action", func(c *Config) interface{} { return &c.Server.ReportTimeout }},
+	{"backend", "data backend (file)", func(c *Config) interface{} { return &c.Data.Backend }},
+	{"users", "path to the users JSON file", func(c *Config) interface{} { return &c.Data.UsersPath }},
+	{"actions", "path to the actions JSON file", func(c *Config) interface{} { return &c.Data.ActionsPath }},
+	{"action-types", "path to the action types JSON file", func(c *Config) interface{} { return &c.Data.ActionTypesPath }},
+	{"reload-interval", "how often missing data files are retried", func(c *Config) interface{} { return &c.Data.ReloadInterval }},
+	{"log-level", "log level (debug, info, warn, error)", func(c *Config) interface{} { return &c.Log.Level }},
+	{"tracing-exporter", "trace exporter (none, otlp, stdout, file)", func(c *Config) interface{} { return &c.Tracing.Exporter }},
+	{"tracing-endpoint", "OTLP/HTTP collector URL", func(c *Config) interface{} { return &c.Tracing.Endpoint }},
+	{"tracing-file", "file that receives spans with the file exporter", func(c *Config) interface{} { return &c.Tracing.FilePath }},
+	{"cache-size", "analytics results to cache (0 disables caching)", func(c *Config) interface{} { return &c.Cache.Size }},
+	{"workspaces-dir", "directory holding one directory per workspace", func(c *Config) interface{} { return &c.Workspaces.Dir }},
+	{"workspaces-max-loaded", "workspaces held in memory at once", func(c *Config) interface{} { return &c.Workspaces.MaxLoaded }},
+	{"workspaces-idle-timeout", "how long an unused workspace stays loaded (0 disables)", func(c *Config) interface{} { return &c.Workspaces.IdleTimeout }},
+	{"audit-log", "audit trail of user erasures (erasure is disabled unless set)", func(c *Config) interface{} { return &c.Audit.LogPath }},
+	{"swagger", "serve the Swagger UI", func(c *Config) interface{} { return &c.Features.Swagger }},
+	{"action-recording", "accept new actions over HTTP", func(c *Config) interface{} { return &c.Features.ActionRecording }},
+	{"metrics", "serve Prometheus metrics on /metrics", func(c *Config) interface{} { return &c.Features.Metrics }},
+	{"request-validation", "reject API requests that do not match the OpenAPI description", func(c *Config) interface{} { return &c.Features.RequestValidation }},
+}
+
+// EnvName returns the environment variable that sets the named option.
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Load resolves the configuration from args (without the program name) and
+// the environment looked up through lookupEnv. The YAML file is taken from
+// the -config flag or SURFE_CONFIG. A variable that is set but empty clears
+// a string setting, such as audit.logPath, and is ignored for the others.
+// The result is validated before it is returned.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	envConfigPath, _ := lookupEnv(EnvName("config"))
+	fs := flag.NewFlagSet("surfe", flag.ContinueOnError)
+	configPath := fs.String("config", envConfigPath, "path to a YAML configuration file")
+	flagValues := make(map[string]string)
+	for _, opt := range options {
+		name := opt.name
+		record := func(value string) error {
+			flagValues[name] = value
+			return nil
+		}
+		if _, isBool := opt.field(cfg).(*bool); isBool {
+			fs.BoolFunc(name, opt.usage, record)
+		} else {
+			fs.Func(name, opt.usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, opt := range options {
+		value, found := lookupEnv(EnvName(opt.name))
+		if !found {
+			continue
+		}
+		field := opt.field(cfg)
+		if _, isString := field.(*string); value != "" || isString {
+			if err := set(field, value); err != nil {
+				return nil, fmt.Errorf("%s: %v", EnvName(opt.name), err)
+			}
+		}
+	}
+	for _, opt := range options {
+		if value, found := flagValues[opt.name]; found {
+			if err := set(opt.field(cfg), value); err != nil {
+				return nil, fmt.Errorf("-%s: %v", opt.name, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("config file: %v", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("config file %s: %v", filePath, err)
+	}
+	return nil
+}
+
+func set(field interface{}, value string) error {
+	switch field := field.(type) {
+	case *string:
+		*field = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*field = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*field = d
+	default:
+		return fmt.Errorf("unsupported option type %T", field)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server.addr: invalid listen address %q", c.Server.Addr))
+	}
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.readTimeout", c.Server.ReadTimeout},
+		{"server.writeTimeout", c.Server.WriteTimeout},
+		{"server.idleTimeout", c.Server.IdleTimeout},
+		{"server.shutdownTimeout", c.Server.ShutdownTimeout},
+		{"server.requestTimeout", c.Server.RequestTimeout},
+		{"server.reportTimeout", c.Server.ReportTimeout},
+		{"data.reloadInterval", c.Data.ReloadInterval},
+	} {
+		if timeout.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive, got %s", timeout.name, timeout.value))
+		}
+	}
+	// A route timeout at or beyond the write timeout would never fire: the
+	// connection is closed before the handler can report it.
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.requestTimeout", c.Server.RequestTimeout},
+		{"server.reportTimeout", c.Server.ReportTimeout},
+	} {
+		if timeout.value >= c.Server.WriteTimeout && c.Server.WriteTimeout > 0 {
+			errs = append(errs, fmt.Errorf("%s: must be shorter than server.writeTimeout (%s), got %s", timeout.name, c.Server.WriteTimeout, timeout.value))
+		}
+	}
+	if c.Data.Backend != BackendFile {
+		errs = append(errs, fmt.Errorf("data.backend: unsupported backend %q", c.Data.Backend))
+	}
+	if c.Data.UsersPath == "" {
+		errs = append(errs, errors.New("data.usersPath: must be set"))
+	}
+	if c.Data.ActionsPath == "" {
+		errs = append(errs, errors.New("data.actionsPath: must be set"))
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level: unknown level %q", c.Log.Level))
+	}
+	switch c.Tracing.Exporter {
+	case TracingNone, TracingOTLP, TracingStdout:
+	case TracingFile:
+		if c.Tracing.FilePath == "" {
+			errs = append(errs, errors.New("tracing.filePath: must be set for the file exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: unknown exporter %q", c.Tracing.Exporter))
+	}
+	if c.Cache.Size < 0 {
+		errs = append(errs, fmt.Errorf("cache.size: must not be negative, got %d", c.Cache.Size))
+	}
+	if c.Workspaces.MaxLoaded <= 0 {
+		errs = append(errs, fmt.Errorf("workspaces.maxLoaded: must be positive, got %d", c.Workspaces.MaxLoaded))
+	}
+	if c.Workspaces.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("workspaces.idleTimeout: must not be negative, got %s", c.Workspaces.IdleTimeout))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, contents string) string {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filePath, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func envFunc(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, found := env[key]
+		return value, found
+	}
+}
+
+func TestLoad(t *testing.T) {
+	configFile := writeConfigFile(t, `
+server:
+  addr: ":9000"
+  readTimeout: 5s
+data:
+  usersPath: /data/users.json
+  actionsPath: /data/actions.json
+log:
+  level: warn
+features:
+  swagger: false
+`)
+
+	tests := []struct {
+		name          string
+		args          []string
+		env           map[string]string
+		expected      func(c *Config)
+		expectedError string
+	}{
+		{
+			name:     "defaults",
+			args:     nil,
+			env:      nil,
+			expected: func(c *Config) {},
+		},
+		{
+			name: "config file",
+			args: []string{"-config", configFile},
+			env:  nil,
+			expected: func(c *Config) {
+				c.Server.Addr = ":9000"
+				c.Server.ReadTimeout = 5 * time.Second
+				c.Data.UsersPath = "/data/users.json"
+				c.Data.ActionsPath = "/data/actions.json"
+				c.Log.Level = "warn"
+				c.Features.Swagger = false
+			},
+		},
+		{
+			name: "environment overrides file",
+			args: nil,
+			env: map[string]string{
+				"SURFE_CONFIG":    configFile,
+				"SURFE_ADDR":      ":9100",
+				"SURFE_LOG_LEVEL": "debug",
+				"SURFE_SWAGGER":   "true",
+			},
+			expected: func(c *Config) {
+				c.Server.Addr = ":9100"
+				c.Server.ReadTimeout = 5 * time.Second
+				c.Data.UsersPath = "/data/users.json"
+				c.Data.ActionsPath = "/data/actions.json"
+				c.Log.Level = "debug"
+			},
+		},
+		{
+			name: "flags override environment",
+			args: []string{"-config", configFile, "-addr", "127.0.0.1:9200", "-read-timeout", "1m", "-cache-size", "0", "-action-recording=false", "-swagger", "-request-validation"},
+			env: map[string]string{
+				"SURFE_ADDR":           ":9100",
+				"SURFE_WORKSPACES_DIR": "/data/workspaces",
+				"SURFE_AUDIT_LOG":      "/var/log/surfe/audit.jsonl",
+			},
+			expected: func(c *Config) {
+				c.Server.Addr = "127.0.0.1:9200"
+				c.Workspaces.Dir = "/data/workspaces"
+				c.Audit.LogPath = "/var/log/surfe/audit.jsonl"
+				c.Server.ReadTimeout = time.Minute
+				c.Data.UsersPath = "/data/users.json"
+				c.Data.ActionsPath = "/data/actions.json"
+				c.Log.Level = "warn"
+				c.Cache.Size = 0
+				c.Features.ActionRecording = false
+				c.Features.RequestValidation = true
+			},
+		},
+		{
+			name: "empty environment value",
+			args: []string{"-config", writeConfigFile(t, "audit:\n  logPath: /var/log/surfe/audit.jsonl\n")},
+			env: map[string]string{
+				"SURFE_AUDIT_LOG":  "",
+				"SURFE_CACHE_SIZE": "",
+			},
+			// Only string settings can be cleared; the others keep their
+			// value.
+			expected: func(c *Config) {},
+		},
+		{
+			name:          "invalid environment value",
+			args:          nil,
+			env:           map[string]string{"SURFE_READ_TIMEOUT": "soon"},
+			expectedError: `SURFE_READ_TIMEOUT: invalid duration "soon"`,
+		},
+		{
+			name:          "invalid integer",
+			args:          []string{"-cache-size", "many"},
+			expectedError: `-cache-size: invalid integer "many"`,
+		},
+		{
+			name:          "unknown file key",
+			args:          []string{"-config", writeConfigFile(t, "server:\n  port: 8000\n")},
+			expectedError: "field port not found",
+		},
+		{
+			name:          "missing file",
+			args:          []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")},
+			expectedError: "config file:",
+		},
+		{
+			name:          "unknown flag",
+			args:          []string{"-port", "8000"},
+			expectedError: "flag provided but not defined: -port",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Load(tt.args, envFunc(tt.env))
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				expected := Default()
+				tt.expected(expected)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, result)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Addr = "8000"
+	cfg.Server.WriteTimeout = 0
+	cfg.Data.Backend = "postgres"
+	cfg.Data.UsersPath = ""
+	cfg.Data.ReloadInterval = -time.Second
+	cfg.Log.Level = "verbose"
+	cfg.Tracing.Exporter = TracingFile
+	cfg.Cache.Size = -1
+	cfg.Workspaces.MaxLoaded = 0
+	cfg.Workspaces.IdleTimeout = -time.Minute
+
+	err := cfg.Validate()
+
+	assert.EqualError(t, err, "invalid configuration: "+
+		"server.addr: invalid listen address \"8000\"\n"+
+		"server.writeTimeout: must be positive, got 0s\n"+
+		"data.reloadInterval: must be positive, got -1s\n"+
+		"data.backend: unsupported backend \"postgres\"\n"+
+		"data.usersPath: must be set\n"+
+		"log.level: unknown level \"verbose\"\n"+
+		"tracing.filePath: must be set for the file exporter\n"+
+		"cache.size: must not be negative, got -1\n"+
+		"workspaces.maxLoaded: must be positive, got 0\n"+
+		"workspaces.idleTimeout: must not be negative, got -1m0s")
+	assert.NoError(t, Default().Validate())
+}
+
+func TestValidate_RouteTimeouts(t *testing.T) {
+	cfg := Default()
+	cfg.Server.WriteTimeout = 20 * time.Second
+	cfg.Server.ReportTimeout = 20 * time.Second
+
+	err := cfg.Validate()
+
+	assert.EqualError(t, err, "invalid configuration: "+
+		"server.reportTimeout: must be shorter than server.writeTimeout (20s), got 20s")
+}
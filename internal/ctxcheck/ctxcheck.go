@@ -0,0 +1,19 @@
+// Package ctxcheck lets long loops over records notice that their request
+// has been abandoned without checking the context on every iteration.
+package ctxcheck
+
+import "context"
+
+// Interval is how many items a loop processes between checks for
+// cancellation.
+const Interval = 1024
+
+// Canceled reports ctx's error once every Interval items, so long scans and
+// computations stop soon after their request is abandoned. i is the index of
+// the current item.
+func Canceled(ctx context.Context, i int) error {
+	if i%Interval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,31 @@
+package ctxcheck
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		i             int
+		expectedError error
+	}{
+		{name: "first item", ctx: ctx, i: 0, expectedError: context.Canceled},
+		{name: "between checks", ctx: ctx, i: 1, expectedError: nil},
+		{name: "next check", ctx: ctx, i: Interval, expectedError: context.Canceled},
+		{name: "not canceled", ctx: context.Background(), i: 0, expectedError: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, Canceled(tt.ctx, tt.i))
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Package export renders referral networks in graph interchange formats
+// understood by tools such as Graphviz and Gephi.
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"surfe/internal/models"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatGraphML Format = "graphml"
+	FormatJSON    Format = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown graph format")
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case FormatDOT, FormatGraphML, FormatJSON:
+		return format, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatDOT:
+		return "text/vnd.graphviz; charset=UTF-8"
+	case FormatGraphML:
+		return "application/graphml+xml; charset=UTF-8"
+	}
+	return "application/json"
+}
+
+// WriteGraph writes the network to w in the given format.
+func WriteGraph(w io.Writer, network *models.ReferralNetwork, format Format) error {
+	switch format {
+	case FormatDOT:
+		return WriteDOT(w, network)
+	case FormatGraphML:
+		return WriteGraphML(w, network)
+	case FormatJSON:
+		return WriteJSONGraph(w, network)
+	}
+	return ErrUnknownFormat
+}
+
+// WriteDOT writes the network as a Graphviz digraph.
+func WriteDOT(w io.Writer, network *models.ReferralNetwork) error {
+	if _, err := fmt.Fprintln(w, "digraph referrals {"); err != nil {
+		return err
+	}
+	for _, node := range network.Nodes {
+		if _, err := fmt.Fprintf(w, "  %d [label=%s, createdAt=%s];\n",
+			node.ID, strconv.Quote(node.Name), strconv.Quote(formatTime(node.CreatedAt))); err != nil {
+			return err
+		}
+	}
+	for _, edge := range network.Edges {
+		if _, err := fmt.Fprintf(w, "  %d -> %d [referredAt=%s];\n",
+			edge.Source, edge.Target, strconv.Quote(formatTime(edge.ReferredAt))); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the network as a directed GraphML document.
+func WriteGraphML(w io.Writer, network *models.ReferralNetwork) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "name", For: "node", AttrName: "name", AttrType: "string"},
+			{ID: "createdAt", For: "node", AttrName: "createdAt", AttrType: "string"},
+			{ID: "referredAt", For: "edge", AttrName: "referredAt", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "referrals", EdgeDefault: "directed"},
+	}
+	for _, node := range network.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: strconv.Itoa(node.ID),
+			Data: []graphMLData{
+				{Key: "name", Value: node.Name},
+				{Key: "createdAt", Value: formatTime(node.CreatedAt)},
+			},
+		})
+	}
+	for _, edge := range network.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: strconv.Itoa(edge.Source),
+			Target: strconv.Itoa(edge.Target),
+			Data: []graphMLData{
+				{Key: "referredAt", Value: formatTime(edge.ReferredAt)},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// JSONGraphDocument is a referral graph in the JSON Graph Format.
+type JSONGraphDocument struct {
+	Graph jsonGraph `json:"graph"`
+}
+
+type jsonGraph struct {
+	Directed bool                     `json:"directed"`
+	Type     string                   `json:"type"`
+	Nodes    map[string]jsonGraphNode `json:"nodes"`
+	Edges    []jsonGraphEdge          `json:"edges"`
+}
+
+type jsonGraphNode struct {
+	Label    string            `json:"label"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type jsonGraphEdge struct {
+	Source   string            `json:"source"`
+	Target   string            `json:"target"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// WriteJSONGraph writes the network in the JSON Graph Format.
+func WriteJSONGraph(w io.Writer, network *models.ReferralNetwork) error {
+	doc := JSONGraphDocument{
+		Graph: jsonGraph{
+			Directed: true,
+			Type:     "referrals",
+			Nodes:    make(map[string]jsonGraphNode, len(network.Nodes)),
+			Edges:    make([]jsonGraphEdge, 0, len(network.Edges)),
+		},
+	}
+	for _, node := range network.Nodes {
+		doc.Graph.Nodes[strconv.Itoa(node.ID)] = jsonGraphNode{
+			Label:    node.Name,
+			Metadata: map[string]string{"createdAt": formatTime(node.CreatedAt)},
+		}
+	}
+	for _, edge := range network.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, jsonGraphEdge{
+			Source:   strconv.Itoa(edge.Source),
+			Target:   strconv.Itoa(edge.Target),
+			Metadata: map[string]string{"referredAt": formatTime(edge.ReferredAt)},
+		})
+	}
+	return json.NewEncoder(w).Encode(doc)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,111 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"surfe/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testNetwork() *models.ReferralNetwork {
+	fixedTime := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	return &models.ReferralNetwork{
+		Nodes: []models.ReferralNode{
+			{ID: 1, Name: `John "JD" Doe`, CreatedAt: fixedTime},
+			{ID: 2, Name: "Jane & Co", CreatedAt: fixedTime},
+		},
+		Edges: []models.ReferralEdge{
+			{Source: 1, Target: 2, ReferredAt: fixedTime.Add(time.Minute)},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expected      Format
+		expectedError bool
+	}{
+		{name: "dot", input: "dot", expected: FormatDOT},
+		{name: "graphml", input: "graphml", expected: FormatGraphML},
+		{name: "json", input: "json", expected: FormatJSON},
+		{name: "unknown", input: "gexf", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseFormat(tt.input)
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, ErrUnknownFormat)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		expected string
+	}{
+		{
+			name:   "dot",
+			format: FormatDOT,
+			expected: "digraph referrals {\n" +
+				"  1 [label=\"John \\\"JD\\\" Doe\", createdAt=\"2024-03-11T20:00:00Z\"];\n" +
+				"  2 [label=\"Jane & Co\", createdAt=\"2024-03-11T20:00:00Z\"];\n" +
+				"  1 -> 2 [referredAt=\"2024-03-11T20:01:00Z\"];\n" +
+				"}\n",
+		},
+		{
+			name:   "graphml",
+			format: FormatGraphML,
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="name" for="node" attr.name="name" attr.type="string"></key>
+  <key id="createdAt" for="node" attr.name="createdAt" attr.type="string"></key>
+  <key id="referredAt" for="edge" attr.name="referredAt" attr.type="string"></key>
+  <graph id="referrals" edgedefault="directed">
+    <node id="1">
+      <data key="name">John &#34;JD&#34; Doe</data>
+      <data key="createdAt">2024-03-11T20:00:00Z</data>
+    </node>
+    <node id="2">
+      <data key="name">Jane &amp; Co</data>
+      <data key="createdAt">2024-03-11T20:00:00Z</data>
+    </node>
+    <edge source="1" target="2">
+      <data key="referredAt">2024-03-11T20:01:00Z</data>
+    </edge>
+  </graph>
+</graphml>
+`,
+		},
+		{
+			name:   "json graph format",
+			format: FormatJSON,
+			expected: `{"graph":{"directed":true,"type":"referrals","nodes":{` +
+				`"1":{"label":"John \"JD\" Doe","metadata":{"createdAt":"2024-03-11T20:00:00Z"}},` +
+				`"2":{"label":"Jane \u0026 Co","metadata":{"createdAt":"2024-03-11T20:00:00Z"}}},` +
+				`"edges":[{"source":"1","target":"2","metadata":{"referredAt":"2024-03-11T20:01:00Z"}}]}}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteGraph(&buf, testNetwork(), tt.format)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
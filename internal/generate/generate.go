@@ -0,0 +1,435 @@
+// Package generate produces synthetic users and actions for load tests and
+// demos. The data is shaped like production data, with a referral forest of
+// configurable shape and activity that follows realistic transitions between
+// action types, but every name and timestamp is made up. The same Config,
+// including its Seed, always produces the same output.
+package generate
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"surfe/internal/models"
+	"time"
+)
+
+const (
+	welcomeType  = "WELCOME"
+	referralType = "REFER_USER"
+)
+
+// Transition is the relative likelihood of an action type following another.
+type Transition struct {
+	To     string
+	Weight float64
+}
+
+// DefaultTransitions is what users do next after each action type: set up
+// their CRM early on, then mostly manage contacts. Referrals are not part of
+// the chain; they are placed by the referral forest.
+var DefaultTransitions = map[string][]Transition{
+	"WELCOME": {
+		{To: "CONNECT_CRM", Weight: 0.40},
+		{To: "ADD_CONTACT", Weight: 0.35},
+		{To: "VIEW_CONTACTS", Weight: 0.25},
+	},
+	"CONNECT_CRM": {
+		{To: "ADD_CONTACT", Weight: 0.50},
+		{To: "VIEW_CONTACTS", Weight: 0.35},
+		{To: "EDIT_CONTACT", Weight: 0.15},
+	},
+	"ADD_CONTACT": {
+		{To: "ADD_CONTACT", Weight: 0.30},
+		{To: "EDIT_CONTACT", Weight: 0.30},
+		{To: "VIEW_CONTACTS", Weight: 0.35},
+		{To: "CONNECT_CRM", Weight: 0.05},
+	},
+	"EDIT_CONTACT": {
+		{To: "ADD_CONTACT", Weight: 0.35},
+		{To: "EDIT_CONTACT", Weight: 0.20},
+		{To: "VIEW_CONTACTS", Weight: 0.42},
+		{To: "CONNECT_CRM", Weight: 0.03},
+	},
+	"VIEW_CONTACTS": {
+		{To: "ADD_CONTACT", Weight: 0.40},
+		{To: "EDIT_CONTACT", Weight: 0.37},
+		{To: "VIEW_CONTACTS", Weight: 0.20},
+		{To: "CONNECT_CRM", Weight: 0.03},
+	},
+}
+
+type Config struct {
+	Users int
+	// Actions is the total number of actions. Every user gets a WELCOME
+	// action and every referred user a REFER_USER action from its
+	// referrer; the rest are spread over users by how long they have been
+	// signed up.
+	Actions int
+	Seed    uint64
+	// Users sign up between Start and End, and act until End.
+	Start time.Time
+	End   time.Time
+	// ReferredShare is the fraction of users who were referred by an
+	// earlier user.
+	ReferredShare float64
+	// MaxDepth bounds how many referrals separate a user from the root of
+	// their referral tree.
+	MaxDepth int
+	// Preferential is the probability that a referrer is chosen in
+	// proportion to the referrals they already made rather than uniformly,
+	// which produces fewer, wider trees as it approaches 1.
+	Preferential float64
+	// Transitions drives the sequence of each user's actions, starting at
+	// WELCOME.
+	Transitions map[string][]Transition
+}
+
+// DefaultConfig returns a dataset the size of the checked-in one.
+func DefaultConfig() Config {
+	return Config{
+		Users:         1000,
+		Actions:       25000,
+		Seed:          1,
+		Start:         time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:           time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		ReferredShare: 0.35,
+		MaxDepth:      6,
+		Preferential:  0.5,
+		Transitions:   DefaultTransitions,
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Users <= 0 {
+		errs = append(errs, fmt.Errorf("users: must be positive, got %d", c.Users))
+	}
+	if c.Actions < c.Users {
+		errs = append(errs, fmt.Errorf("actions: must be at least the number of users (%d), got %d", c.Users, c.Actions))
+	}
+	if !c.End.After(c.Start) {
+		errs = append(errs, fmt.Errorf("end: must be after start (%s), got %s", c.Start.Format(time.RFC3339), c.End.Format(time.RFC3339)))
+	}
+	if c.ReferredShare < 0 || c.ReferredShare > 1 {
+		errs = append(errs, fmt.Errorf("referredShare: must be between 0 and 1, got %g", c.ReferredShare))
+	}
+	if c.MaxDepth < 0 {
+		errs = append(errs, fmt.Errorf("maxDepth: must not be negative, got %d", c.MaxDepth))
+	}
+	if c.Preferential < 0 || c.Preferential > 1 {
+		errs = append(errs, fmt.Errorf("preferential: must be between 0 and 1, got %g", c.Preferential))
+	}
+	if len(c.Transitions[welcomeType]) == 0 {
+		errs = append(errs, errors.New("transitions: must have transitions from WELCOME"))
+	}
+	for from, transitions := range c.Transitions {
+		for _, t := range transitions {
+			if t.Weight <= 0 {
+				errs = append(errs, fmt.Errorf("transitions: %s to %s must have a positive weight", from, t.To))
+			}
+			if len(c.Transitions[t.To]) == 0 {
+				errs = append(errs, fmt.Errorf("transitions: %s leads to %s, which has no transitions", from, t.To))
+			}
+			if t.To == welcomeType || t.To == referralType {
+				errs = append(errs, fmt.Errorf("transitions: %s cannot follow %s", t.To, from))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid generator configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Summary describes the generated data.
+type Summary struct {
+	Users     int `json:"users"`
+	Actions   int `json:"actions"`
+	Referrals int `json:"referrals"`
+	Trees     int `json:"trees"`
+	MaxDepth  int `json:"maxDepth"`
+}
+
+// Write generates the data described by cfg and writes the users and the
+// actions as JSON arrays to users and actions. IDs start at 1, as a
+// targetUser of 0 means the action does not target a user. Records are
+// streamed, so memory use grows with the number of users only.
+func Write(users, actions io.Writer, cfg Config) (*Summary, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	g := newGenerator(cfg)
+	g.planReferrals()
+	if cfg.Actions < cfg.Users+g.summary.Referrals {
+		return nil, fmt.Errorf("invalid generator configuration: actions: must be at least %d for %d users and %d referrals, got %d",
+			cfg.Users+g.summary.Referrals, cfg.Users, g.summary.Referrals, cfg.Actions)
+	}
+	g.planActivity()
+
+	if err := g.writeUsers(users); err != nil {
+		return nil, fmt.Errorf("write users: %w", err)
+	}
+	if err := g.writeActions(actions); err != nil {
+		return nil, fmt.Errorf("write actions: %w", err)
+	}
+	return &g.summary, nil
+}
+
+type generator struct {
+	cfg     Config
+	rng     *rand.Rand
+	summary Summary
+
+	// Indexed by user, in signup order; the user ID is the index plus one.
+	createdAt  []time.Time
+	referrer   []int32 // -1 for the roots of referral trees
+	referredAt []time.Time
+	// The users referred by user i are children[childStart[i]:childStart[i+1]].
+	childStart []int32
+	children   []int32
+	// extra is the number of actions of each user besides their WELCOME
+	// and referrals.
+	extra []int32
+}
+
+func newGenerator(cfg Config) *generator {
+	return &generator{
+		cfg: cfg,
+		rng: rand.New(rand.NewPCG(cfg.Seed, 0x5eed)),
+	}
+}
+
+// span returns a random duration up to d.
+func (g *generator) span(d time.Duration) time.Duration {
+	return time.Duration(g.rng.Float64() * float64(d))
+}
+
+// planReferrals spreads signups over the period and decides who referred
+// whom, building the referral forest one user at a time.
+func (g *generator) planReferrals() {
+	n := g.cfg.Users
+	period := g.cfg.End.Sub(g.cfg.Start)
+	g.createdAt = make([]time.Time, n)
+	g.referrer = make([]int32, n)
+	g.referredAt = make([]time.Time, n)
+	depth := make([]uint16, n)
+
+	// eligible holds the users who may still refer someone without
+	// exceeding MaxDepth. tickets holds them too, plus one more entry for
+	// every referral they made, so drawing from it favours prolific
+	// referrers.
+	var eligible, tickets []int32
+	for i := 0; i < n; i++ {
+		g.createdAt[i] = g.cfg.Start.Add(time.Duration((float64(i) + g.rng.Float64()) / float64(n) * float64(period))).Truncate(time.Millisecond)
+		g.referrer[i] = -1
+
+		if len(eligible) > 0 && g.rng.Float64() < g.cfg.ReferredShare {
+			var ref int32
+			if g.rng.Float64() < g.cfg.Preferential {
+				ref = tickets[g.rng.IntN(len(tickets))]
+			} else {
+				ref = eligible[g.rng.IntN(len(eligible))]
+			}
+			g.referrer[i] = ref
+			depth[i] = depth[ref] + 1
+			// The referral is sent after the referrer signed up and
+			// before the referred user does.
+			window := g.createdAt[i].Sub(g.createdAt[ref])
+			g.referredAt[i] = g.createdAt[i].Add(-g.span(window)).Truncate(time.Millisecond)
+			if g.referredAt[i].Before(g.createdAt[ref]) {
+				g.referredAt[i] = g.createdAt[ref]
+			}
+			tickets = append(tickets, ref)
+			g.summary.Referrals++
+			g.summary.MaxDepth = max(g.summary.MaxDepth, int(depth[i]))
+		} else {
+			g.summary.Trees++
+		}
+		if int(depth[i]) < g.cfg.MaxDepth {
+			eligible = append(eligible, int32(i))
+			tickets = append(tickets, int32(i))
+		}
+	}
+
+	g.childStart = make([]int32, n+1)
+	for _, ref := range g.referrer {
+		if ref >= 0 {
+			g.childStart[ref+1]++
+		}
+	}
+	for i := 0; i < n; i++ {
+		g.childStart[i+1] += g.childStart[i]
+	}
+	g.children = make([]int32, g.summary.Referrals)
+	next := slices.Clone(g.childStart[:n])
+	for i, ref := range g.referrer {
+		if ref >= 0 {
+			g.children[next[ref]] = int32(i)
+			next[ref]++
+		}
+	}
+	g.summary.Users = n
+}
+
+// planActivity spreads the actions that are neither WELCOME nor referrals
+// over users, in proportion to how long they have been signed up times a
+// random activity level, so that a few users are much more active than
+// most.
+func (g *generator) planActivity() {
+	n := g.cfg.Users
+	remaining := g.cfg.Actions - n - g.summary.Referrals
+	weights := make([]float64, n)
+	total := 0.0
+	for i := range weights {
+		tenure := g.cfg.End.Sub(g.createdAt[i]).Hours()
+		weights[i] = g.rng.ExpFloat64() * tenure
+		total += weights[i]
+	}
+
+	g.extra = make([]int32, n)
+	assigned := 0
+	for i, w := range weights {
+		count := 0
+		if total > 0 {
+			count = int(float64(remaining) * w / total)
+		}
+		g.extra[i] = int32(count)
+		assigned += count
+	}
+	// Rounding down leaves fewer than n actions over.
+	for i := 0; assigned < remaining; i = (i + 1) % n {
+		g.extra[i]++
+		assigned++
+	}
+	g.summary.Actions = g.cfg.Actions
+}
+
+func (g *generator) writeUsers(w io.Writer) error {
+	out := newArrayWriter(w)
+	for i := range g.createdAt {
+		user := models.User{
+			ID:        i + 1,
+			Name:      firstNames[g.rng.IntN(len(firstNames))],
+			CreatedAt: g.createdAt[i],
+		}
+		if err := out.write(user); err != nil {
+			return err
+		}
+	}
+	return out.close()
+}
+
+// writeActions writes each user's actions in time order: WELCOME at signup,
+// then a walk over the transitions with the user's referrals interleaved at
+// the times they were sent.
+func (g *generator) writeActions(w io.Writer) error {
+	out := newArrayWriter(w)
+	nextID := 1
+	emit := func(action models.Action) error {
+		action.ID = nextID
+		nextID++
+		return out.write(action)
+	}
+
+	var times []time.Time
+	for i := range g.createdAt {
+		userID := i + 1
+		if err := emit(models.Action{Type: welcomeType, UserID: userID, CreatedAt: g.createdAt[i]}); err != nil {
+			return err
+		}
+
+		active := g.cfg.End.Sub(g.createdAt[i])
+		times = times[:0]
+		for j := int32(0); j < g.extra[i]; j++ {
+			times = append(times, g.createdAt[i].Add(g.span(active)).Truncate(time.Millisecond))
+		}
+		slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+
+		referred := g.children[g.childStart[i]:g.childStart[i+1]]
+		slices.SortFunc(referred, func(a, b int32) int { return g.referredAt[a].Compare(g.referredAt[b]) })
+		state := welcomeType
+		r := 0
+		for _, t := range times {
+			for ; r < len(referred) && !g.referredAt[referred[r]].After(t); r++ {
+				if err := g.emitReferral(emit, userID, referred[r]); err != nil {
+					return err
+				}
+			}
+			state = g.next(state)
+			if err := emit(models.Action{Type: state, UserID: userID, CreatedAt: t}); err != nil {
+				return err
+			}
+		}
+		for ; r < len(referred); r++ {
+			if err := g.emitReferral(emit, userID, referred[r]); err != nil {
+				return err
+			}
+		}
+	}
+	return out.close()
+}
+
+func (g *generator) emitReferral(emit func(models.Action) error, userID int, referred int32) error {
+	return emit(models.Action{
+		Type:       referralType,
+		UserID:     userID,
+		TargetUser: models.UserRef(int(referred) + 1),
+		CreatedAt:  g.referredAt[referred],
+	})
+}
+
+// next draws the action type that follows from.
+func (g *generator) next(from string) string {
+	transitions := g.cfg.Transitions[from]
+	total := 0.0
+	for _, t := range transitions {
+		total += t.Weight
+	}
+	x := g.rng.Float64() * total
+	for _, t := range transitions {
+		if x < t.Weight {
+			return t.To
+		}
+		x -= t.Weight
+	}
+	return transitions[len(transitions)-1].To
+}
+
+// arrayWriter streams records as a JSON array with one record per line.
+type arrayWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func newArrayWriter(w io.Writer) *arrayWriter {
+	return &arrayWriter{w: bufio.NewWriterSize(w, 1<<20)}
+}
+
+func (a *arrayWriter) write(v any) error {
+	record, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if a.count == 0 {
+		sep = "[\n  "
+	}
+	a.count++
+	a.w.WriteString(sep)
+	_, err = a.w.Write(record)
+	return err
+}
+
+func (a *arrayWriter) close() error {
+	if a.count == 0 {
+		a.w.WriteString("[")
+	}
+	a.w.WriteString("\n]\n")
+	return a.w.Flush()
+}
@@ -0,0 +1,169 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"surfe/internal/actiontypes"
+	"surfe/internal/models"
+	"surfe/internal/validate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generate(t *testing.T, cfg Config) (*Summary, []byte, []byte) {
+	var users, actions bytes.Buffer
+	summary, err := Write(&users, &actions, cfg)
+	require.NoError(t, err)
+	return summary, users.Bytes(), actions.Bytes()
+}
+
+func TestWrite(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Users = 2000
+	cfg.Actions = 40000
+
+	summary, usersJSON, actionsJSON := generate(t, cfg)
+
+	var users []models.User
+	var actions []models.Action
+	require.NoError(t, json.Unmarshal(usersJSON, &users))
+	require.NoError(t, json.Unmarshal(actionsJSON, &actions))
+
+	assert.Len(t, users, cfg.Users)
+	assert.Len(t, actions, cfg.Actions)
+	assert.Equal(t, cfg.Users, summary.Users)
+	assert.Equal(t, cfg.Actions, summary.Actions)
+	assert.Equal(t, cfg.Users, summary.Trees+summary.Referrals)
+	assert.InDelta(t, cfg.ReferredShare, float64(summary.Referrals)/float64(cfg.Users), 0.05)
+	assert.LessOrEqual(t, summary.MaxDepth, cfg.MaxDepth)
+
+	report := validate.Dataset(users, actions, actiontypes.Default(), cfg.End)
+	assert.Empty(t, report.Findings)
+
+	referred := make(map[int]int)
+	for _, action := range actions {
+		if action.Type == referralType {
+			referred[*action.TargetUser]++
+		}
+	}
+	assert.Len(t, referred, summary.Referrals)
+	for userID, count := range referred {
+		assert.Equal(t, 1, count, "user %d referred more than once", userID)
+	}
+}
+
+func TestWrite_Deterministic(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Users = 300
+	cfg.Actions = 3000
+
+	_, users1, actions1 := generate(t, cfg)
+	_, users2, actions2 := generate(t, cfg)
+	assert.Equal(t, users1, users2)
+	assert.Equal(t, actions1, actions2)
+
+	cfg.Seed++
+	_, users3, actions3 := generate(t, cfg)
+	assert.NotEqual(t, users1, users3)
+	assert.NotEqual(t, actions1, actions3)
+}
+
+func TestWrite_Transitions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Users = 500
+	cfg.Actions = 100000
+	cfg.ReferredShare = 0
+	cfg.Transitions = map[string][]Transition{
+		"WELCOME":     {{To: "ADD_CONTACT", Weight: 1}},
+		"ADD_CONTACT": {{To: "ADD_CONTACT", Weight: 3}, {To: "CONNECT_CRM", Weight: 1}},
+		"CONNECT_CRM": {{To: "ADD_CONTACT", Weight: 1}},
+	}
+
+	summary, _, actionsJSON := generate(t, cfg)
+	var actions []models.Action
+	require.NoError(t, json.Unmarshal(actionsJSON, &actions))
+
+	// Actions are written per user in time order.
+	counts := make(map[string]int)
+	total := 0
+	for i := 1; i < len(actions); i++ {
+		if actions[i-1].UserID == actions[i].UserID && actions[i-1].Type == "ADD_CONTACT" {
+			counts[actions[i].Type]++
+			total++
+		}
+	}
+	assert.Zero(t, summary.Referrals)
+	assert.InDelta(t, 0.75, float64(counts["ADD_CONTACT"])/float64(total), 0.02)
+	assert.InDelta(t, 0.25, float64(counts["CONNECT_CRM"])/float64(total), 0.02)
+}
+
+func TestWrite_ReferralShape(t *testing.T) {
+	shape := func(maxDepth int, preferential float64) (depth, fanout int) {
+		cfg := DefaultConfig()
+		cfg.Users = 3000
+		cfg.Actions = 10000
+		cfg.ReferredShare = 0.6
+		cfg.MaxDepth = maxDepth
+		cfg.Preferential = preferential
+
+		summary, _, actionsJSON := generate(t, cfg)
+		var actions []models.Action
+		require.NoError(t, json.Unmarshal(actionsJSON, &actions))
+		referrals := make(map[int]int)
+		for _, action := range actions {
+			if action.Type == referralType {
+				referrals[action.UserID]++
+				fanout = max(fanout, referrals[action.UserID])
+			}
+		}
+		return summary.MaxDepth, fanout
+	}
+
+	flatDepth, _ := shape(1, 0)
+	uniformDepth, uniformFanout := shape(20, 0)
+	preferentialDepth, preferentialFanout := shape(20, 1)
+
+	assert.Equal(t, 1, flatDepth)
+	assert.Greater(t, uniformDepth, 2)
+	assert.LessOrEqual(t, uniformDepth, 20)
+	assert.LessOrEqual(t, preferentialDepth, 20)
+	assert.Greater(t, preferentialFanout, 2*uniformFanout, "preferential attachment makes wider trees")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Users = 0
+	cfg.Actions = -1
+	cfg.End = cfg.Start
+	cfg.ReferredShare = 1.5
+	cfg.MaxDepth = -1
+	cfg.Preferential = -0.1
+	cfg.Transitions = map[string][]Transition{
+		"WELCOME": {{To: "JUMP", Weight: 1}},
+	}
+
+	err := cfg.Validate()
+
+	assert.EqualError(t, err, "invalid generator configuration: "+
+		"users: must be positive, got 0\n"+
+		"actions: must be at least the number of users (0), got -1\n"+
+		"end: must be after start (2020-01-01T00:00:00Z), got 2020-01-01T00:00:00Z\n"+
+		"referredShare: must be between 0 and 1, got 1.5\n"+
+		"maxDepth: must not be negative, got -1\n"+
+		"preferential: must be between 0 and 1, got -0.1\n"+
+		"transitions: WELCOME leads to JUMP, which has no transitions")
+	defaults := DefaultConfig()
+	assert.NoError(t, defaults.Validate())
+}
+
+func TestWrite_TooFewActions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Actions = cfg.Users
+
+	_, err := Write(&bytes.Buffer{}, &bytes.Buffer{}, cfg)
+
+	assert.ErrorContains(t, err, "actions: must be at least")
+}
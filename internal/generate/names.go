@@ -0,0 +1,14 @@
+package generate
+
+// firstNames are given to generated users. They are common given names and
+// do not refer to real users.
+var firstNames = []string{
+	"Ada", "Alan", "Alice", "Amara", "Ana", "Anders", "Aria", "Arjun",
+	"Ben", "Bianca", "Carlos", "Chen", "Chloe", "Dara", "David", "Elena",
+	"Emil", "Emma", "Ezra", "Fatima", "Felix", "Grace", "Hana", "Hugo",
+	"Ines", "Isaac", "Ivy", "Jonas", "Julia", "Kai", "Kenji", "Lara",
+	"Leo", "Lina", "Luca", "Maya", "Mei", "Milan", "Nadia", "Noah",
+	"Nora", "Omar", "Oscar", "Priya", "Rafael", "Rosa", "Sami", "Sara",
+	"Sofia", "Tariq", "Theo", "Uma", "Victor", "Wen", "Yara", "Yusuf",
+	"Zara", "Zoe", "Malik", "Freya", "Mateo", "Aisha", "Lucas", "Iris",
+}
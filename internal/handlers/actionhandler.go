@@ -3,11 +3,15 @@ package handlers
 import (
 	"net/http"
 	"strings"
+	"surfe/internal/models"
 	"surfe/internal/services"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
+const defaultActivationType = "CONNECT_CRM"
+
 type ActionHandler struct {
 	actionService services.ActionService
 }
@@ -18,21 +22,71 @@ func NewActionHandler(actionService services.ActionService) *ActionHandler {
 	}
 }
 
+// @Summary List action types
+// @Description List every known action type with its description and category
+// @Tags actions
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Success 200 {array} models.ActionType
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 500 {object} models.Problem
+// @Router /v1/action-types [get]
+func (h *ActionHandler) GetActionTypes(c echo.Context) error {
+	actionTypes, err := h.actionService.GetActionTypes(c.Request().Context())
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, actionTypes)
+}
+
+// @Summary Get action type
+// @Description Get the description and category of an action type
+// @Tags actions
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param type path string true "Action Type"
+// @Success 200 {object} models.ActionType
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v1/action-types/{type} [get]
+func (h *ActionHandler) GetActionType(c echo.Context) error {
+	actionType, err := h.actionService.GetActionType(c.Request().Context(), strings.ToUpper(c.Param("type")))
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, actionType)
+}
+
 // @Summary Get next action probabilities
 // @Description Get probabilities of next actions based on current action type
 // @Tags actions
 // @Accept json
 // @Produce json
+// @Produce application/problem+json
 // @Param type path string true "Action Type"
 // @Success 200 {object} models.ActionProbability
-// @Failure 500 {object} error
-// @Router /actions/{type}/next [get]
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v1/actions/{type}/next [get]
 func (h *ActionHandler) GetNextActionProbabilities(c echo.Context) error {
 	actionType := strings.ToUpper(c.Param("type"))
 
-	probabilities, err := h.actionService.GetNextActionProbabilities(actionType)
+	probabilities, err := h.actionService.GetNextActionProbabilities(c.Request().Context(), actionType)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+		return errorProblem(c, err)
 	}
 
 	return c.JSON(http.StatusOK, probabilities)
@@ -43,14 +97,101 @@ func (h *ActionHandler) GetNextActionProbabilities(c echo.Context) error {
 // @Tags actions
 // @Accept json
 // @Produce json
+// @Produce application/problem+json
 // @Success 200 {object} map[int]int
-// @Failure 500 {object} error
-// @Router /actions/referral [get]
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v1/actions/referral [get]
 func (h *ActionHandler) GetReferralIndex(c echo.Context) error {
-	referralIndex, err := h.actionService.GetReferralIndex()
+	referralIndex, err := h.actionService.GetReferralIndex(c.Request().Context())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+		return errorProblem(c, err)
 	}
 
 	return c.JSON(http.StatusOK, referralIndex)
 }
+
+// @Summary Get referral conversion quality
+// @Description Get, for each referrer, how many referred users activated and the median time to activation
+// @Tags referrals
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param activation query string false "Comma-separated activation action types" default(CONNECT_CRM)
+// @Success 200 {array} models.ReferralQuality
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v1/referrals/quality [get]
+func (h *ActionHandler) GetReferralQuality(c echo.Context) error {
+	quality, err := h.actionService.GetReferralQuality(c.Request().Context(), activationTypes(c))
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, quality)
+}
+
+// activationTypes returns the action types in the activation query
+// parameter, or the default activation type when there are none.
+func activationTypes(c echo.Context) []string {
+	var activationTypes []string
+	for _, actionType := range strings.Split(c.QueryParam("activation"), ",") {
+		if actionType = strings.TrimSpace(actionType); actionType != "" {
+			activationTypes = append(activationTypes, strings.ToUpper(actionType))
+		}
+	}
+	if len(activationTypes) == 0 {
+		activationTypes = []string{defaultActivationType}
+	}
+	return activationTypes
+}
+
+// @Summary Record action
+// @Description Record a new user action. The user, and the target user of actions that refer to one, must exist, and the action must pass the checks of `surfe validate`. Referrals are reflected in the referral index immediately.
+// @Tags actions
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param action body models.Action true "Action"
+// @Success 201 {object} models.Action
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v1/actions [post]
+func (h *ActionHandler) RecordAction(c echo.Context) error {
+	action, invalid := bindAction(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	stored, err := h.actionService.RecordAction(userContext(c, action.UserID), action)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, stored)
+}
+
+// bindAction reads the action to record from the request body, or describes
+// why it is invalid.
+func bindAction(c echo.Context) (models.Action, string) {
+	var action models.Action
+	if err := c.Bind(&action); err != nil {
+		return action, "Invalid action"
+	}
+
+	action.Type = strings.ToUpper(strings.TrimSpace(action.Type))
+	if action.Type == "" {
+		return action, "Action type is required"
+	}
+	if action.CreatedAt.IsZero() {
+		action.CreatedAt = time.Now().UTC()
+	}
+	return action, ""
+}
@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -16,16 +23,56 @@ type MockActionService struct {
 	mock.Mock
 }
 
-func (m *MockActionService) GetNextActionProbabilities(actionType string) (map[string]float64, error) {
+func (m *MockActionService) GetActionTypes(ctx context.Context) ([]models.ActionType, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ActionType), args.Error(1)
+}
+
+func (m *MockActionService) GetActionType(ctx context.Context, name string) (*models.ActionType, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ActionType), args.Error(1)
+}
+
+func (m *MockActionService) GetNextActionProbabilities(ctx context.Context, actionType string) (map[string]float64, error) {
 	args := m.Called(actionType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).(map[string]float64), args.Error(1)
 }
 
-func (m *MockActionService) GetReferralIndex() (map[int]int, error) {
+func (m *MockActionService) GetNextActions(ctx context.Context, actionType string) (*models.NextActions, error) {
+	args := m.Called(actionType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.NextActions), args.Error(1)
+}
+
+func (m *MockActionService) GetReferralIndex(ctx context.Context) (map[int]int, error) {
 	args := m.Called()
 	return args.Get(0).(map[int]int), args.Error(1)
 }
 
+func (m *MockActionService) GetReferralQuality(ctx context.Context, activationTypes []string) ([]models.ReferralQuality, error) {
+	args := m.Called(activationTypes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ReferralQuality), args.Error(1)
+}
+
+func (m *MockActionService) RecordAction(ctx context.Context, action models.Action) (models.Action, error) {
+	args := m.Called(action)
+	return args.Get(0).(models.Action), args.Error(1)
+}
+
 func TestGetNextActionProbabilities(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -49,15 +96,21 @@ func TestGetNextActionProbabilities(t *testing.T) {
 				"REFER_USER":   0.25,
 			},
 		},
+		{
+			name:           "unknown action type",
+			actionType:     "LOGUOT",
+			mockResponse:   nil,
+			mockError:      fmt.Errorf("action type LOGUOT: %w", apperrors.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   problemBody(http.StatusNotFound, "action type LOGUOT: not found"),
+		},
 		{
 			name:           "service error",
 			actionType:     "INVALID",
 			mockResponse:   nil,
 			mockError:      assert.AnError,
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody: map[string]interface{}{
-				"error": "Internal server error",
-			},
+			expectedBody:   problemBody(http.StatusInternalServerError, "Internal server error"),
 		},
 	}
 
@@ -124,9 +177,7 @@ func TestGetReferralIndex(t *testing.T) {
 			mockResponse:   nil,
 			mockError:      assert.AnError,
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody: map[string]interface{}{
-				"error": "Internal server error",
-			},
+			expectedBody:   problemBody(http.StatusInternalServerError, "Internal server error"),
 		},
 	}
 
@@ -161,3 +212,354 @@ func TestGetReferralIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestGetReferralQuality(t *testing.T) {
+	median := 3600.0
+	tests := []struct {
+		name            string
+		query           string
+		activationTypes []string
+		mockResponse    []models.ReferralQuality
+		mockError       error
+		expectedStatus  int
+		expectedBody    interface{}
+	}{
+		{
+			name:            "default activation type",
+			query:           "",
+			activationTypes: []string{"CONNECT_CRM"},
+			mockResponse: []models.ReferralQuality{
+				{ReferrerID: 1, Referred: 2, Activated: 1, ActivationRate: 0.5, MedianActivationSeconds: &median},
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusOK,
+			expectedBody: []interface{}{
+				map[string]interface{}{
+					"referrerId":              float64(1),
+					"referred":                float64(2),
+					"activated":               float64(1),
+					"activationRate":          0.5,
+					"medianActivationSeconds": float64(3600),
+				},
+			},
+		},
+		{
+			name:            "custom activation types",
+			query:           "?activation=connect_crm,%20add_contact",
+			activationTypes: []string{"CONNECT_CRM", "ADD_CONTACT"},
+			mockResponse:    []models.ReferralQuality{},
+			mockError:       nil,
+			expectedStatus:  http.StatusOK,
+			expectedBody:    []interface{}{},
+		},
+		{
+			name:            "service error",
+			query:           "",
+			activationTypes: []string{"CONNECT_CRM"},
+			mockResponse:    nil,
+			mockError:       assert.AnError,
+			expectedStatus:  http.StatusInternalServerError,
+			expectedBody:    problemBody(http.StatusInternalServerError, "Internal server error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// Mock service
+			mockService := new(MockActionService)
+			mockService.On("GetReferralQuality", tt.activationTypes).Return(tt.mockResponse, tt.mockError)
+
+			// Create handler
+			h := NewActionHandler(mockService)
+
+			// Test
+			err := h.GetReferralQuality(c)
+
+			// Assertions
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			var response interface{}
+			err = json.Unmarshal(rec.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, response)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecordAction(t *testing.T) {
+	fixedTime := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		body           string
+		mockAction     *models.Action
+		mockResponse   models.Action
+		mockError      error
+		expectedStatus int
+		expectedBody   map[string]interface{}
+	}{
+		{
+			name:           "referral recorded",
+			body:           `{"type":"refer_user","userId":1,"targetUser":2,"createdAt":"2024-03-11T20:00:00Z"}`,
+			mockAction:     &models.Action{Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: fixedTime},
+			mockResponse:   models.Action{ID: 7, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: fixedTime},
+			mockError:      nil,
+			expectedStatus: http.StatusCreated,
+			expectedBody: map[string]interface{}{
+				"id":         float64(7),
+				"type":       "REFER_USER",
+				"userId":     float64(1),
+				"targetUser": float64(2),
+				"createdAt":  fixedTime.Format(time.RFC3339),
+			},
+		},
+		{
+			name:           "referral to user 0",
+			body:           `{"type":"REFER_USER","userId":1,"targetUser":0,"createdAt":"2024-03-11T20:00:00Z"}`,
+			mockAction:     &models.Action{Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(0), CreatedAt: fixedTime},
+			mockResponse:   models.Action{ID: 8, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(0), CreatedAt: fixedTime},
+			mockError:      nil,
+			expectedStatus: http.StatusCreated,
+			expectedBody: map[string]interface{}{
+				"id":         float64(8),
+				"type":       "REFER_USER",
+				"userId":     float64(1),
+				"targetUser": float64(0),
+				"createdAt":  fixedTime.Format(time.RFC3339),
+			},
+		},
+		{
+			name:           "missing type",
+			body:           `{"userId":1}`,
+			mockAction:     nil,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "Action type is required"),
+		},
+		{
+			name:           "malformed body",
+			body:           `{"userId":`,
+			mockAction:     nil,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "Invalid action"),
+		},
+		{
+			name:           "referral without target",
+			body:           `{"type":"REFER_USER","userId":1,"createdAt":"2024-03-11T20:00:00Z"}`,
+			mockAction:     &models.Action{Type: "REFER_USER", UserID: 1, CreatedAt: fixedTime},
+			mockResponse:   models.Action{},
+			mockError:      fmt.Errorf("%w: REFER_USER action has no targetUser", apperrors.ErrInvalidArgument),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "invalid argument: REFER_USER action has no targetUser"),
+		},
+		{
+			name:           "target on an action without one",
+			body:           `{"type":"WELCOME","userId":1,"targetUser":2,"createdAt":"2024-03-11T20:00:00Z"}`,
+			mockAction:     &models.Action{Type: "WELCOME", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: fixedTime},
+			mockResponse:   models.Action{},
+			mockError:      fmt.Errorf("%w: targetUser 2 is set on a WELCOME action", apperrors.ErrInvalidArgument),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "invalid argument: targetUser 2 is set on a WELCOME action"),
+		},
+		{
+			name:           "future timestamp",
+			body:           `{"type":"LOGIN","userId":1,"createdAt":"2124-03-11T20:00:00Z"}`,
+			mockAction:     &models.Action{Type: "LOGIN", UserID: 1, CreatedAt: fixedTime.AddDate(100, 0, 0)},
+			mockResponse:   models.Action{},
+			mockError:      fmt.Errorf("%w: createdAt 2124-03-11T20:00:00Z is in the future", apperrors.ErrInvalidArgument),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "invalid argument: createdAt 2124-03-11T20:00:00Z is in the future"),
+		},
+		{
+			name:           "before user created",
+			body:           `{"type":"LOGIN","userId":1,"createdAt":"2024-03-11T20:00:00Z"}`,
+			mockAction:     &models.Action{Type: "LOGIN", UserID: 1, CreatedAt: fixedTime},
+			mockResponse:   models.Action{},
+			mockError:      fmt.Errorf("%w: createdAt 2024-03-11T20:00:00Z is before user 1 was created at 2024-03-12T00:00:00Z", apperrors.ErrInvalidArgument),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "invalid argument: createdAt 2024-03-11T20:00:00Z is before user 1 was created at 2024-03-12T00:00:00Z"),
+		},
+		{
+			name:           "service error",
+			body:           `{"type":"LOGIN","userId":1,"createdAt":"2024-03-11T20:00:00Z"}`,
+			mockAction:     &models.Action{Type: "LOGIN", UserID: 1, CreatedAt: fixedTime},
+			mockResponse:   models.Action{},
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   problemBody(http.StatusInternalServerError, "Internal server error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// Mock service
+			mockService := new(MockActionService)
+			if tt.mockAction != nil {
+				mockService.On("RecordAction", *tt.mockAction).Return(tt.mockResponse, tt.mockError)
+			}
+
+			// Create handler
+			h := NewActionHandler(mockService)
+
+			// Test
+			err := h.RecordAction(c)
+
+			// Assertions
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(rec.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, response)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetActionTypes(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockResponse   []models.ActionType
+		mockError      error
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name: "successful response",
+			mockResponse: []models.ActionType{
+				{Name: "REFER_USER", Description: "User referred the target user", Category: "growth", TargetsUser: true},
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusOK,
+			expectedBody: []interface{}{
+				map[string]interface{}{
+					"name":        "REFER_USER",
+					"description": "User referred the target user",
+					"category":    "growth",
+					"targetsUser": true,
+				},
+			},
+		},
+		{
+			name:           "service error",
+			mockResponse:   nil,
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   problemBody(http.StatusInternalServerError, "Internal server error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// Mock service
+			mockService := new(MockActionService)
+			mockService.On("GetActionTypes").Return(tt.mockResponse, tt.mockError)
+
+			// Create handler
+			h := NewActionHandler(mockService)
+
+			// Test
+			err := h.GetActionTypes(c)
+
+			// Assertions
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			var response interface{}
+			err = json.Unmarshal(rec.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, response)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetActionType(t *testing.T) {
+	tests := []struct {
+		name           string
+		actionType     string
+		mockResponse   *models.ActionType
+		mockError      error
+		expectedStatus int
+		expectedBody   map[string]interface{}
+	}{
+		{
+			name:           "known type",
+			actionType:     "connect_crm",
+			mockResponse:   &models.ActionType{Name: "CONNECT_CRM", Description: "User connected a CRM", Category: "integration"},
+			mockError:      nil,
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"name":        "CONNECT_CRM",
+				"description": "User connected a CRM",
+				"category":    "integration",
+				"targetsUser": false,
+			},
+		},
+		{
+			name:           "unknown type",
+			actionType:     "connect_cmr",
+			mockResponse:   nil,
+			mockError:      fmt.Errorf("action type CONNECT_CMR: %w", apperrors.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   problemBody(http.StatusNotFound, "action type CONNECT_CMR: not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/action-types/:type")
+			c.SetParamNames("type")
+			c.SetParamValues(tt.actionType)
+
+			// Mock service
+			mockService := new(MockActionService)
+			mockService.On("GetActionType", strings.ToUpper(tt.actionType)).Return(tt.mockResponse, tt.mockError)
+
+			// Create handler
+			h := NewActionHandler(mockService)
+
+			// Test
+			err := h.GetActionType(c)
+
+			// Assertions
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(rec.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, response)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
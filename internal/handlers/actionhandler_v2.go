@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary List action types
+// @Description List every known action type with its description and category, ordered by name
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Success 200 {object} models.Envelope{data=[]models.ActionType}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 500 {object} models.Problem
+// @Router /v2/action-types [get]
+func (h *ActionHandler) GetActionTypesV2(c echo.Context) error {
+	actionTypes, err := h.actionService.GetActionTypes(c.Request().Context())
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, listEnvelope(c, actionTypes))
+}
+
+// @Summary Get action type
+// @Description Get the description and category of an action type
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param type path string true "Action Type"
+// @Success 200 {object} models.Envelope{data=models.ActionType}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v2/action-types/{type} [get]
+func (h *ActionHandler) GetActionTypeV2(c echo.Context) error {
+	actionType, err := h.actionService.GetActionType(c.Request().Context(), strings.ToUpper(c.Param("type")))
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, envelope(c, actionType))
+}
+
+// @Summary Get next actions
+// @Description Get how often each action type followed the given one, with the raw counts and unrounded probabilities, most frequent first and by name among equals
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param type path string true "Action Type"
+// @Success 200 {object} models.Envelope{data=models.NextActions}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v2/actions/{type}/next [get]
+func (h *ActionHandler) GetNextActionsV2(c echo.Context) error {
+	nextActions, err := h.actionService.GetNextActions(c.Request().Context(), strings.ToUpper(c.Param("type")))
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, envelope(c, nextActions))
+}
+
+// @Summary Get referral index
+// @Description Get how many users each user has referred, directly or indirectly, ordered by user ID
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Success 200 {object} models.Envelope{data=[]models.ReferralCount}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v2/actions/referral [get]
+func (h *ActionHandler) GetReferralIndexV2(c echo.Context) error {
+	referralIndex, err := h.actionService.GetReferralIndex(c.Request().Context())
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	counts := make([]models.ReferralCount, 0, len(referralIndex))
+	for userID, referrals := range referralIndex {
+		counts = append(counts, models.ReferralCount{UserID: userID, Referrals: referrals})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].UserID < counts[j].UserID
+	})
+
+	return c.JSON(http.StatusOK, listEnvelope(c, counts))
+}
+
+// @Summary Get referral conversion quality
+// @Description Get, for each referrer, how many referred users activated and the median time to activation, ordered by referrer ID
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param activation query string false "Comma-separated activation action types" default(CONNECT_CRM)
+// @Success 200 {object} models.Envelope{data=[]models.ReferralQuality}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v2/referrals/quality [get]
+func (h *ActionHandler) GetReferralQualityV2(c echo.Context) error {
+	quality, err := h.actionService.GetReferralQuality(c.Request().Context(), activationTypes(c))
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, listEnvelope(c, quality))
+}
+
+// @Summary Record action
+// @Description Record a new user action. The user, and the target user of actions that refer to one, must exist, and the action must pass the checks of `surfe validate`. Referrals are reflected in the referral index immediately.
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param action body models.Action true "Action"
+// @Success 201 {object} models.Envelope{data=models.Action}
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v2/actions [post]
+func (h *ActionHandler) RecordActionV2(c echo.Context) error {
+	action, invalid := bindAction(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	stored, err := h.actionService.RecordAction(userContext(c, action.UserID), action)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, envelope(c, stored))
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNextActionsV2(t *testing.T) {
+	tests := []struct {
+		name           string
+		actionType     string
+		mockResponse   *models.NextActions
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:       "successful response",
+			actionType: "login",
+			mockResponse: &models.NextActions{
+				ActionType: "LOGIN",
+				Total:      3,
+				Next: []models.NextAction{
+					{Type: "VIEW_PROFILE", Count: 2, Probability: 2.0 / 3},
+					{Type: "REFER_USER", Count: 1, Probability: 1.0 / 3},
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data":{"actionType":"LOGIN","total":3,"next":[` +
+				`{"type":"VIEW_PROFILE","count":2,"probability":0.6666666666666666},` +
+				`{"type":"REFER_USER","count":1,"probability":0.3333333333333333}]},` +
+				`"meta":{"dataVersion":"v7"},"links":{"self":"/api/v2/actions/login/next"}}`,
+		},
+		{
+			name:           "unknown action type",
+			actionType:     "LOGUOT",
+			mockError:      fmt.Errorf("action type LOGUOT: %w", apperrors.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"action type LOGUOT: not found","instance":"/api/v2/actions/LOGUOT/next"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v2/actions/"+tt.actionType+"/next", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v2/actions/:type/next")
+			c.SetParamNames("type")
+			c.SetParamValues(tt.actionType)
+			c.Response().Header().Set("ETag", `"v7"`)
+
+			mockService := new(MockActionService)
+			mockService.On("GetNextActions", strings.ToUpper(tt.actionType)).Return(tt.mockResponse, tt.mockError)
+
+			err := NewActionHandler(mockService).GetNextActionsV2(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetReferralIndexV2(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/actions/referral", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	mockService := new(MockActionService)
+	mockService.On("GetReferralIndex").Return(map[int]int{12: 0, 3: 2, 7: 1}, nil)
+
+	err := NewActionHandler(mockService).GetReferralIndexV2(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":[{"userId":3,"referrals":2},{"userId":7,"referrals":1},{"userId":12,"referrals":0}],`+
+		`"meta":{"count":3},"links":{"self":"/api/v2/actions/referral"}}`, rec.Body.String())
+}
+
+func TestRecordActionV2(t *testing.T) {
+	fixedTime := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		body           string
+		mockAction     *models.Action
+		mockResponse   models.Action
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "action recorded",
+			body:           `{"type":"refer_user","userId":1,"targetUser":2,"createdAt":"2024-03-11T20:00:00Z"}`,
+			mockAction:     &models.Action{Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: fixedTime},
+			mockResponse:   models.Action{ID: 7, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: fixedTime},
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{"data":{"id":7,"type":"REFER_USER","userId":1,"targetUser":2,"createdAt":"2024-03-11T20:00:00Z"},` +
+				`"meta":{},"links":{"self":"/api/v2/actions"}}`,
+		},
+		{
+			name:           "missing type",
+			body:           `{"userId":1}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Action type is required","instance":"/api/v2/actions"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/actions", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockService := new(MockActionService)
+			if tt.mockAction != nil {
+				mockService.On("RecordAction", *tt.mockAction).Return(tt.mockResponse, nil)
+			}
+
+			err := NewActionHandler(mockService).RecordActionV2(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"surfe/internal/services"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Conditional tags GET responses with an ETag and Last-Modified derived from
+// the data version, and answers 304 Not Modified without running the handler
+// when the client's If-None-Match or If-Modified-Since shows its copy is
+// current. Every response depends only on the request and the data, so the
+// same version means the same body.
+func Conditional(versionService services.VersionService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return next(c)
+			}
+			version := versionService.Version(req.Context())
+			if version == nil {
+				return next(c)
+			}
+
+			etag := `"` + version.Tag + `"`
+			lastModified := version.ModifiedAt.UTC().Format(http.TimeFormat)
+			header := c.Response().Header()
+			header.Set(echo.HeaderLastModified, lastModified)
+			header.Set("ETag", etag)
+
+			if notModified(req, etag, version.ModifiedAt) {
+				return c.NoContent(http.StatusNotModified)
+			}
+
+			// Error responses do not represent the data, so they are not
+			// tagged.
+			c.Response().Before(func() {
+				if c.Response().Status >= http.StatusMultipleChoices {
+					header.Del("ETag")
+					header.Del(echo.HeaderLastModified)
+				}
+			})
+			return next(c)
+		}
+	}
+}
+
+// notModified evaluates the request's preconditions as RFC 9110 section 13.2.2
+// orders them: If-Modified-Since only applies without If-None-Match.
+func notModified(req *http.Request, etag string, modifiedAt time.Time) bool {
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+	ifModifiedSince, err := http.ParseTime(req.Header.Get(echo.HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+	return !modifiedAt.Truncate(time.Second).After(ifModifiedSince)
+}
+
+// etagMatches reports whether any entity tag in an If-None-Match list
+// weakly matches etag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
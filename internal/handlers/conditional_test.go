@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockVersionService is a mock implementation of services.VersionService
+type MockVersionService struct {
+	mock.Mock
+}
+
+func (m *MockVersionService) Version(ctx context.Context) *models.DataVersion {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.DataVersion)
+}
+
+func TestConditional(t *testing.T) {
+	modifiedAt := time.Date(2024, 3, 11, 20, 0, 0, 500, time.UTC)
+	version := &models.DataVersion{Tag: "0a1b2c", ModifiedAt: modifiedAt}
+	lastModified := "Mon, 11 Mar 2024 20:00:00 GMT"
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		version         *models.DataVersion
+		headers         map[string]string
+		expectedStatus  int
+		expectedETag    string
+		expectedHandled bool
+	}{
+		{
+			name:            "tags response",
+			method:          http.MethodGet,
+			path:            "/actions/referral",
+			version:         version,
+			expectedStatus:  http.StatusOK,
+			expectedETag:    `"0a1b2c"`,
+			expectedHandled: true,
+		},
+		{
+			name:           "etag matches",
+			method:         http.MethodGet,
+			path:           "/actions/referral",
+			version:        version,
+			headers:        map[string]string{"If-None-Match": `"ffff", W/"0a1b2c"`},
+			expectedStatus: http.StatusNotModified,
+			expectedETag:   `"0a1b2c"`,
+		},
+		{
+			name:            "etag stale",
+			method:          http.MethodGet,
+			path:            "/actions/referral",
+			version:         version,
+			headers:         map[string]string{"If-None-Match": `"ffff"`},
+			expectedStatus:  http.StatusOK,
+			expectedETag:    `"0a1b2c"`,
+			expectedHandled: true,
+		},
+		{
+			name:           "not modified since",
+			method:         http.MethodGet,
+			path:           "/actions/referral",
+			version:        version,
+			headers:        map[string]string{"If-Modified-Since": lastModified},
+			expectedStatus: http.StatusNotModified,
+			expectedETag:   `"0a1b2c"`,
+		},
+		{
+			name:            "modified since",
+			method:          http.MethodGet,
+			path:            "/actions/referral",
+			version:         version,
+			headers:         map[string]string{"If-Modified-Since": "Mon, 11 Mar 2024 19:59:59 GMT"},
+			expectedStatus:  http.StatusOK,
+			expectedETag:    `"0a1b2c"`,
+			expectedHandled: true,
+		},
+		{
+			name:            "If-None-Match takes precedence",
+			method:          http.MethodGet,
+			path:            "/actions/referral",
+			version:         version,
+			headers:         map[string]string{"If-None-Match": `"ffff"`, "If-Modified-Since": lastModified},
+			expectedStatus:  http.StatusOK,
+			expectedETag:    `"0a1b2c"`,
+			expectedHandled: true,
+		},
+		{
+			name:            "error response untagged",
+			method:          http.MethodGet,
+			path:            "/fail",
+			version:         version,
+			expectedStatus:  http.StatusNotFound,
+			expectedHandled: true,
+		},
+		{
+			name:            "data not loaded",
+			method:          http.MethodGet,
+			path:            "/actions/referral",
+			version:         nil,
+			headers:         map[string]string{"If-None-Match": "*"},
+			expectedStatus:  http.StatusOK,
+			expectedHandled: true,
+		},
+		{
+			name:            "POST ignored",
+			method:          http.MethodPost,
+			path:            "/actions/referral",
+			headers:         map[string]string{"If-None-Match": "*"},
+			expectedStatus:  http.StatusOK,
+			expectedHandled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versionService := new(MockVersionService)
+			versionService.On("Version").Return(tt.version)
+
+			handled := false
+			handler := func(c echo.Context) error {
+				handled = true
+				if c.Path() == "/fail" {
+					return echo.NewHTTPError(http.StatusNotFound, "not found")
+				}
+				return c.JSON(http.StatusOK, map[int]int{1: 2})
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler
+			e.Use(Conditional(versionService))
+			e.Add(tt.method, "/actions/referral", handler)
+			e.GET("/fail", handler)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedHandled, handled)
+			assert.Equal(t, tt.expectedETag, rec.Header().Get("ETag"))
+			if tt.expectedETag != "" {
+				assert.Equal(t, lastModified, rec.Header().Get(echo.HeaderLastModified))
+			} else {
+				assert.Empty(t, rec.Header().Get(echo.HeaderLastModified))
+			}
+			if tt.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"surfe/internal/logging"
+
+	"github.com/labstack/echo/v4"
+)
+
+// userContext attaches userID to the request's logger, so the access log and
+// anything logged further down carries it, and returns the updated context.
+func userContext(c echo.Context, userID int) context.Context {
+	ctx := logging.With(c.Request().Context(), slog.Int("user_id", userID))
+	c.SetRequest(c.Request().WithContext(ctx))
+	return ctx
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"strings"
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// envelope wraps data in the body of a /api/v2 response. It must be built
+// after Conditional has tagged the response, so that the data version in
+// the body matches the ETag.
+func envelope(c echo.Context, data any) models.Envelope {
+	return models.Envelope{
+		Data: data,
+		Meta: models.Meta{
+			DataVersion: strings.Trim(c.Response().Header().Get("ETag"), `"`),
+		},
+		Links: models.Links{Self: c.Request().URL.RequestURI()},
+	}
+}
+
+// listEnvelope wraps a list, counting its items. A nil list is sent as an
+// empty one.
+func listEnvelope[T any](c echo.Context, items []T) models.Envelope {
+	if items == nil {
+		items = []T{}
+	}
+	count := len(items)
+	e := envelope(c, items)
+	e.Meta.Count = &count
+	return e
+}
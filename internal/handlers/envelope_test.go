@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		etag     string
+		build    func(c echo.Context) models.Envelope
+		expected models.Envelope
+	}{
+		{
+			name:  "tagged response",
+			etag:  `"3f9a0c7e"`,
+			build: func(c echo.Context) models.Envelope { return envelope(c, models.ActionCount{Count: 2}) },
+			expected: models.Envelope{
+				Data:  models.ActionCount{Count: 2},
+				Meta:  models.Meta{DataVersion: "3f9a0c7e"},
+				Links: models.Links{Self: "/api/v2/referrals/stats?top=3"},
+			},
+		},
+		{
+			name:  "untagged response",
+			build: func(c echo.Context) models.Envelope { return envelope(c, models.ActionCount{Count: 2}) },
+			expected: models.Envelope{
+				Data:  models.ActionCount{Count: 2},
+				Links: models.Links{Self: "/api/v2/referrals/stats?top=3"},
+			},
+		},
+		{
+			name:  "list",
+			etag:  `"3f9a0c7e"`,
+			build: func(c echo.Context) models.Envelope { return listEnvelope(c, []int{4, 5}) },
+			expected: models.Envelope{
+				Data:  []int{4, 5},
+				Meta:  models.Meta{DataVersion: "3f9a0c7e", Count: intPtr(2)},
+				Links: models.Links{Self: "/api/v2/referrals/stats?top=3"},
+			},
+		},
+		{
+			name:  "nil list",
+			build: func(c echo.Context) models.Envelope { return listEnvelope[int](c, nil) },
+			expected: models.Envelope{
+				Data:  []int{},
+				Meta:  models.Meta{Count: intPtr(0)},
+				Links: models.Links{Self: "/api/v2/referrals/stats?top=3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v2/referrals/stats?top=3", nil)
+			c := e.NewContext(req, httptest.NewRecorder())
+			if tt.etag != "" {
+				c.Response().Header().Set("ETag", tt.etag)
+			}
+
+			assert.Equal(t, tt.expected, tt.build(c))
+		})
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockErasureService is a mock implementation of services.ErasureService
+type MockErasureService struct {
+	mock.Mock
+}
+
+func (m *MockErasureService) EraseUser(ctx context.Context, userID int) (*models.Erasure, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Erasure), args.Error(1)
+}
+
+func TestEraseUser(t *testing.T) {
+	erasedAt := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		userID         string
+		mockErasure    *models.Erasure
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "user erased",
+			userID:         "2",
+			mockErasure:    &models.Erasure{UserID: 2, ErasedAt: erasedAt, ActionsDeleted: 4, ActionsAnonymised: 2},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"userId":2,"erasedAt":"2024-03-11T20:00:00Z","actionsDeleted":4,"actionsAnonymised":2}`,
+		},
+		{
+			name:           "user not found",
+			userID:         "999",
+			mockError:      fmt.Errorf("user 999: %w", apperrors.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"user 999: not found","instance":"/api/v1/users/999"}`,
+		},
+		{
+			name:           "invalid user ID",
+			userID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid user ID","instance":"/api/v1/users/invalid"}`,
+		},
+		{
+			name:           "audit failure",
+			userID:         "2",
+			mockError:      fmt.Errorf("user 2 erased but not audited: %w", assert.AnError),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"Internal server error","instance":"/api/v1/users/2"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/"+tt.userID, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/users/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.userID)
+
+			mockService := new(MockErasureService)
+			if id, err := strconv.Atoi(tt.userID); err == nil {
+				mockService.On("EraseUser", id).Return(tt.mockErasure, tt.mockError)
+			}
+
+			err := NewErasureHandler(mockService).EraseUser(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Erase user
+// @Description Erase a user and their actions for a data protection request. Referrals made by or to the user are kept, reattributed to an anonymous placeholder with a negative ID, so the referral counts of the user's referrers do not change. The erasure is written to disk and recorded in the audit trail before the response is sent.
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.Envelope{data=models.Erasure}
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v2/users/{id} [delete]
+func (h *ErasureHandler) EraseUserV2(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem(c, http.StatusBadRequest, "Invalid user ID")
+	}
+
+	erasure, err := h.erasureService.EraseUser(userContext(c, id), id)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, envelope(c, erasure))
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEraseUserV2(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v2/users/2", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/api/v2/users/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+
+	mockService := new(MockErasureService)
+	mockService.On("EraseUser", 2).Return(&models.Erasure{UserID: 2, ErasedAt: time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC), ActionsDeleted: 4, ActionsAnonymised: 2}, nil)
+
+	err := NewErasureHandler(mockService).EraseUserV2(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"userId":2,"erasedAt":"2024-03-11T20:00:00Z","actionsDeleted":4,"actionsAnonymised":2},`+
+		`"meta":{},"links":{"self":"/api/v2/users/2"}}`, rec.Body.String())
+	mockService.AssertExpectations(t)
+}
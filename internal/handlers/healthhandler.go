@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"surfe/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HealthHandler struct {
+	healthService services.HealthService
+}
+
+func NewHealthHandler(healthService services.HealthService) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+	}
+}
+
+// Liveness reports that the process is up and serving requests.
+func (h *HealthHandler) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness reports the status of each dataset, responding 503 until all of
+// them are loaded.
+func (h *HealthHandler) Readiness(c echo.Context) error {
+	readiness := h.healthService.Readiness(c.Request().Context())
+	if !readiness.Ready {
+		return c.JSON(http.StatusServiceUnavailable, readiness)
+	}
+
+	return c.JSON(http.StatusOK, readiness)
+}
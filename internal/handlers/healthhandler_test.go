@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockHealthService is a mock implementation of services.HealthService
+type MockHealthService struct {
+	mock.Mock
+}
+
+func (m *MockHealthService) Readiness(ctx context.Context) *models.Readiness {
+	args := m.Called()
+	return args.Get(0).(*models.Readiness)
+}
+
+func TestLiveness(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handler := NewHealthHandler(new(MockHealthService))
+
+	if assert.NoError(t, handler.Liveness(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name           string
+		readiness      *models.Readiness
+		expectedStatus int
+	}{
+		{
+			name: "ready",
+			readiness: &models.Readiness{
+				Ready:    true,
+				Datasets: []models.DatasetStatus{{Name: "users", Source: "users.json", Loaded: true, Records: 2}},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "not ready",
+			readiness: &models.Readiness{
+				Ready:    false,
+				Datasets: []models.DatasetStatus{{Name: "users", Source: "users.json", Error: "file not found"}},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockService := new(MockHealthService)
+			mockService.On("Readiness").Return(tt.readiness)
+
+			handler := NewHealthHandler(mockService)
+
+			if assert.NoError(t, handler.Readiness(c)) {
+				assert.Equal(t, tt.expectedStatus, rec.Code)
+
+				var readiness models.Readiness
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &readiness))
+				assert.Equal(t, *tt.readiness, readiness)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
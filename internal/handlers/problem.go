@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// problem writes an RFC 7807 problem details response.
+func problem(c echo.Context, status int, detail string) error {
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return c.JSON(status, models.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request().URL.Path,
+	})
+}
+
+// errorProblem writes the problem details response matching a domain error.
+// Unrecognised errors are reported as internal errors without their message.
+func errorProblem(c echo.Context, err error) error {
+	status := errorStatus(err)
+	detail := err.Error()
+	switch status {
+	case http.StatusInternalServerError:
+		detail = "Internal server error"
+	case http.StatusGatewayTimeout:
+		detail = "Request timed out"
+	}
+	return problem(c, status, detail)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperrors.ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, apperrors.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		// The client has gone away; the response is only seen by logs
+		// and metrics.
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// HTTPErrorHandler renders errors that reach Echo, such as unknown routes, as
+// problem details so that every error response has the same shape.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := errorStatus(err)
+	detail := ""
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.Code
+		if message, ok := httpErr.Message.(string); ok {
+			detail = message
+		}
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = problem(c, status, detail)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"surfe/internal/apperrors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func problemBody(status int, detail string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "about:blank",
+		"title":    http.StatusText(status),
+		"status":   float64(status),
+		"detail":   detail,
+		"instance": "/",
+	}
+}
+
+func TestErrorProblem(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedBody   map[string]interface{}
+	}{
+		{
+			name:           "not found",
+			err:            fmt.Errorf("user 7: %w", apperrors.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   problemBody(http.StatusNotFound, "user 7: not found"),
+		},
+		{
+			name:           "invalid argument",
+			err:            fmt.Errorf("%w: user ID -1", apperrors.ErrInvalidArgument),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "invalid argument: user ID -1"),
+		},
+		{
+			name:           "unavailable",
+			err:            fmt.Errorf("%w: actions.json missing", apperrors.ErrUnavailable),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   problemBody(http.StatusServiceUnavailable, "unavailable: actions.json missing"),
+		},
+		{
+			name:           "deadline exceeded",
+			err:            fmt.Errorf("referral index: %w", context.DeadlineExceeded),
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   problemBody(http.StatusGatewayTimeout, "Request timed out"),
+		},
+		{
+			name:           "canceled",
+			err:            context.Canceled,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   problemBody(http.StatusServiceUnavailable, "context canceled"),
+		},
+		{
+			name:           "unknown error hides message",
+			err:            errors.New("disk on fire"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   problemBody(http.StatusInternalServerError, "Internal server error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := errorProblem(c, tt.err)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+
+			var response map[string]interface{}
+			err = json.Unmarshal(rec.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, response)
+		})
+	}
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+
+	var response map[string]interface{}
+	err := json.Unmarshal(rec.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, problemBody(http.StatusNotFound, "Not Found"), response)
+}
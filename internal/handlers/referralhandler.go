@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"surfe/internal/export"
+	"surfe/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+const defaultLargestTrees = 5
+
+type ReferralHandler struct {
+	referralService services.ReferralService
+}
+
+func NewReferralHandler(referralService services.ReferralService) *ReferralHandler {
+	return &ReferralHandler{
+		referralService: referralService,
+	}
+}
+
+// @Summary Get referral forest statistics
+// @Description Get the number of referral trees, the largest trees, the depth distribution, referred versus organic acquisition and the K-factor per monthly signup cohort
+// @Tags referrals
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param top query int false "Number of largest trees to return" default(5)
+// @Success 200 {object} models.ReferralStats
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v1/referrals/stats [get]
+func (h *ReferralHandler) GetReferralStats(c echo.Context) error {
+	top, invalid := parseTop(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	stats, err := h.referralService.GetReferralStats(c.Request().Context(), top)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// parseTop reads the top query parameter, or describes why it is invalid.
+func parseTop(c echo.Context) (int, string) {
+	param := c.QueryParam("top")
+	if param == "" {
+		return defaultLargestTrees, ""
+	}
+	top, err := strconv.Atoi(param)
+	if err != nil || top < 0 {
+		return 0, "Invalid top"
+	}
+	return top, ""
+}
+
+// @Summary Export referral graph
+// @Description Export the referral graph with user names and referral timestamps as DOT, GraphML or JSON Graph Format
+// @Tags referrals
+// @Produce json
+// @Produce application/problem+json
+// @Produce text/vnd.graphviz
+// @Produce application/graphml+xml
+// @Param format query string false "Output format" Enums(dot, graphml, json) default(json)
+// @Param root query int false "Only export the tree below this user"
+// @Param depth query int false "Maximum number of referral levels to export"
+// @Success 200 {object} export.JSONGraphDocument "The graph; DOT and GraphML are returned as text"
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v1/referrals/graph [get]
+func (h *ReferralHandler) GetReferralGraph(c echo.Context) error {
+	format := export.FormatJSON
+	if param := c.QueryParam("format"); param != "" {
+		var err error
+		format, err = export.ParseFormat(strings.ToLower(param))
+		if err != nil {
+			return problem(c, http.StatusBadRequest, "Invalid format")
+		}
+	}
+
+	root, depth, invalid := parseSubtree(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	network, err := h.referralService.GetReferralNetwork(c.Request().Context(), root, depth)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, format.ContentType())
+	c.Response().WriteHeader(http.StatusOK)
+	return export.WriteGraph(c.Response(), network, format)
+}
+
+// parseSubtree reads the root and depth query parameters that limit the
+// referral graph, or describes why they are invalid. A depth of -1 means
+// no limit.
+func parseSubtree(c echo.Context) (*int, int, string) {
+	var root *int
+	if param := c.QueryParam("root"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, 0, "Invalid root"
+		}
+		root = &id
+	}
+
+	depth := -1
+	if param := c.QueryParam("depth"); param != "" {
+		var err error
+		depth, err = strconv.Atoi(param)
+		if err != nil || depth < 0 {
+			return nil, 0, "Invalid depth"
+		}
+	}
+	return root, depth, ""
+}
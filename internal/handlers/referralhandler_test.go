@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReferralService is a mock implementation of services.ReferralService
+type MockReferralService struct {
+	mock.Mock
+}
+
+func (m *MockReferralService) GetReferralStats(ctx context.Context, top int) (*models.ReferralStats, error) {
+	args := m.Called(top)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReferralStats), args.Error(1)
+}
+
+func (m *MockReferralService) GetReferralNetwork(ctx context.Context, root *int, maxDepth int) (*models.ReferralNetwork, error) {
+	args := m.Called(root, maxDepth)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReferralNetwork), args.Error(1)
+}
+
+func TestGetReferralStats(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		top            int
+		mockResponse   *models.ReferralStats
+		mockError      error
+		expectedStatus int
+		expectedBody   map[string]interface{}
+	}{
+		{
+			name:  "successful response",
+			query: "?top=1",
+			top:   1,
+			mockResponse: &models.ReferralStats{
+				Trees:             1,
+				LargestTrees:      []models.ReferralTree{{RootID: 1, Size: 2, Depth: 1}},
+				DepthDistribution: []models.DepthCount{{Depth: 0, Users: 1}, {Depth: 1, Users: 1}},
+				Acquisition:       models.Acquisition{Users: 2, Referred: 1, Organic: 1, ReferredShare: 0.5, OrganicShare: 0.5},
+				Cohorts:           []models.CohortVirality{{Cohort: "2024-03", Users: 2, Referrals: 1, KFactor: 0.5}},
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"trees": float64(1),
+				"largestTrees": []interface{}{
+					map[string]interface{}{"rootId": float64(1), "size": float64(2), "depth": float64(1)},
+				},
+				"depthDistribution": []interface{}{
+					map[string]interface{}{"depth": float64(0), "users": float64(1)},
+					map[string]interface{}{"depth": float64(1), "users": float64(1)},
+				},
+				"acquisition": map[string]interface{}{
+					"users":         float64(2),
+					"referred":      float64(1),
+					"organic":       float64(1),
+					"referredShare": 0.5,
+					"organicShare":  0.5,
+				},
+				"cohorts": []interface{}{
+					map[string]interface{}{"cohort": "2024-03", "users": float64(2), "referrals": float64(1), "kFactor": 0.5},
+				},
+			},
+		},
+		{
+			name:           "invalid top",
+			query:          "?top=abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "Invalid top"),
+		},
+		{
+			name:           "service error",
+			query:          "",
+			top:            5,
+			mockResponse:   nil,
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   problemBody(http.StatusInternalServerError, "Internal server error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockService := new(MockReferralService)
+			if tt.expectedStatus != http.StatusBadRequest {
+				mockService.On("GetReferralStats", tt.top).Return(tt.mockResponse, tt.mockError)
+			}
+
+			h := NewReferralHandler(mockService)
+
+			err := h.GetReferralStats(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(rec.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, response)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetReferralGraph(t *testing.T) {
+	fixedTime := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	root := 1
+	network := &models.ReferralNetwork{
+		Nodes: []models.ReferralNode{
+			{ID: 1, Name: "John Doe", CreatedAt: fixedTime},
+			{ID: 2, Name: "Jane Smith", CreatedAt: fixedTime},
+		},
+		Edges: []models.ReferralEdge{
+			{Source: 1, Target: 2, ReferredAt: fixedTime},
+		},
+	}
+	tests := []struct {
+		name                string
+		query               string
+		root                *int
+		depth               int
+		mockResponse        *models.ReferralNetwork
+		mockError           error
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "dot rooted and limited",
+			query:               "?format=DOT&root=1&depth=2",
+			root:                &root,
+			depth:               2,
+			mockResponse:        network,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/vnd.graphviz; charset=UTF-8",
+			expectedBody: "digraph referrals {\n" +
+				"  1 [label=\"John Doe\", createdAt=\"2024-03-11T20:00:00Z\"];\n" +
+				"  2 [label=\"Jane Smith\", createdAt=\"2024-03-11T20:00:00Z\"];\n" +
+				"  1 -> 2 [referredAt=\"2024-03-11T20:00:00Z\"];\n" +
+				"}\n",
+		},
+		{
+			name:                "json by default",
+			query:               "",
+			root:                nil,
+			depth:               -1,
+			mockResponse:        network,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody: `{"graph":{"directed":true,"type":"referrals","nodes":{` +
+				`"1":{"label":"John Doe","metadata":{"createdAt":"2024-03-11T20:00:00Z"}},` +
+				`"2":{"label":"Jane Smith","metadata":{"createdAt":"2024-03-11T20:00:00Z"}}},` +
+				`"edges":[{"source":"1","target":"2","metadata":{"referredAt":"2024-03-11T20:00:00Z"}}]}}` + "\n",
+		},
+		{
+			name:                "unknown root",
+			query:               "?root=1",
+			root:                &root,
+			depth:               -1,
+			mockResponse:        nil,
+			mockError:           fmt.Errorf("user 1: %w", apperrors.ErrNotFound),
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: MIMEApplicationProblemJSON,
+			expectedBody:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"user 1: not found","instance":"/"}` + "\n",
+		},
+		{
+			name:                "invalid format",
+			query:               "?format=svg",
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: MIMEApplicationProblemJSON,
+			expectedBody:        `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid format","instance":"/"}` + "\n",
+		},
+		{
+			name:                "invalid depth",
+			query:               "?depth=-1",
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: MIMEApplicationProblemJSON,
+			expectedBody:        `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid depth","instance":"/"}` + "\n",
+		},
+		{
+			name:                "service error",
+			query:               "?format=graphml",
+			root:                nil,
+			depth:               -1,
+			mockResponse:        nil,
+			mockError:           assert.AnError,
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: MIMEApplicationProblemJSON,
+			expectedBody:        `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"Internal server error","instance":"/"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockService := new(MockReferralService)
+			if tt.expectedStatus != http.StatusBadRequest {
+				mockService.On("GetReferralNetwork", tt.root, tt.depth).Return(tt.mockResponse, tt.mockError)
+			}
+
+			h := NewReferralHandler(mockService)
+
+			err := h.GetReferralGraph(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedContentType, rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, tt.expectedBody, rec.Body.String())
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Get referral forest statistics
+// @Description Get the number of referral trees, the largest trees, the depth distribution, referred versus organic acquisition and the K-factor per monthly signup cohort
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param top query int false "Number of largest trees to return" default(5)
+// @Success 200 {object} models.Envelope{data=models.ReferralStats}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v2/referrals/stats [get]
+func (h *ReferralHandler) GetReferralStatsV2(c echo.Context) error {
+	top, invalid := parseTop(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	stats, err := h.referralService.GetReferralStats(c.Request().Context(), top)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, envelope(c, stats))
+}
+
+// @Summary Get referral graph
+// @Description Get the referral graph with user names and referral timestamps, nodes ordered by user ID and edges by source then target. The DOT and GraphML exports remain on /api/v1/referrals/graph.
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param root query int false "Only include the tree below this user"
+// @Param depth query int false "Maximum number of referral levels to include"
+// @Success 200 {object} models.Envelope{data=models.ReferralNetwork}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v2/referrals/graph [get]
+func (h *ReferralHandler) GetReferralGraphV2(c echo.Context) error {
+	root, depth, invalid := parseSubtree(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	network, err := h.referralService.GetReferralNetwork(c.Request().Context(), root, depth)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, envelope(c, network))
+}
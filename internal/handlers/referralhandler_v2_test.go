@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReferralGraphV2(t *testing.T) {
+	fixedTime := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	root := 1
+	network := &models.ReferralNetwork{
+		Nodes: []models.ReferralNode{
+			{ID: 1, Name: "John Doe", CreatedAt: fixedTime},
+			{ID: 2, Name: "Jane Smith", CreatedAt: fixedTime},
+		},
+		Edges: []models.ReferralEdge{
+			{Source: 1, Target: 2, ReferredAt: fixedTime},
+		},
+	}
+	tests := []struct {
+		name           string
+		query          string
+		root           *int
+		depth          int
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "rooted and limited",
+			query:          "?root=1&depth=2",
+			root:           &root,
+			depth:          2,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data":{"nodes":[` +
+				`{"id":1,"name":"John Doe","createdAt":"2024-03-11T20:00:00Z"},` +
+				`{"id":2,"name":"Jane Smith","createdAt":"2024-03-11T20:00:00Z"}],` +
+				`"edges":[{"source":1,"target":2,"referredAt":"2024-03-11T20:00:00Z"}]},` +
+				`"meta":{},"links":{"self":"/api/v2/referrals/graph?root=1&depth=2"}}`,
+		},
+		{
+			name:           "invalid depth",
+			query:          "?depth=-1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid depth","instance":"/api/v2/referrals/graph"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v2/referrals/graph"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockService := new(MockReferralService)
+			if tt.expectedStatus == http.StatusOK {
+				mockService.On("GetReferralNetwork", tt.root, tt.depth).Return(network, nil)
+			}
+
+			err := NewReferralHandler(mockService).GetReferralGraphV2(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"surfe/internal/services"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		expectedStatus int
+	}{
+		{
+			name:           "finishes in time",
+			timeout:        time.Second,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "times out",
+			timeout:        time.Millisecond,
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/actions/referral", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			handler := NewActionHandler(&slowActionService{delay: 50 * time.Millisecond})
+
+			err := Timeout(tt.timeout)(handler.GetReferralIndex)(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusGatewayTimeout {
+				var response map[string]interface{}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+				assert.Equal(t, "Request timed out", response["detail"])
+			}
+		})
+	}
+}
+
+// slowActionService computes the referral index in delay, or gives up when
+// the request context is done first.
+type slowActionService struct {
+	services.ActionService
+	delay time.Duration
+}
+
+func (s *slowActionService) GetReferralIndex(ctx context.Context) (map[int]int, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(s.delay):
+		return map[int]int{1: 1}, nil
+	}
+}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"surfe/internal/models"
 	"surfe/internal/services"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
+// maxBatchSize bounds how many user IDs one batch request may look up.
+const maxBatchSize = 100
+
 type UserHandler struct {
 	userService services.UserService
 }
@@ -21,25 +27,25 @@ func NewUserHandler(service services.UserService) *UserHandler {
 // @Tags users
 // @Accept json
 // @Produce json
+// @Produce application/problem+json
 // @Param id path int true "User ID"
 // @Success 200 {object} models.User
-// @Failure 400 {object} error
-// @Failure 404 {object} error
-// @Failure 500 {object} error
-// @Router /users/{id} [get]
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v1/users/{id} [get]
 func (h *UserHandler) GetUserByID(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return problem(c, http.StatusBadRequest, "Invalid user ID")
 	}
 
-	user, err := h.userService.GetUserByID(id)
+	user, err := h.userService.GetUserByID(userContext(c, id), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
-	}
-
-	if user == nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+		return errorProblem(c, err)
 	}
 
 	return c.JSON(http.StatusOK, user)
@@ -50,21 +56,124 @@ func (h *UserHandler) GetUserByID(c echo.Context) error {
 // @Tags users
 // @Accept json
 // @Produce json
+// @Produce application/problem+json
 // @Param id path int true "User ID"
 // @Success 200 {object} models.ActionCount
-// @Failure 400 {object} error
-// @Failure 500 {object} error
-// @Router /users/{id}/actions/count [get]
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v1/users/{id}/actions/count [get]
 func (h *UserHandler) GetUserActionCount(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+		return problem(c, http.StatusBadRequest, "Invalid user ID")
 	}
 
-	count, err := h.userService.GetUserActionCount(id)
+	count, err := h.userService.GetUserActionCount(userContext(c, id), id)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+		return errorProblem(c, err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]int{"count": count})
 }
+
+// @Summary Get user action stats
+// @Description Break a user's actions down by type, with the time of their first and last action and the number of days they were active. The figures cover the user's whole history and, when from or to is given, that window as well.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param id path int true "User ID"
+// @Param from query string false "Start of the window, inclusive (RFC 3339)"
+// @Param to query string false "End of the window, exclusive (RFC 3339)"
+// @Success 200 {object} models.UserActionStats
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v1/users/{id}/actions/stats [get]
+func (h *UserHandler) GetUserActionStats(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem(c, http.StatusBadRequest, "Invalid user ID")
+	}
+	from, to, invalid := parseWindow(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	stats, err := h.userService.GetUserActionStats(userContext(c, id), id, from, to)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// parseWindow reads the from and to query parameters bounding the actions
+// to count, or describes why they are invalid. Either may be nil.
+func parseWindow(c echo.Context) (*time.Time, *time.Time, string) {
+	var bounds [2]*time.Time
+	for i, name := range []string{"from", "to"} {
+		param := c.QueryParam(name)
+		if param == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, param)
+		if err != nil {
+			return nil, nil, "Invalid " + name
+		}
+		bounds[i] = &t
+	}
+	from, to := bounds[0], bounds[1]
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, "from must be before to"
+	}
+	return from, to, ""
+}
+
+// @Summary Get users in bulk
+// @Description Look up to 100 users and their action counts in one request. Each distinct ID is answered once, in the order requested; IDs without a user, including negative IDs, are marked found=false.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param request body models.UserBatchRequest true "User IDs"
+// @Success 200 {object} models.UserBatch
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v1/users/batch [post]
+func (h *UserHandler) GetUsers(c echo.Context) error {
+	ids, invalid := bindUserIDs(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	users, err := h.userService.GetUsers(c.Request().Context(), ids)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, models.UserBatch{Users: users})
+}
+
+// bindUserIDs reads the IDs of a batch lookup from the request body, or
+// describes why they are invalid.
+func bindUserIDs(c echo.Context) ([]int, string) {
+	var req models.UserBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, "Invalid request"
+	}
+	if len(req.IDs) == 0 {
+		return nil, "At least one user ID is required"
+	}
+	if len(req.IDs) > maxBatchSize {
+		return nil, fmt.Sprintf("At most %d user IDs are allowed, got %d", maxBatchSize, len(req.IDs))
+	}
+	return req.IDs, ""
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"surfe/internal/apperrors"
 	"surfe/internal/models"
 
 	"github.com/labstack/echo/v4"
@@ -19,7 +23,7 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) GetUserByID(id int) (*models.User, error) {
+func (m *MockUserService) GetUserByID(ctx context.Context, id int) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -27,11 +31,27 @@ func (m *MockUserService) GetUserByID(id int) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserService) GetUserActionCount(userID int) (int, error) {
+func (m *MockUserService) GetUserActionCount(ctx context.Context, userID int) (int, error) {
 	args := m.Called(userID)
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockUserService) GetUserActionStats(ctx context.Context, userID int, from, to *time.Time) (*models.UserActionStats, error) {
+	args := m.Called(userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserActionStats), args.Error(1)
+}
+
+func (m *MockUserService) GetUsers(ctx context.Context, ids []int) ([]models.UserBatchItem, error) {
+	args := m.Called(ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UserBatchItem), args.Error(1)
+}
+
 func TestGetUserByID(t *testing.T) {
 	fixedTime := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
 	tests := []struct {
@@ -62,11 +82,9 @@ func TestGetUserByID(t *testing.T) {
 			name:           "user not found",
 			userID:         "999",
 			mockUser:       nil,
-			mockError:      nil,
+			mockError:      fmt.Errorf("user 999: %w", apperrors.ErrNotFound),
 			expectedStatus: http.StatusNotFound,
-			expectedBody: map[string]interface{}{
-				"error": "User not found",
-			},
+			expectedBody:   problemBody(http.StatusNotFound, "user 999: not found"),
 		},
 		{
 			name:           "invalid user ID",
@@ -74,9 +92,23 @@ func TestGetUserByID(t *testing.T) {
 			mockUser:       nil,
 			mockError:      nil,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody: map[string]interface{}{
-				"error": "Invalid user ID",
-			},
+			expectedBody:   problemBody(http.StatusBadRequest, "Invalid user ID"),
+		},
+		{
+			name:           "invalid argument",
+			userID:         "-1",
+			mockUser:       nil,
+			mockError:      fmt.Errorf("%w: user ID -1", apperrors.ErrInvalidArgument),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "invalid argument: user ID -1"),
+		},
+		{
+			name:           "data unavailable",
+			userID:         "1",
+			mockUser:       nil,
+			mockError:      fmt.Errorf("%w: users.json missing", apperrors.ErrUnavailable),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   problemBody(http.StatusServiceUnavailable, "unavailable: users.json missing"),
 		},
 		{
 			name:           "service error",
@@ -84,9 +116,7 @@ func TestGetUserByID(t *testing.T) {
 			mockUser:       nil,
 			mockError:      assert.AnError,
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody: map[string]interface{}{
-				"error": "Internal server error",
-			},
+			expectedBody:   problemBody(http.StatusInternalServerError, "Internal server error"),
 		},
 	}
 
@@ -148,9 +178,7 @@ func TestGetUserActionCount(t *testing.T) {
 			mockCount:      0,
 			mockError:      nil,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody: map[string]interface{}{
-				"error": "Invalid user ID",
-			},
+			expectedBody:   problemBody(http.StatusBadRequest, "Invalid user ID"),
 		},
 		{
 			name:           "service error",
@@ -158,9 +186,7 @@ func TestGetUserActionCount(t *testing.T) {
 			mockCount:      0,
 			mockError:      assert.AnError,
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody: map[string]interface{}{
-				"error": "Internal server error",
-			},
+			expectedBody:   problemBody(http.StatusInternalServerError, "Internal server error"),
 		},
 	}
 
@@ -196,3 +222,230 @@ func TestGetUserActionCount(t *testing.T) {
 		})
 	}
 }
+
+func TestGetUserActionStats(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	first := time.Date(2024, 2, 11, 9, 30, 0, 0, time.UTC)
+	last := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		query          string
+		mockFrom       *time.Time
+		mockTo         *time.Time
+		mockStats      *models.UserActionStats
+		mockError      error
+		expectedStatus int
+		expectedBody   map[string]interface{}
+	}{
+		{
+			name:  "whole history",
+			query: "",
+			mockStats: &models.UserActionStats{UserID: 1, ActionStats: models.ActionStats{
+				Total:         3,
+				ByType:        []models.ActionTypeCount{{Type: "LOGIN", Count: 2}, {Type: "REFER_USER", Count: 1}},
+				FirstActionAt: &first,
+				LastActionAt:  &last,
+				ActiveDays:    2,
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"userId": float64(1),
+				"total":  float64(3),
+				"byType": []interface{}{
+					map[string]interface{}{"type": "LOGIN", "count": float64(2)},
+					map[string]interface{}{"type": "REFER_USER", "count": float64(1)},
+				},
+				"firstActionAt": first.Format(time.RFC3339),
+				"lastActionAt":  last.Format(time.RFC3339),
+				"activeDays":    float64(2),
+			},
+		},
+		{
+			name:     "window",
+			query:    "?from=2024-03-01T00:00:00Z&to=2024-04-01T00:00:00Z",
+			mockFrom: &from,
+			mockTo:   &to,
+			mockStats: &models.UserActionStats{UserID: 1, ActionStats: models.ActionStats{ByType: []models.ActionTypeCount{}}, Window: &models.ActionWindow{
+				From:        &from,
+				To:          &to,
+				ActionStats: models.ActionStats{ByType: []models.ActionTypeCount{}},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"userId":        float64(1),
+				"total":         float64(0),
+				"byType":        []interface{}{},
+				"firstActionAt": nil,
+				"lastActionAt":  nil,
+				"activeDays":    float64(0),
+				"window": map[string]interface{}{
+					"from":          from.Format(time.RFC3339),
+					"to":            to.Format(time.RFC3339),
+					"total":         float64(0),
+					"byType":        []interface{}{},
+					"firstActionAt": nil,
+					"lastActionAt":  nil,
+					"activeDays":    float64(0),
+				},
+			},
+		},
+		{
+			name:           "invalid from",
+			query:          "?from=2024-03-01",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "Invalid from"),
+		},
+		{
+			name:           "empty window",
+			query:          "?from=2024-04-01T00:00:00Z&to=2024-03-01T00:00:00Z",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "from must be before to"),
+		},
+		{
+			name:           "user not found",
+			query:          "",
+			mockError:      fmt.Errorf("user 1: %w", apperrors.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   problemBody(http.StatusNotFound, "user 1: not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/users/:id/actions/stats")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			mockService := new(MockUserService)
+			if tt.mockStats != nil || tt.mockError != nil {
+				mockService.On("GetUserActionStats", 1, tt.mockFrom, tt.mockTo).Return(tt.mockStats, tt.mockError)
+			}
+
+			err := NewUserHandler(mockService).GetUserActionStats(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(rec.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, response)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	fixedTime := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	count := 3
+	tooMany := strings.TrimSuffix(strings.Repeat("1,", maxBatchSize+1), ",")
+	tests := []struct {
+		name           string
+		body           string
+		mockIDs        []int
+		mockResponse   []models.UserBatchItem
+		mockError      error
+		expectedStatus int
+		expectedBody   map[string]interface{}
+	}{
+		{
+			name:    "found and not found",
+			body:    `{"ids":[1,999]}`,
+			mockIDs: []int{1, 999},
+			mockResponse: []models.UserBatchItem{
+				{ID: 1, Found: true, User: &models.User{ID: 1, Name: "John Doe", CreatedAt: fixedTime}, ActionCount: &count},
+				{ID: 999},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"users": []interface{}{
+					map[string]interface{}{
+						"id":    float64(1),
+						"found": true,
+						"user": map[string]interface{}{
+							"id":        float64(1),
+							"name":      "John Doe",
+							"createdAt": fixedTime.Format(time.RFC3339),
+						},
+						"actionCount": float64(3),
+					},
+					map[string]interface{}{
+						"id":    float64(999),
+						"found": false,
+					},
+				},
+			},
+		},
+		{
+			name:           "no IDs",
+			body:           `{"ids":[]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "At least one user ID is required"),
+		},
+		{
+			name:           "too many IDs",
+			body:           `{"ids":[` + tooMany + `]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, fmt.Sprintf("At most %d user IDs are allowed, got %d", maxBatchSize, maxBatchSize+1)),
+		},
+		{
+			name:           "malformed body",
+			body:           `{"ids":["1"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   problemBody(http.StatusBadRequest, "Invalid request"),
+		},
+		{
+			name:           "negative ID not found",
+			body:           `{"ids":[-1]}`,
+			mockIDs:        []int{-1},
+			mockResponse:   []models.UserBatchItem{{ID: -1}},
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]interface{}{
+				"users": []interface{}{
+					map[string]interface{}{"id": float64(-1), "found": false},
+				},
+			},
+		},
+		{
+			name:           "service error",
+			body:           `{"ids":[1]}`,
+			mockIDs:        []int{1},
+			mockError:      assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   problemBody(http.StatusInternalServerError, "Internal server error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockService := new(MockUserService)
+			if tt.mockIDs != nil {
+				mockService.On("GetUsers", tt.mockIDs).Return(tt.mockResponse, tt.mockError)
+			}
+
+			err := NewUserHandler(mockService).GetUsers(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(rec.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, response)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
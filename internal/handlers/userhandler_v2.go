@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Get user by ID
+// @Description Get user details by their ID
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.Envelope{data=models.User}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v2/users/{id} [get]
+func (h *UserHandler) GetUserByIDV2(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem(c, http.StatusBadRequest, "Invalid user ID")
+	}
+
+	user, err := h.userService.GetUserByID(userContext(c, id), id)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, envelope(c, user))
+}
+
+// @Summary Get user action count
+// @Description Get the total number of actions performed by a user
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.Envelope{data=models.ActionCount}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v2/users/{id}/actions/count [get]
+func (h *UserHandler) GetUserActionCountV2(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem(c, http.StatusBadRequest, "Invalid user ID")
+	}
+
+	count, err := h.userService.GetUserActionCount(userContext(c, id), id)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, envelope(c, models.ActionCount{Count: count}))
+}
+
+// @Summary Get user action stats
+// @Description Break a user's actions down by type, with the time of their first and last action and the number of days they were active. The figures cover the user's whole history and, when from or to is given, that window as well.
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param id path int true "User ID"
+// @Param from query string false "Start of the window, inclusive (RFC 3339)"
+// @Param to query string false "End of the window, exclusive (RFC 3339)"
+// @Success 200 {object} models.Envelope{data=models.UserActionStats}
+// @Header 200 {string} ETag "Version of the data the response was built from"
+// @Header 200 {string} Last-Modified "When the data last changed"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /v2/users/{id}/actions/stats [get]
+func (h *UserHandler) GetUserActionStatsV2(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return problem(c, http.StatusBadRequest, "Invalid user ID")
+	}
+	from, to, invalid := parseWindow(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	stats, err := h.userService.GetUserActionStats(userContext(c, id), id, from, to)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, envelope(c, stats))
+}
+
+// @Summary Get users in bulk
+// @Description Look up to 100 users and their action counts in one request. Each distinct ID is answered once, in the order requested; IDs without a user, including negative IDs, are marked found=false.
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Produce application/problem+json
+// @Param request body models.UserBatchRequest true "User IDs"
+// @Success 200 {object} models.Envelope{data=[]models.UserBatchItem}
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Failure 504 {object} models.Problem
+// @Router /v2/users/batch [post]
+func (h *UserHandler) GetUsersV2(c echo.Context) error {
+	ids, invalid := bindUserIDs(c)
+	if invalid != "" {
+		return problem(c, http.StatusBadRequest, invalid)
+	}
+
+	users, err := h.userService.GetUsers(c.Request().Context(), ids)
+	if err != nil {
+		return errorProblem(c, err)
+	}
+
+	return c.JSON(http.StatusOK, listEnvelope(c, users))
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUserByIDV2(t *testing.T) {
+	fixedTime := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		userID         string
+		mockUser       *models.User
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "user found",
+			userID:         "1",
+			mockUser:       &models.User{ID: 1, Name: "John Doe", CreatedAt: fixedTime},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data":{"id":1,"name":"John Doe","createdAt":"2024-03-11T20:00:00Z"},` +
+				`"meta":{"dataVersion":"v7"},"links":{"self":"/api/v2/users/1"}}`,
+		},
+		{
+			name:           "user not found",
+			userID:         "999",
+			mockError:      fmt.Errorf("user 999: %w", apperrors.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"user 999: not found","instance":"/api/v2/users/999"}`,
+		},
+		{
+			name:           "invalid user ID",
+			userID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid user ID","instance":"/api/v2/users/invalid"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v2/users/"+tt.userID, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v2/users/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.userID)
+			c.Response().Header().Set("ETag", `"v7"`)
+
+			mockService := new(MockUserService)
+			if id, err := strconv.Atoi(tt.userID); err == nil {
+				mockService.On("GetUserByID", id).Return(tt.mockUser, tt.mockError)
+			}
+
+			err := NewUserHandler(mockService).GetUserByIDV2(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetUserActionCountV2(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/users/1/actions/count", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/api/v2/users/:id/actions/count")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	mockService := new(MockUserService)
+	mockService.On("GetUserActionCount", 1).Return(5, nil)
+
+	err := NewUserHandler(mockService).GetUserActionCountV2(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"count":5},"meta":{},"links":{"self":"/api/v2/users/1/actions/count"}}`, rec.Body.String())
+}
+
+func TestGetUserActionStatsV2(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/users/1/actions/stats", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/api/v2/users/:id/actions/stats")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	mockService := new(MockUserService)
+	mockService.On("GetUserActionStats", 1, (*time.Time)(nil), (*time.Time)(nil)).
+		Return(&models.UserActionStats{UserID: 1, ActionStats: models.ActionStats{ByType: []models.ActionTypeCount{}}}, nil)
+
+	err := NewUserHandler(mockService).GetUserActionStatsV2(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"userId":1,"total":0,"byType":[],"firstActionAt":null,"lastActionAt":null,"activeDays":0},`+
+		`"meta":{},"links":{"self":"/api/v2/users/1/actions/stats"}}`, rec.Body.String())
+}
+
+func TestGetUsersV2(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/users/batch", strings.NewReader(`{"ids":[2,999]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	count := 0
+	mockService := new(MockUserService)
+	mockService.On("GetUsers", []int{2, 999}).Return([]models.UserBatchItem{
+		{ID: 2, Found: true, User: &models.User{ID: 2, Name: "Jane Smith", CreatedAt: time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)}, ActionCount: &count},
+		{ID: 999},
+	}, nil)
+
+	err := NewUserHandler(mockService).GetUsersV2(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":[`+
+		`{"id":2,"found":true,"user":{"id":2,"name":"Jane Smith","createdAt":"2024-03-11T20:00:00Z"},"actionCount":0},`+
+		`{"id":999,"found":false}],`+
+		`"meta":{"count":2},"links":{"self":"/api/v2/users/batch"}}`, rec.Body.String())
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"surfe/internal/logging"
+	"surfe/internal/workspace"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WorkspaceRegistry is implemented by *workspace.Registry.
+type WorkspaceRegistry interface {
+	Acquire(ctx context.Context, id string) (*workspace.Workspace, func(), error)
+}
+
+type WorkspaceHandler struct {
+	registry WorkspaceRegistry
+}
+
+func NewWorkspaceHandler(registry WorkspaceRegistry) *WorkspaceHandler {
+	return &WorkspaceHandler{registry: registry}
+}
+
+// routeKey carries a pointer through which a workspace's handler reports the
+// route it matched.
+type routeKey struct{}
+
+// Serve hands the request to the handler of the workspace named by the ws
+// path parameter, which serves the same routes as the server's own dataset
+// under /api/v1/workspaces/:ws and /api/v2/workspaces/:ws. The workspace is
+// opened on first use.
+func (h *WorkspaceHandler) Serve(c echo.Context) error {
+	ws, release, err := h.registry.Acquire(c.Request().Context(), c.Param("ws"))
+	if err != nil {
+		return errorProblem(c, err)
+	}
+	defer release()
+
+	ctx := logging.With(c.Request().Context(), slog.String("workspace", ws.ID))
+	c.SetRequest(c.Request().WithContext(ctx))
+
+	var route string
+	ws.Handler.ServeHTTP(c.Response(), c.Request().WithContext(context.WithValue(ctx, routeKey{}, &route)))
+	// Metrics are labelled with the workspace's route rather than the
+	// wildcard.
+	if route != "" {
+		c.SetPath(route)
+	}
+	return nil
+}
+
+// WorkspaceRoute reports the route a workspace's handler matched to the
+// WorkspaceHandler that called it. It belongs on the workspace's Echo
+// instance.
+func WorkspaceRoute() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if route, ok := c.Request().Context().Value(routeKey{}).(*string); ok {
+				*route = c.Path()
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"surfe/internal/apperrors"
+	"surfe/internal/workspace"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWorkspaceRegistry is a mock implementation of WorkspaceRegistry
+type MockWorkspaceRegistry struct {
+	mock.Mock
+}
+
+func (m *MockWorkspaceRegistry) Acquire(ctx context.Context, id string) (*workspace.Workspace, func(), error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*workspace.Workspace), args.Get(1).(func()), args.Error(2)
+}
+
+func TestWorkspaceHandler_Serve(t *testing.T) {
+	// The workspace's own router, as the server builds it.
+	inner := echo.New()
+	inner.HTTPErrorHandler = HTTPErrorHandler
+	inner.Use(WorkspaceRoute())
+	inner.GET("/api/v1/workspaces/:ws/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "user "+c.Param("id")+" of "+c.Param("ws"))
+	})
+	acme := &workspace.Workspace{ID: "acme", Handler: inner}
+
+	tests := []struct {
+		name           string
+		path           string
+		setupMock      func(m *MockWorkspaceRegistry, released *int)
+		expectedStatus int
+		expectedBody   string
+		expectedRoute  string
+		expectReleased bool
+	}{
+		{
+			name: "served by the workspace",
+			path: "/api/v1/workspaces/acme/users/7",
+			setupMock: func(m *MockWorkspaceRegistry, released *int) {
+				m.On("Acquire", "acme").Return(acme, func() { *released++ }, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "user 7 of acme",
+			expectedRoute:  "/api/v1/workspaces/:ws/users/:id",
+			expectReleased: true,
+		},
+		{
+			name: "unknown route in the workspace",
+			path: "/api/v1/workspaces/acme/teams",
+			setupMock: func(m *MockWorkspaceRegistry, released *int) {
+				m.On("Acquire", "acme").Return(acme, func() { *released++ }, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"instance":"/api/v1/workspaces/acme/teams"`,
+			expectedRoute:  "/api/v1/workspaces/:ws/*",
+			expectReleased: true,
+		},
+		{
+			name: "unknown workspace",
+			path: "/api/v1/workspaces/hooli/users/7",
+			setupMock: func(m *MockWorkspaceRegistry, released *int) {
+				m.On("Acquire", "hooli").Return(nil, nil, fmt.Errorf("%w: workspace %q", apperrors.ErrNotFound, "hooli"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"detail":"not found: workspace \"hooli\""`,
+			expectedRoute:  "/api/v1/workspaces/:ws/*",
+		},
+		{
+			name: "workspace unavailable",
+			path: "/api/v1/workspaces/acme/users/7",
+			setupMock: func(m *MockWorkspaceRegistry, released *int) {
+				m.On("Acquire", "acme").Return(nil, nil, fmt.Errorf("%w: workspace %q: users dataset not loaded", apperrors.ErrUnavailable, "acme"))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `"status":503`,
+			expectedRoute:  "/api/v1/workspaces/:ws/*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := new(MockWorkspaceRegistry)
+			released := 0
+			tt.setupMock(registry, &released)
+			handler := NewWorkspaceHandler(registry)
+
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler
+			var route string
+			e.Any("/api/v1/workspaces/:ws/*", func(c echo.Context) error {
+				err := handler.Serve(c)
+				route = c.Path()
+				return err
+			})
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.expectedBody)
+			assert.Equal(t, tt.expectedRoute, route)
+			if tt.expectReleased {
+				assert.Equal(t, 1, released, "workspace not released")
+			}
+			registry.AssertExpectations(t)
+		})
+	}
+}
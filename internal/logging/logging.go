@@ -0,0 +1,57 @@
+// Package logging provides structured JSON logging with log/slog. Each
+// request's logger carries its request ID, route and trace ID and travels in
+// the request context, so services and repositories log with the same
+// attributes as the access log.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+type contextKey struct{}
+
+type requestIDKey struct{}
+
+// New returns a logger that writes JSON lines to w, dropping records below
+// level ("debug", "info", "warn" or "error").
+func New(w io.Writer, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl})), nil
+}
+
+// NewContext returns a copy of ctx that carries logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger if
+// there is none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With returns a copy of ctx whose logger adds args to every record.
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// WithRequestID returns a copy of ctx that carries the ID of the request it
+// belongs to.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "" outside a
+// request.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
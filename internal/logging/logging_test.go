@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeRecords parses the JSON lines written by a logger.
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name          string
+		level         string
+		expectedMsgs  []any
+		expectedError string
+	}{
+		{name: "debug", level: "debug", expectedMsgs: []any{"debug", "info", "warn", "error"}},
+		{name: "warn", level: "warn", expectedMsgs: []any{"warn", "error"}},
+		{name: "upper case", level: "ERROR", expectedMsgs: []any{"error"}},
+		{name: "unknown level", level: "verbose", expectedError: "unknown name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, tt.level)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error")
+
+			var msgs []any
+			for _, record := range decodeRecords(t, &buf) {
+				msgs = append(msgs, record["msg"])
+			}
+			assert.Equal(t, tt.expectedMsgs, msgs)
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Same(t, slog.Default(), FromContext(context.Background()))
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info")
+	require.NoError(t, err)
+	ctx := NewContext(context.Background(), logger)
+	assert.Same(t, logger, FromContext(ctx))
+
+	FromContext(With(ctx, slog.Int("user_id", 7))).Info("user loaded")
+	FromContext(ctx).Info("unrelated")
+
+	records := decodeRecords(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, 7.0, records[0]["user_id"])
+	assert.NotContains(t, records[1], "user_id")
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestIDLength bounds the X-Request-ID values accepted from clients.
+const maxRequestIDLength = 128
+
+// Middleware gives every request a logger carrying its request ID, route and,
+// when the request is traced, trace ID, and writes one access log record per
+// request. The request ID is also available from RequestID. A valid
+// X-Request-ID from the client is kept, otherwise a new ID is generated;
+// either way it is echoed in the response.
+func Middleware(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			requestID := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+			attrs := []any{slog.String("request_id", requestID), slog.String("route", c.Path())}
+			if span := trace.SpanContextFromContext(req.Context()); span.IsValid() {
+				attrs = append(attrs, slog.String("trace_id", span.TraceID().String()))
+			}
+			requestLogger := logger.With(attrs...)
+			c.SetRequest(req.WithContext(WithRequestID(NewContext(req.Context(), requestLogger), requestID)))
+
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response so the
+				// logged status matches what the client receives.
+				c.Error(err)
+			}
+
+			res := c.Response()
+			level := slog.LevelInfo
+			switch {
+			case res.Status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case res.Status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+			fields := []any{
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", res.Status),
+				slog.Int64("bytes_out", res.Size),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_ip", c.RealIP()),
+				slog.String("user_agent", req.UserAgent()),
+			}
+			if err != nil {
+				fields = append(fields, slog.String("error", err.Error()))
+			}
+			// Use the request's current logger so attributes added by the
+			// handler, such as the user ID, are included.
+			FromContext(c.Request().Context()).Log(req.Context(), level, "request", fields...)
+			return err
+		}
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		requestID      string
+		expectedID     string
+		expectedStatus int
+		expectedLevel  string
+		expectedRoute  string
+	}{
+		{
+			name:           "request ID kept",
+			path:           "/users/7",
+			requestID:      "client-abc-123",
+			expectedID:     "client-abc-123",
+			expectedStatus: http.StatusOK,
+			expectedLevel:  "INFO",
+			expectedRoute:  "/users/:id",
+		},
+		{
+			name:           "request ID generated",
+			path:           "/users/7",
+			expectedStatus: http.StatusOK,
+			expectedLevel:  "INFO",
+			expectedRoute:  "/users/:id",
+		},
+		{
+			name:           "invalid request ID replaced",
+			path:           "/users/7",
+			requestID:      "has spaces",
+			expectedStatus: http.StatusOK,
+			expectedLevel:  "INFO",
+			expectedRoute:  "/users/:id",
+		},
+		{
+			name:           "oversized request ID replaced",
+			path:           "/users/7",
+			requestID:      strings.Repeat("a", maxRequestIDLength+1),
+			expectedStatus: http.StatusOK,
+			expectedLevel:  "INFO",
+			expectedRoute:  "/users/:id",
+		},
+		{
+			name:           "client error",
+			path:           "/users/0",
+			expectedStatus: http.StatusBadRequest,
+			expectedLevel:  "WARN",
+			expectedRoute:  "/users/:id",
+		},
+		{
+			name:           "server error",
+			path:           "/fail",
+			expectedStatus: http.StatusInternalServerError,
+			expectedLevel:  "ERROR",
+			expectedRoute:  "/fail",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, "info")
+			require.NoError(t, err)
+
+			var contextRequestID string
+			e := echo.New()
+			e.Use(Middleware(logger))
+			e.GET("/users/:id", func(c echo.Context) error {
+				contextRequestID = RequestID(c.Request().Context())
+				ctx := With(c.Request().Context(), slog.String("user_id", c.Param("id")))
+				c.SetRequest(c.Request().WithContext(ctx))
+				if c.Param("id") == "0" {
+					return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+				}
+				FromContext(ctx).Info("user loaded")
+				return c.String(http.StatusOK, "ok")
+			})
+			e.GET("/fail", func(c echo.Context) error {
+				return echo.NewHTTPError(http.StatusInternalServerError, "boom")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.requestID != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			requestID := rec.Header().Get(echo.HeaderXRequestID)
+			if tt.expectedID != "" {
+				assert.Equal(t, tt.expectedID, requestID)
+			} else {
+				assert.Regexp(t, "^[0-9a-f]{32}$", requestID)
+			}
+
+			records := decodeRecords(t, &buf)
+			require.NotEmpty(t, records)
+			for _, record := range records {
+				assert.Equal(t, requestID, record["request_id"])
+				assert.Equal(t, tt.expectedRoute, record["route"])
+			}
+			access := records[len(records)-1]
+			assert.Equal(t, "request", access["msg"])
+			assert.Equal(t, tt.expectedLevel, access["level"])
+			assert.Equal(t, float64(tt.expectedStatus), access["status"])
+			assert.Equal(t, tt.path, access["path"])
+			if tt.expectedRoute == "/users/:id" {
+				assert.Equal(t, strings.TrimPrefix(tt.path, "/users/"), access["user_id"])
+				assert.Equal(t, requestID, contextRequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"surfe/internal/cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheHitsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cache", "hits_total"),
+		"Service calls answered from the cache or by a computation already in progress, by method.",
+		[]string{"method"}, nil)
+	cacheMissesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cache", "misses_total"),
+		"Service calls that had to be computed, by method.",
+		[]string{"method"}, nil)
+)
+
+// cacheCollector reads a cache's counters at scrape time.
+type cacheCollector struct {
+	cache *cache.Cache
+}
+
+func (c cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+}
+
+func (c cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.cache.Stats() {
+		ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits), stats.Method)
+		ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses), stats.Method)
+	}
+}
+
+// Cache exposes the hit and miss counts and the size of c. It must be called
+// at most once per Metrics.
+func (m *Metrics) Cache(c *cache.Cache) {
+	m.registry.MustRegister(
+		cacheCollector{cache: c},
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_entries",
+			Help:      "Results currently held by the cache.",
+		}, func() float64 {
+			return float64(c.Len())
+		}),
+	)
+}
@@ -0,0 +1,109 @@
+// Package metrics exposes Prometheus metrics for the HTTP, service and
+// repository layers. HTTP traffic is recorded by an Echo middleware; services
+// and repositories are instrumented by wrapping their interfaces.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "surfe"
+
+// unmatchedRoute labels requests that did not match any route, so unknown
+// paths do not each create a new series.
+const unmatchedRoute = "unmatched"
+
+type Metrics struct {
+	registry           *prometheus.Registry
+	httpRequests       *prometheus.CounterVec
+	httpDuration       *prometheus.HistogramVec
+	serviceDuration    *prometheus.HistogramVec
+	repositoryDuration *prometheus.HistogramVec
+}
+
+// New creates the collectors on a dedicated registry, along with the Go
+// runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		serviceDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "service_duration_seconds",
+			Help:      "Service call latency by service and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"service", "method"}),
+		repositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_duration_seconds",
+			Help:      "Repository query latency by repository and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"repository", "method"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.serviceDuration,
+		m.repositoryDuration,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware records the count and latency of every request, labelled with
+// the route template rather than the raw path.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response so the
+				// recorded status matches what the client receives.
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			method := c.Request().Method
+			status := strconv.Itoa(c.Response().Status)
+
+			m.httpRequests.WithLabelValues(method, route, status).Inc()
+			m.httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+func (m *Metrics) observeService(service, method string, start time.Time) {
+	m.serviceDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+}
+
+func (m *Metrics) observeRepository(repository, method string, start time.Time) {
+	m.repositoryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+}
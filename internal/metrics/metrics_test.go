@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"surfe/internal/cache"
+	"surfe/internal/models"
+	"surfe/internal/repository"
+	"surfe/internal/services"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+	e := echo.New()
+	e.Use(m.Middleware())
+	e.GET("/api/v1/users/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+		}
+		return c.JSON(http.StatusOK, map[string]string{"id": c.Param("id")})
+	})
+
+	for _, path := range []string{"/api/v1/users/1", "/api/v1/users/2", "/api/v1/users/0", "/unknown"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/api/v1/users/:id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/api/v1/users/:id", "400")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.UserRepository(&stubUserRepository{status: models.DatasetStatus{Name: "users", Loaded: true, Records: 3}})
+	m.ActionRepository(&stubActionRepository{status: models.DatasetStatus{Name: "actions"}})
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	for _, line := range []string{
+		`surfe_repository_records{repository="users"} 3`,
+		`surfe_repository_loaded{repository="users"} 1`,
+		`surfe_repository_records{repository="actions"} 0`,
+		`surfe_repository_loaded{repository="actions"} 0`,
+		"go_goroutines",
+	} {
+		assert.True(t, strings.Contains(body, line), "missing %q", line)
+	}
+}
+
+func TestDecorators(t *testing.T) {
+	m := New()
+	userRepo := m.UserRepository(&stubUserRepository{})
+	actionRepo := m.ActionRepository(&stubActionRepository{})
+	actionService := m.ActionService(&stubActionService{})
+
+	userRepo.GetByID(context.Background(), 1)
+	actionRepo.GetAll(context.Background())
+	actionRepo.GetAll(context.Background())
+	actionService.GetReferralIndex(context.Background())
+	actionService.GetNextActionProbabilities(context.Background(), "WELCOME")
+
+	types, err := actionService.GetActionTypes(context.Background())
+
+	// Only the instrumented methods create series; the rest pass through.
+	assert.NoError(t, err)
+	assert.Len(t, types, 1)
+	assert.Equal(t, 2, testutil.CollectAndCount(m.repositoryDuration))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.serviceDuration))
+}
+
+func TestCache(t *testing.T) {
+	m := New()
+	c := cache.New(services.NewVersionService(&stubActionRepository{status: loadedStatus}), 10)
+	m.Cache(c)
+	actionService := c.ActionService(&stubActionService{})
+
+	actionService.GetReferralIndex(context.Background())
+	actionService.GetReferralIndex(context.Background())
+	actionService.GetNextActionProbabilities(context.Background(), "WELCOME")
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		`surfe_cache_hits_total{method="GetReferralIndex"} 1`,
+		`surfe_cache_misses_total{method="GetReferralIndex"} 1`,
+		`surfe_cache_hits_total{method="GetNextActionProbabilities"} 0`,
+		`surfe_cache_misses_total{method="GetNextActionProbabilities"} 1`,
+		"surfe_cache_entries 2",
+	} {
+		assert.True(t, strings.Contains(body, line), "missing %q", line)
+	}
+}
+
+var loadedAt = time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+
+var loadedStatus = models.DatasetStatus{Name: "actions", Loaded: true, LoadedAt: &loadedAt, ModifiedAt: &loadedAt, Version: 1}
+
+type stubUserRepository struct {
+	repository.UserRepository
+	status models.DatasetStatus
+}
+
+func (r *stubUserRepository) Status(ctx context.Context) models.DatasetStatus { return r.status }
+func (r *stubUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return &models.User{ID: id}, nil
+}
+
+type stubActionRepository struct {
+	repository.ActionRepository
+	status models.DatasetStatus
+}
+
+func (r *stubActionRepository) Status(ctx context.Context) models.DatasetStatus { return r.status }
+func (r *stubActionRepository) GetAll(ctx context.Context) ([]models.Action, error) {
+	return nil, nil
+}
+
+type stubActionService struct {
+	services.ActionService
+}
+
+func (s *stubActionService) GetActionTypes(ctx context.Context) ([]models.ActionType, error) {
+	return []models.ActionType{{Name: "WELCOME"}}, nil
+}
+
+func (s *stubActionService) GetNextActionProbabilities(ctx context.Context, actionType string) (map[string]float64, error) {
+	return map[string]float64{}, nil
+}
+
+func (s *stubActionService) GetReferralIndex(ctx context.Context) (map[int]int, error) {
+	return map[int]int{}, nil
+}
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"context"
+	"surfe/internal/models"
+	"surfe/internal/repository"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registerDataset exposes the number of records a repository has loaded and
+// whether its data is loaded at all. Both are read at scrape time.
+func (m *Metrics) registerDataset(name string, dataset repository.Dataset) {
+	labels := prometheus.Labels{"repository": name}
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "repository_records",
+			Help:        "Records currently loaded by the repository.",
+			ConstLabels: labels,
+		}, func() float64 {
+			return float64(dataset.Status(context.Background()).Records)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "repository_loaded",
+			Help:        "Whether the repository's data is loaded (1) or not (0).",
+			ConstLabels: labels,
+		}, func() float64 {
+			if dataset.Status(context.Background()).Loaded {
+				return 1
+			}
+			return 0
+		}),
+	)
+}
+
+type actionRepository struct {
+	repository.ActionRepository
+	metrics *Metrics
+}
+
+// ActionRepository instruments an action repository. It must be called at
+// most once per Metrics, as it registers the repository's gauges.
+func (m *Metrics) ActionRepository(next repository.ActionRepository) repository.ActionRepository {
+	m.registerDataset("actions", next)
+	return m.WorkspaceActionRepository(next)
+}
+
+// WorkspaceActionRepository instruments the action repository of a
+// workspace. It records query latencies alongside the server's own
+// repository but registers no gauges, so it may be called any number of
+// times.
+func (m *Metrics) WorkspaceActionRepository(next repository.ActionRepository) repository.ActionRepository {
+	return &actionRepository{
+		ActionRepository: next,
+		metrics:          m,
+	}
+}
+
+func (r *actionRepository) GetByUserID(ctx context.Context, userID int) ([]models.Action, error) {
+	defer r.metrics.observeRepository("actions", "GetByUserID", time.Now())
+	return r.ActionRepository.GetByUserID(ctx, userID)
+}
+
+func (r *actionRepository) CountByUserIDs(ctx context.Context, userIDs []int) (map[int]int, error) {
+	defer r.metrics.observeRepository("actions", "CountByUserIDs", time.Now())
+	return r.ActionRepository.CountByUserIDs(ctx, userIDs)
+}
+
+func (r *actionRepository) GetAll(ctx context.Context) ([]models.Action, error) {
+	defer r.metrics.observeRepository("actions", "GetAll", time.Now())
+	return r.ActionRepository.GetAll(ctx)
+}
+
+func (r *actionRepository) GetNextActions(ctx context.Context, actionType string) (map[string]int, int, error) {
+	defer r.metrics.observeRepository("actions", "GetNextActions", time.Now())
+	return r.ActionRepository.GetNextActions(ctx, actionType)
+}
+
+func (r *actionRepository) GetReferrals(ctx context.Context) (map[int][]int, error) {
+	defer r.metrics.observeRepository("actions", "GetReferrals", time.Now())
+	return r.ActionRepository.GetReferrals(ctx)
+}
+
+func (r *actionRepository) Add(ctx context.Context, action models.Action) (models.Action, error) {
+	defer r.metrics.observeRepository("actions", "Add", time.Now())
+	return r.ActionRepository.Add(ctx, action)
+}
+
+func (r *actionRepository) DeleteByUserID(ctx context.Context, userID int) (int, int, error) {
+	defer r.metrics.observeRepository("actions", "DeleteByUserID", time.Now())
+	return r.ActionRepository.DeleteByUserID(ctx, userID)
+}
+
+type userRepository struct {
+	repository.UserRepository
+	metrics *Metrics
+}
+
+// UserRepository instruments a user repository. It must be called at most
+// once per Metrics, as it registers the repository's gauges.
+func (m *Metrics) UserRepository(next repository.UserRepository) repository.UserRepository {
+	m.registerDataset("users", next)
+	return m.WorkspaceUserRepository(next)
+}
+
+// WorkspaceUserRepository instruments the user repository of a workspace,
+// like WorkspaceActionRepository.
+func (m *Metrics) WorkspaceUserRepository(next repository.UserRepository) repository.UserRepository {
+	return &userRepository{
+		UserRepository: next,
+		metrics:        m,
+	}
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	defer r.metrics.observeRepository("users", "GetByID", time.Now())
+	return r.UserRepository.GetByID(ctx, id)
+}
+
+func (r *userRepository) GetByIDs(ctx context.Context, ids []int) (map[int]models.User, error) {
+	defer r.metrics.observeRepository("users", "GetByIDs", time.Now())
+	return r.UserRepository.GetByIDs(ctx, ids)
+}
+
+func (r *userRepository) GetAll(ctx context.Context) ([]models.User, error) {
+	defer r.metrics.observeRepository("users", "GetAll", time.Now())
+	return r.UserRepository.GetAll(ctx)
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int) error {
+	defer r.metrics.observeRepository("users", "Delete", time.Now())
+	return r.UserRepository.Delete(ctx, id)
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"surfe/internal/models"
+	"surfe/internal/services"
+	"time"
+)
+
+// actionService times the action service calls that do the most work. The
+// remaining methods are passed through to the embedded service.
+type actionService struct {
+	services.ActionService
+	metrics *Metrics
+}
+
+// ActionService instruments an action service.
+func (m *Metrics) ActionService(next services.ActionService) services.ActionService {
+	return &actionService{
+		ActionService: next,
+		metrics:       m,
+	}
+}
+
+func (s *actionService) GetNextActionProbabilities(ctx context.Context, actionType string) (map[string]float64, error) {
+	defer s.metrics.observeService("action", "GetNextActionProbabilities", time.Now())
+	return s.ActionService.GetNextActionProbabilities(ctx, actionType)
+}
+
+func (s *actionService) GetNextActions(ctx context.Context, actionType string) (*models.NextActions, error) {
+	defer s.metrics.observeService("action", "GetNextActions", time.Now())
+	return s.ActionService.GetNextActions(ctx, actionType)
+}
+
+func (s *actionService) GetReferralIndex(ctx context.Context) (map[int]int, error) {
+	defer s.metrics.observeService("action", "GetReferralIndex", time.Now())
+	return s.ActionService.GetReferralIndex(ctx)
+}
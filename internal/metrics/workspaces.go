@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"surfe/internal/workspace"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Workspaces exposes how many workspaces r holds in memory. It must be
+// called at most once per Metrics.
+func (m *Metrics) Workspaces(r *workspace.Registry) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workspaces_loaded",
+		Help:      "Workspaces currently loaded.",
+	}, func() float64 {
+		return float64(len(r.Loaded()))
+	}))
+}
@@ -8,22 +8,255 @@ type User struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+// IsAnonymous reports whether userID is an anonymous placeholder that took
+// an erased user's place in the referral graph. Placeholders have negative
+// IDs, which no real user can have.
+func IsAnonymous(userID int) bool {
+	return userID < 0
+}
+
 type Action struct {
-	ID         int       `json:"id"`
-	Type       string    `json:"type"`
-	UserID     int       `json:"userId"`
-	TargetUser int       `json:"targetUser"`
+	ID     int    `json:"id"`
+	Type   string `json:"type"`
+	UserID int    `json:"userId"`
+	// TargetUser is the user the action refers to, absent for actions that
+	// do not refer to one. A targetUser of 0 refers to user 0.
+	TargetUser *int      `json:"targetUser,omitempty"`
 	CreatedAt  time.Time `json:"createdAt"`
 }
 
+// Target returns the user the action refers to, and whether it has one.
+func (a Action) Target() (int, bool) {
+	if a.TargetUser == nil {
+		return 0, false
+	}
+	return *a.TargetUser, true
+}
+
+// UserRef returns a reference to user id, for Action.TargetUser.
+func UserRef(id int) *int {
+	return &id
+}
+
+type ActionType struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	TargetsUser bool   `json:"targetsUser"`
+}
+
 type ActionCount struct {
 	Count int `json:"count"`
 }
 
+// UserActionStats breaks down a user's actions over their whole history and,
+// when the request gives a from/to window, within that window.
+type UserActionStats struct {
+	UserID int `json:"userId"`
+	ActionStats
+	Window *ActionWindow `json:"window,omitempty"`
+}
+
+// ActionWindow holds the figures of the actions created at or after From and
+// before To. Either bound may be absent.
+type ActionWindow struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+	ActionStats
+}
+
+// ActionStats summarises a set of actions. FirstActionAt and LastActionAt
+// are null when there are none, and ActiveDays counts the distinct UTC dates
+// with at least one action.
+type ActionStats struct {
+	Total         int               `json:"total"`
+	ByType        []ActionTypeCount `json:"byType"`
+	FirstActionAt *time.Time        `json:"firstActionAt" extensions:"x-nullable"`
+	LastActionAt  *time.Time        `json:"lastActionAt" extensions:"x-nullable"`
+	ActiveDays    int               `json:"activeDays"`
+}
+
+// ActionTypeCount is how many actions of one type a set holds. Lists of them
+// are ordered most frequent first, and by type among equals.
+type ActionTypeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// Erasure reports what erasing a user removed. ActionsAnonymised counts the
+// referrals by or to the user that now belong to an anonymous placeholder.
+type Erasure struct {
+	UserID            int       `json:"userId"`
+	ErasedAt          time.Time `json:"erasedAt"`
+	ActionsDeleted    int       `json:"actionsDeleted"`
+	ActionsAnonymised int       `json:"actionsAnonymised"`
+}
+
+// AuditEvent is one entry of the audit trail.
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Workspace string    `json:"workspace,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	Erasure   *Erasure  `json:"erasure,omitempty"`
+}
+
+// UserBatchRequest lists the users to look up in one request. The validate
+// tag documents the limits the handler enforces.
+type UserBatchRequest struct {
+	IDs []int `json:"ids" validate:"required,min=1,max=100"`
+}
+
+// UserBatch holds one result per distinct requested ID, in the order the
+// IDs were requested.
+type UserBatch struct {
+	Users []UserBatchItem `json:"users"`
+}
+
+// UserBatchItem is the result for one requested ID. User and ActionCount are
+// only set when the user was found.
+type UserBatchItem struct {
+	ID          int   `json:"id"`
+	Found       bool  `json:"found"`
+	User        *User `json:"user,omitempty"`
+	ActionCount *int  `json:"actionCount,omitempty"`
+}
+
 type ActionProbability map[string]float64
 
+// NextActions are the action types users performed right after ActionType,
+// most frequent first.
+type NextActions struct {
+	ActionType string       `json:"actionType"`
+	Total      int          `json:"total"`
+	Next       []NextAction `json:"next"`
+}
+
+type NextAction struct {
+	Type        string  `json:"type"`
+	Count       int     `json:"count"`
+	Probability float64 `json:"probability"`
+}
+
 type ReferralIndex struct {
 	Index map[int]int `json:"index"`
 }
 
 // type ReferralIndex map[int]int
+
+type ReferralCount struct {
+	UserID    int `json:"userId"`
+	Referrals int `json:"referrals"`
+}
+
+type ReferralQuality struct {
+	ReferrerID              int      `json:"referrerId"`
+	Referred                int      `json:"referred"`
+	Activated               int      `json:"activated"`
+	ActivationRate          float64  `json:"activationRate"`
+	MedianActivationSeconds *float64 `json:"medianActivationSeconds" extensions:"x-nullable"`
+}
+
+type ReferralStats struct {
+	Trees             int              `json:"trees"`
+	LargestTrees      []ReferralTree   `json:"largestTrees"`
+	DepthDistribution []DepthCount     `json:"depthDistribution"`
+	Acquisition       Acquisition      `json:"acquisition"`
+	Cohorts           []CohortVirality `json:"cohorts"`
+}
+
+type ReferralTree struct {
+	RootID int `json:"rootId"`
+	Size   int `json:"size"`
+	Depth  int `json:"depth"`
+}
+
+type DepthCount struct {
+	Depth int `json:"depth"`
+	Users int `json:"users"`
+}
+
+type Acquisition struct {
+	Users         int     `json:"users"`
+	Referred      int     `json:"referred"`
+	Organic       int     `json:"organic"`
+	ReferredShare float64 `json:"referredShare"`
+	OrganicShare  float64 `json:"organicShare"`
+}
+
+type CohortVirality struct {
+	Cohort    string  `json:"cohort"`
+	Users     int     `json:"users"`
+	Referrals int     `json:"referrals"`
+	KFactor   float64 `json:"kFactor"`
+}
+
+type ReferralNetwork struct {
+	Nodes []ReferralNode `json:"nodes"`
+	Edges []ReferralEdge `json:"edges"`
+}
+
+type ReferralNode struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ReferralEdge struct {
+	Source     int       `json:"source"`
+	Target     int       `json:"target"`
+	ReferredAt time.Time `json:"referredAt"`
+}
+
+// Problem is an RFC 7807 problem details body.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// Envelope is the body of every successful /api/v2 response. The type of
+// Data depends on the route.
+type Envelope struct {
+	Data  any   `json:"data"`
+	Meta  Meta  `json:"meta"`
+	Links Links `json:"links"`
+}
+
+type Meta struct {
+	// DataVersion is the version of the data the response was built from,
+	// the same as its ETag without the quotes.
+	DataVersion string `json:"dataVersion,omitempty"`
+	// Count is the number of items in data when it is a list.
+	Count *int `json:"count,omitempty"`
+}
+
+type Links struct {
+	Self string `json:"self"`
+}
+
+type DatasetStatus struct {
+	Name       string     `json:"name"`
+	Source     string     `json:"source"`
+	Loaded     bool       `json:"loaded"`
+	LoadedAt   *time.Time `json:"loadedAt"`
+	ModifiedAt *time.Time `json:"modifiedAt"`
+	Version    uint64     `json:"version"`
+	Records    int        `json:"records"`
+	Checksum   string     `json:"checksum,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// DataVersion identifies the state of every dataset behind the API. Tag
+// changes whenever any dataset is reloaded or modified.
+type DataVersion struct {
+	Tag        string
+	ModifiedAt time.Time
+}
+
+type Readiness struct {
+	Ready    bool            `json:"ready"`
+	Datasets []DatasetStatus `json:"datasets"`
+}
@@ -0,0 +1,166 @@
+package openapi_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"surfe/internal/config"
+	"surfe/internal/logging"
+	"surfe/internal/openapi"
+	"surfe/internal/server"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer starts the API over copies of the fixture data files, so that
+// recorded actions do not touch testdata.
+func newServer(t *testing.T) *server.Server {
+	dir := t.TempDir()
+	for _, name := range []string{"users.json", "actions.json"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+
+	cfg := config.Default()
+	cfg.Data.UsersPath = filepath.Join(dir, "users.json")
+	cfg.Data.ActionsPath = filepath.Join(dir, "actions.json")
+	cfg.Audit.LogPath = filepath.Join(dir, "audit.jsonl")
+	cfg.Features.Swagger = false
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithCancel(logging.NewContext(context.Background(), logger))
+	t.Cleanup(cancel)
+	srv, err := server.New(ctx, cfg, logger)
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// loadSpec reads the description the way clients get it, from docs/.
+func loadSpec(t *testing.T) *openapi.Spec {
+	data, err := os.ReadFile(filepath.Join("..", "..", "docs", "swagger.json"))
+	require.NoError(t, err)
+	spec, err := openapi.Load(data)
+	require.NoError(t, err)
+	return spec
+}
+
+// TestContract calls every documented operation and checks that the status
+// and body of each response are what the description promises.
+func TestContract(t *testing.T) {
+	spec := loadSpec(t)
+	srv := newServer(t)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		header         map[string]string
+		expectedStatus int
+	}{
+		{name: "user", method: http.MethodGet, path: "/api/v1/users/1", expectedStatus: http.StatusOK},
+		{name: "unknown user", method: http.MethodGet, path: "/api/v1/users/99", expectedStatus: http.StatusNotFound},
+		{name: "invalid user ID", method: http.MethodGet, path: "/api/v1/users/abc", expectedStatus: http.StatusBadRequest},
+		{name: "user not modified", method: http.MethodGet, path: "/api/v1/users/1", header: map[string]string{"If-None-Match": "*"}, expectedStatus: http.StatusNotModified},
+		{name: "user action count", method: http.MethodGet, path: "/api/v1/users/2/actions/count", expectedStatus: http.StatusOK},
+		{name: "user action stats", method: http.MethodGet, path: "/api/v1/users/2/actions/stats?from=2024-01-01T00:00:00Z", expectedStatus: http.StatusOK},
+		{name: "action stats of unknown user", method: http.MethodGet, path: "/api/v1/users/99/actions/stats", expectedStatus: http.StatusNotFound},
+		{name: "users in bulk", method: http.MethodPost, path: "/api/v1/users/batch", body: `{"ids":[2,99,1,2,-1]}`, expectedStatus: http.StatusOK},
+		{name: "users in bulk without IDs", method: http.MethodPost, path: "/api/v1/users/batch", body: `{"ids":[]}`, expectedStatus: http.StatusBadRequest},
+		{name: "action types", method: http.MethodGet, path: "/api/v1/action-types", expectedStatus: http.StatusOK},
+		{name: "action type", method: http.MethodGet, path: "/api/v1/action-types/refer_user", expectedStatus: http.StatusOK},
+		{name: "unknown action type", method: http.MethodGet, path: "/api/v1/action-types/LOGUOT", expectedStatus: http.StatusNotFound},
+		{name: "next action probabilities", method: http.MethodGet, path: "/api/v1/actions/WELCOME/next", expectedStatus: http.StatusOK},
+		{name: "next actions of unknown type", method: http.MethodGet, path: "/api/v1/actions/LOGUOT/next", expectedStatus: http.StatusNotFound},
+		{name: "referral index", method: http.MethodGet, path: "/api/v1/actions/referral", expectedStatus: http.StatusOK},
+		{name: "record action", method: http.MethodPost, path: "/api/v1/actions", body: `{"type":"CONNECT_CRM","userId":4}`, expectedStatus: http.StatusCreated},
+		{name: "record invalid action", method: http.MethodPost, path: "/api/v1/actions", body: `{"userId":4}`, expectedStatus: http.StatusBadRequest},
+		{name: "record action for unknown user", method: http.MethodPost, path: "/api/v1/actions", body: `{"type":"CONNECT_CRM","userId":99}`, expectedStatus: http.StatusBadRequest},
+		{name: "referral quality", method: http.MethodGet, path: "/api/v1/referrals/quality?activation=CONNECT_CRM", expectedStatus: http.StatusOK},
+		{name: "referral quality of unknown type", method: http.MethodGet, path: "/api/v1/referrals/quality?activation=LOGUOT", expectedStatus: http.StatusBadRequest},
+		{name: "referral stats", method: http.MethodGet, path: "/api/v1/referrals/stats?top=2", expectedStatus: http.StatusOK},
+		{name: "referral graph", method: http.MethodGet, path: "/api/v1/referrals/graph", expectedStatus: http.StatusOK},
+		{name: "referral graph as DOT", method: http.MethodGet, path: "/api/v1/referrals/graph?format=dot&root=1", expectedStatus: http.StatusOK},
+		{name: "referral graph of unknown root", method: http.MethodGet, path: "/api/v1/referrals/graph?root=99", expectedStatus: http.StatusNotFound},
+
+		{name: "v2 user", method: http.MethodGet, path: "/api/v2/users/1", expectedStatus: http.StatusOK},
+		{name: "v2 unknown user", method: http.MethodGet, path: "/api/v2/users/99", expectedStatus: http.StatusNotFound},
+		{name: "v2 user action count", method: http.MethodGet, path: "/api/v2/users/2/actions/count", expectedStatus: http.StatusOK},
+		{name: "v2 user action stats", method: http.MethodGet, path: "/api/v2/users/4/actions/stats", expectedStatus: http.StatusOK},
+		{name: "v2 users in bulk", method: http.MethodPost, path: "/api/v2/users/batch", body: `{"ids":[3,99]}`, expectedStatus: http.StatusOK},
+		{name: "v2 action types", method: http.MethodGet, path: "/api/v2/action-types", expectedStatus: http.StatusOK},
+		{name: "v2 action type", method: http.MethodGet, path: "/api/v2/action-types/WELCOME", expectedStatus: http.StatusOK},
+		{name: "v2 next actions", method: http.MethodGet, path: "/api/v2/actions/WELCOME/next", expectedStatus: http.StatusOK},
+		{name: "v2 referral index", method: http.MethodGet, path: "/api/v2/actions/referral", expectedStatus: http.StatusOK},
+		{name: "v2 record action", method: http.MethodPost, path: "/api/v2/actions", body: `{"type":"CONNECT_CRM","userId":4}`, expectedStatus: http.StatusCreated},
+		{name: "v2 referral quality", method: http.MethodGet, path: "/api/v2/referrals/quality", expectedStatus: http.StatusOK},
+		{name: "v2 referral stats", method: http.MethodGet, path: "/api/v2/referrals/stats", expectedStatus: http.StatusOK},
+		{name: "v2 invalid top", method: http.MethodGet, path: "/api/v2/referrals/stats?top=-1", expectedStatus: http.StatusBadRequest},
+		{name: "v2 referral graph", method: http.MethodGet, path: "/api/v2/referrals/graph?root=1&depth=1", expectedStatus: http.StatusOK},
+
+		// Erasures come last, as they change the data the cases above read.
+		{name: "erase user", method: http.MethodDelete, path: "/api/v1/users/2", expectedStatus: http.StatusOK},
+		{name: "erase unknown user", method: http.MethodDelete, path: "/api/v1/users/2", expectedStatus: http.StatusNotFound},
+		{name: "v2 erase user", method: http.MethodDelete, path: "/api/v2/users/3", expectedStatus: http.StatusOK},
+		{name: "referral graph after erasure", method: http.MethodGet, path: "/api/v1/referrals/graph", expectedStatus: http.StatusOK},
+	}
+
+	covered := make(map[openapi.Operation]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+
+			srv.Echo.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+			operation, err := spec.FindOperation(req)
+			require.NoError(t, err)
+			covered[operation] = true
+			assert.NoError(t, spec.ValidateResponse(req, rec.Code, rec.Header(), rec.Body.Bytes()))
+		})
+	}
+
+	for _, operation := range spec.Operations() {
+		assert.True(t, covered[operation], "no contract test calls %s", operation)
+	}
+}
+
+// routeParam matches Echo's path parameters, :id in /users/:id.
+var routeParam = regexp.MustCompile(`:([a-zA-Z]+)`)
+
+// TestContract_RoutesDocumented checks that every API route the server
+// registers is in the description.
+func TestContract_RoutesDocumented(t *testing.T) {
+	spec := loadSpec(t)
+	srv := newServer(t)
+
+	documented := make(map[openapi.Operation]bool)
+	for _, operation := range spec.Operations() {
+		documented[operation] = true
+	}
+	for _, route := range srv.Echo.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") || route.Method == echo.RouteNotFound {
+			continue
+		}
+		operation := openapi.Operation{Method: route.Method, Path: routeParam.ReplaceAllString(route.Path, "{$1}")}
+		assert.True(t, documented[operation], "%s is not documented", operation)
+	}
+}
@@ -0,0 +1,24 @@
+package openapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ValidateRequests rejects requests whose parameters or body do not match
+// their operation in spec with 400 Bad Request before they reach the
+// handler. Requests spec has no operation for, such as health checks and
+// workspace routes, are passed through.
+func ValidateRequests(spec *Spec) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := spec.ValidateRequest(c.Request())
+			if err != nil && !errors.Is(err, ErrNoOperation) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return next(c)
+		}
+	}
+}
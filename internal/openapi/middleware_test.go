@@ -0,0 +1,95 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"surfe/internal/handlers"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRequests(t *testing.T) {
+	spec, err := Default()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "valid request",
+			method:         http.MethodGet,
+			path:           "/api/v1/users/1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid path parameter",
+			method:         http.MethodGet,
+			path:           "/api/v1/users/abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `parameter \"id\" in path has an error`,
+		},
+		{
+			name:           "invalid query parameter",
+			method:         http.MethodGet,
+			path:           "/api/v2/referrals/stats?top=five",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `parameter \"top\" in query has an error`,
+		},
+		{
+			name:           "invalid body",
+			method:         http.MethodPost,
+			path:           "/api/v1/actions",
+			body:           `{"type":"WELCOME","userId":"one"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `request body has an error`,
+		},
+		{
+			name:           "valid body reaches the handler",
+			method:         http.MethodPost,
+			path:           "/api/v1/actions",
+			body:           `{"type":"WELCOME","userId":1}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"type":"WELCOME","userId":1}`,
+		},
+		{
+			name:           "undocumented route",
+			method:         http.MethodGet,
+			path:           "/api/v1/workspaces/acme/users/abc",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = handlers.HTTPErrorHandler
+			e.Use(ValidateRequests(spec))
+			echoBody := func(c echo.Context) error {
+				return c.Stream(http.StatusOK, echo.MIMEApplicationJSON, c.Request().Body)
+			}
+			e.GET("/api/v1/users/:id", echoBody)
+			e.GET("/api/v2/referrals/stats", echoBody)
+			e.POST("/api/v1/actions", echoBody)
+			e.GET("/api/v1/workspaces/:ws/*", echoBody)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.expectedBody)
+		})
+	}
+}
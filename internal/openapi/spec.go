@@ -0,0 +1,174 @@
+// Package openapi checks requests and responses against the API's OpenAPI
+// description, the Swagger 2.0 document that swag generates into docs/ from
+// the handler annotations.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"surfe/docs"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// Spec is a loaded API description.
+type Spec struct {
+	doc    *openapi3.T
+	router routers.Router
+}
+
+// Load reads a Swagger 2.0 document. Its paths are matched below its base
+// path on any host.
+func Load(data []byte) (*Spec, error) {
+	var doc2 openapi2.T
+	if err := json.Unmarshal(data, &doc2); err != nil {
+		return nil, fmt.Errorf("openapi: %v", err)
+	}
+	doc, err := openapi2conv.ToV3(&doc2)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: %v", err)
+	}
+	// The server is relative so requests match whatever host they were
+	// sent to.
+	doc.Servers = openapi3.Servers{{URL: doc2.BasePath}}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("openapi: invalid document: %v", err)
+	}
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: %v", err)
+	}
+	return &Spec{doc: doc, router: router}, nil
+}
+
+// Default loads the description compiled into the binary, the same one
+// served under /swagger.
+func Default() (*Spec, error) {
+	return Load([]byte(docs.SwaggerInfo.ReadDoc()))
+}
+
+// Operation is a method and path template in the description, such as GET
+// /api/v1/users/{id}.
+type Operation struct {
+	Method string
+	Path   string
+}
+
+func (o Operation) String() string {
+	return o.Method + " " + o.Path
+}
+
+// Operations lists every operation in the description, ordered by path and
+// method.
+func (s *Spec) Operations() []Operation {
+	base := s.doc.Servers[0].URL
+	var operations []Operation
+	for path, item := range s.doc.Paths.Map() {
+		for method := range item.Operations() {
+			operations = append(operations, Operation{Method: method, Path: base + path})
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Path != operations[j].Path {
+			return operations[i].Path < operations[j].Path
+		}
+		return operations[i].Method < operations[j].Method
+	})
+	return operations
+}
+
+// FindOperation returns the operation matching req, or ErrNoOperation if the
+// description has none.
+func (s *Spec) FindOperation(req *http.Request) (Operation, error) {
+	input, err := s.requestInput(req)
+	if err != nil {
+		return Operation{}, err
+	}
+	return Operation{Method: input.Route.Method, Path: s.doc.Servers[0].URL + input.Route.Path}, nil
+}
+
+// ErrNoOperation is returned for requests the description has no operation
+// for.
+var ErrNoOperation = errors.New("no operation matches the request")
+
+// ValidateRequest checks the request's parameters and body against its
+// operation, or returns ErrNoOperation if the description has none. The
+// body is left for the handler to read.
+func (s *Spec) ValidateRequest(req *http.Request) error {
+	input, err := s.requestInput(req)
+	if err != nil {
+		return err
+	}
+	return openapi3filter.ValidateRequest(req.Context(), input)
+}
+
+// ValidateResponse checks that a response to req has a status and content
+// type the operation documents and, for JSON, a body matching the schema.
+// Swagger 2.0 has one schema per status, so other formats such as DOT are
+// not checked beyond their content type.
+func (s *Spec) ValidateResponse(req *http.Request, status int, header http.Header, body []byte) error {
+	input, err := s.requestInput(req)
+	if err != nil {
+		return err
+	}
+	return openapi3filter.ValidateResponse(req.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 status,
+		Header:                 header,
+		Body:                   io.NopCloser(bytes.NewReader(body)),
+		Options: &openapi3filter.Options{
+			ExcludeResponseBody:   !isJSON(header.Get("Content-Type")),
+			IncludeResponseStatus: true,
+			MultiError:            true,
+		},
+	})
+}
+
+// isJSON reports whether contentType is application/json or a JSON-based
+// type such as application/problem+json.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+func (s *Spec) requestInput(req *http.Request) (*openapi3filter.RequestValidationInput, error) {
+	route, pathParams, err := s.router.FindRoute(req)
+	if err != nil {
+		var routeErr *routers.RouteError
+		if errors.As(err, &routeErr) {
+			return nil, ErrNoOperation
+		}
+		return nil, err
+	}
+	options := &openapi3filter.Options{
+		SkipSettingDefaults: true,
+		MultiError:          true,
+	}
+	// Request errors are sent to clients, who need the reason but not the
+	// schema and value that are otherwise appended.
+	options.WithCustomSchemaErrorFunc(func(err *openapi3.SchemaError) string {
+		if path := err.JSONPointer(); len(path) > 0 {
+			return fmt.Sprintf("%s: %s", strings.Join(path, "."), err.Reason)
+		}
+		return err.Reason
+	})
+	return &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    options,
+	}, nil
+}
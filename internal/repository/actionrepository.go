@@ -1,37 +1,97 @@
 package repository
 
 import (
-	"encoding/json"
-	"os"
+	"context"
+	"fmt"
+	"log/slog"
 	"sort"
+	"surfe/internal/actiontypes"
+	"surfe/internal/apperrors"
+	"surfe/internal/ctxcheck"
+	"surfe/internal/logging"
 	"surfe/internal/models"
+	"sync"
 )
 
 type actionRepository struct {
+	mu      sync.RWMutex
+	dataset datasetState
+	types   *actiontypes.Registry
 	actions []models.Action
+	nextID  int
+	dirty   bool
 }
 
-func NewActionRepository(filePath string) (ActionRepository, error) {
-	repo := &actionRepository{}
-	if err := repo.loadData(filePath); err != nil {
+// NewActionRepository loads actions from a JSON file and fails if the file
+// cannot be read. Every action type seen in the file is observed by the
+// registry.
+func NewActionRepository(filePath string, types *actiontypes.Registry) (ActionRepository, error) {
+	repo := OpenActionRepository(filePath, types)
+	if err := repo.Load(context.Background()); err != nil {
 		return nil, err
 	}
 	return repo, nil
 }
 
-func (r *actionRepository) loadData(filePath string) error {
-	file, err := os.Open(filePath) // Adjust the path if the file is in a different location
+// OpenActionRepository returns a repository backed by a JSON file without
+// loading it. Queries fail with apperrors.ErrUnavailable until Load succeeds.
+func OpenActionRepository(filePath string, types *actiontypes.Registry) ActionRepository {
+	return &actionRepository{
+		dataset: datasetState{name: "actions", source: filePath},
+		types:   types,
+	}
+}
+
+func (r *actionRepository) Load(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var actions []models.Action
+	checksum, err := readJSONFile(r.dataset.source, &actions)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if err != nil {
+		r.dataset.loadErr = err
 		return err
 	}
-	defer file.Close()
 
-	return json.NewDecoder(file).Decode(&r.actions)
+	r.actions = actions
+	r.nextID = 0
+	r.dirty = false
+	for _, action := range r.actions {
+		r.types.Observe(action.Type)
+		if action.ID >= r.nextID {
+			r.nextID = action.ID + 1
+		}
+	}
+	r.dataset.markLoaded(ctx, checksum, len(actions))
+	return nil
+}
+
+func (r *actionRepository) Status(ctx context.Context) models.DatasetStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.dataset.status()
 }
 
-func (r *actionRepository) GetByUserID(userID int) ([]models.Action, error) {
+func (r *actionRepository) GetByUserID(ctx context.Context, userID int) ([]models.Action, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return nil, err
+	}
+	recordScanned(ctx, len(r.actions))
+
 	userActions := []models.Action{}
-	for _, action := range r.actions {
+	for i, action := range r.actions {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return nil, err
+		}
 		if action.UserID == userID {
 			userActions = append(userActions, action)
 		}
@@ -39,15 +99,61 @@ func (r *actionRepository) GetByUserID(userID int) ([]models.Action, error) {
 	return userActions, nil
 }
 
-func (r *actionRepository) GetAll() ([]models.Action, error) {
+func (r *actionRepository) CountByUserIDs(ctx context.Context, userIDs []int) (map[int]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int, len(userIDs))
+	for _, id := range userIDs {
+		counts[id] = 0
+	}
+	recordScanned(ctx, len(r.actions))
+
+	for i, action := range r.actions {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		if _, found := counts[action.UserID]; found {
+			counts[action.UserID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *actionRepository) GetAll(ctx context.Context) ([]models.Action, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	recordScanned(ctx, len(r.actions))
+
 	actions := make([]models.Action, len(r.actions))
 	copy(actions, r.actions)
 	return actions, nil
 }
 
-func (r *actionRepository) GetNextActions(actionType string) (map[string]int, int, error) {
+func (r *actionRepository) GetNextActions(ctx context.Context, actionType string) (map[string]int, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return nil, 0, err
+	}
+	recordScanned(ctx, len(r.actions))
+
 	userActions := make(map[int][]models.Action)
-	for _, a := range r.actions {
+	for i, a := range r.actions {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return nil, 0, err
+		}
 		userActions[a.UserID] = append(userActions[a.UserID], a)
 	}
 
@@ -55,6 +161,10 @@ func (r *actionRepository) GetNextActions(actionType string) (map[string]int, in
 	total := 0
 
 	for _, actions := range userActions {
+		// Sorting dominates the cost here, so check once per user.
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
 
 		sort.Slice(actions, func(i, j int) bool {
 			return actions[i].CreatedAt.Before(actions[j].CreatedAt)
@@ -71,12 +181,117 @@ func (r *actionRepository) GetNextActions(actionType string) (map[string]int, in
 	return counts, total, nil
 }
 
-func (r *actionRepository) GetReferrals() (map[int][]int, error) {
+func (r *actionRepository) GetReferrals(ctx context.Context) (map[int][]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return nil, err
+	}
+	recordScanned(ctx, len(r.actions))
+
 	referrals := make(map[int][]int)
-	for _, action := range r.actions {
-		if action.Type == "REFER_USER" {
-			referrals[action.UserID] = append(referrals[action.UserID], action.TargetUser)
+	for i, action := range r.actions {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		if target, found := action.Target(); found && r.types.TargetsUser(action.Type) {
+			referrals[action.UserID] = append(referrals[action.UserID], target)
 		}
 	}
 	return referrals, nil
 }
+
+func (r *actionRepository) Add(ctx context.Context, action models.Action) (models.Action, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return models.Action{}, err
+	}
+
+	action.ID = r.nextID
+	r.nextID++
+	r.actions = append(r.actions, action)
+	r.dirty = true
+	r.dataset.markModified(len(r.actions))
+	return action, nil
+}
+
+func (r *actionRepository) DeleteByUserID(ctx context.Context, userID int) (int, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return 0, 0, err
+	}
+	if userID < 0 {
+		return 0, 0, fmt.Errorf("%w: user ID %d", apperrors.ErrInvalidArgument, userID)
+	}
+	recordScanned(ctx, len(r.actions))
+
+	// The placeholder gets the next negative ID not yet taken by an earlier
+	// erasure.
+	placeholder := -1
+	for i, action := range r.actions {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return 0, 0, err
+		}
+		placeholder = min(placeholder, action.UserID-1)
+		if target, found := action.Target(); found {
+			placeholder = min(placeholder, target-1)
+		}
+	}
+
+	// The new slice is only swapped in once it is complete, so a cancelled
+	// deletion leaves the data untouched.
+	kept := make([]models.Action, 0, len(r.actions))
+	deleted, anonymised := 0, 0
+	for _, action := range r.actions {
+		target, hasTarget := action.Target()
+		targetsUser := hasTarget && target == userID
+		switch {
+		case !r.types.TargetsUser(action.Type):
+			if action.UserID == userID {
+				deleted++
+				continue
+			}
+		case action.UserID == userID || targetsUser:
+			if action.UserID == userID {
+				action.UserID = placeholder
+			}
+			if targetsUser {
+				action.TargetUser = models.UserRef(placeholder)
+			}
+			anonymised++
+		}
+		kept = append(kept, action)
+	}
+	if deleted == 0 && anonymised == 0 {
+		return 0, 0, nil
+	}
+
+	r.actions = kept
+	r.dirty = true
+	r.dataset.markModified(len(r.actions))
+	return deleted, anonymised, nil
+}
+
+func (r *actionRepository) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := writeJSONFile(r.dataset.source, r.actions); err != nil {
+		return err
+	}
+	r.dirty = false
+	logging.FromContext(ctx).Info("dataset flushed",
+		slog.String("dataset", r.dataset.name), slog.Int("records", len(r.actions)))
+	return nil
+}
@@ -1,11 +1,15 @@
 package repository
 
 import (
+	"context"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"surfe/internal/actiontypes"
+	"surfe/internal/apperrors"
 	"surfe/internal/models"
 
 	"github.com/stretchr/testify/assert"
@@ -23,14 +27,12 @@ func setupActionTestFile(t *testing.T) (string, func()) {
 			"id": 1,
 			"type": "LOGIN",
 			"userId": 1,
-			"targetUser": 0,
 			"createdAt": "2024-03-11T20:00:00Z"
 		},
 		{
 			"id": 2,
 			"type": "VIEW_PROFILE",
 			"userId": 1,
-			"targetUser": 0,
 			"createdAt": "2024-03-11T20:01:00Z"
 		},
 		{
@@ -51,7 +53,6 @@ func setupActionTestFile(t *testing.T) (string, func()) {
 			"id": 5,
 			"type": "LOGIN",
 			"userId": 2,
-			"targetUser": 0,
 			"createdAt": "2024-03-11T20:04:00Z"
 		},
 		{
@@ -115,21 +116,19 @@ func TestActionRepository_GetByUserID(t *testing.T) {
 					ID:         1,
 					Type:       "LOGIN",
 					UserID:     1,
-					TargetUser: 0,
 					CreatedAt:  time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC),
 				},
 				{
 					ID:         2,
 					Type:       "VIEW_PROFILE",
 					UserID:     1,
-					TargetUser: 0,
 					CreatedAt:  time.Date(2024, 3, 11, 20, 1, 0, 0, time.UTC),
 				},
 				{
 					ID:         3,
 					Type:       "REFER_USER",
 					UserID:     1,
-					TargetUser: 2,
+					TargetUser: models.UserRef(2),
 					CreatedAt:  time.Date(2024, 3, 11, 20, 2, 0, 0, time.UTC),
 				},
 			},
@@ -145,12 +144,12 @@ func TestActionRepository_GetByUserID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo, err := NewActionRepository(filePath)
+			repo, err := NewActionRepository(filePath, actiontypes.Default())
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			result, err := repo.GetByUserID(tt.userID)
+			result, err := repo.GetByUserID(context.Background(), tt.userID)
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -162,6 +161,21 @@ func TestActionRepository_GetByUserID(t *testing.T) {
 	}
 }
 
+func TestActionRepository_CountByUserIDs(t *testing.T) {
+	filePath, cleanup := setupActionTestFile(t)
+	defer cleanup()
+
+	repo, err := NewActionRepository(filePath, actiontypes.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := repo.CountByUserIDs(context.Background(), []int{2, 1, 999})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{1: 3, 2: 3, 999: 0}, result)
+}
+
 func TestActionRepository_GetAll(t *testing.T) {
 	filePath, cleanup := setupActionTestFile(t)
 	defer cleanup()
@@ -171,53 +185,50 @@ func TestActionRepository_GetAll(t *testing.T) {
 			ID:         1,
 			Type:       "LOGIN",
 			UserID:     1,
-			TargetUser: 0,
 			CreatedAt:  time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC),
 		},
 		{
 			ID:         2,
 			Type:       "VIEW_PROFILE",
 			UserID:     1,
-			TargetUser: 0,
 			CreatedAt:  time.Date(2024, 3, 11, 20, 1, 0, 0, time.UTC),
 		},
 		{
 			ID:         3,
 			Type:       "REFER_USER",
 			UserID:     1,
-			TargetUser: 2,
+			TargetUser: models.UserRef(2),
 			CreatedAt:  time.Date(2024, 3, 11, 20, 2, 0, 0, time.UTC),
 		},
 		{
 			ID:         4,
 			Type:       "REFER_USER",
 			UserID:     2,
-			TargetUser: 3,
+			TargetUser: models.UserRef(3),
 			CreatedAt:  time.Date(2024, 3, 11, 20, 3, 0, 0, time.UTC),
 		},
 		{
 			ID:         5,
 			Type:       "LOGIN",
 			UserID:     2,
-			TargetUser: 0,
 			CreatedAt:  time.Date(2024, 3, 11, 20, 4, 0, 0, time.UTC),
 		},
 		{
 			ID:         6,
 			Type:       "REFER_USER",
 			UserID:     2,
-			TargetUser: 0,
+			TargetUser: models.UserRef(0),
 			CreatedAt:  time.Date(2024, 3, 11, 20, 5, 0, 0, time.UTC),
 		},
 	}
 
 	t.Run("get all actions", func(t *testing.T) {
-		repo, err := NewActionRepository(filePath)
+		repo, err := NewActionRepository(filePath, actiontypes.Default())
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		result, err := repo.GetAll()
+		result, err := repo.GetAll(context.Background())
 		assert.NoError(t, err)
 		assert.Equal(t, expectedActions, result)
 	})
@@ -264,12 +275,12 @@ func TestActionRepository_GetNextActions(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo, err := NewActionRepository(filePath)
+			repo, err := NewActionRepository(filePath, actiontypes.Default())
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			result, total, err := repo.GetNextActions(tt.actionType)
+			result, total, err := repo.GetNextActions(context.Background(), tt.actionType)
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -292,13 +303,203 @@ func TestActionRepository_GetReferrals(t *testing.T) {
 	}
 
 	t.Run("get referrals", func(t *testing.T) {
-		repo, err := NewActionRepository(filePath)
+		repo, err := NewActionRepository(filePath, actiontypes.Default())
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		result, err := repo.GetReferrals()
+		result, err := repo.GetReferrals(context.Background())
 		assert.NoError(t, err)
 		assert.Equal(t, expectedReferrals, result)
 	})
 }
+
+func TestActionRepository_Add(t *testing.T) {
+	filePath, cleanup := setupActionTestFile(t)
+	defer cleanup()
+
+	t.Run("add assigns next ID", func(t *testing.T) {
+		repo, err := NewActionRepository(filePath, actiontypes.Default())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		action := models.Action{
+			Type:       "REFER_USER",
+			UserID:     3,
+			TargetUser: models.UserRef(4),
+			CreatedAt:  time.Date(2024, 3, 11, 20, 6, 0, 0, time.UTC),
+		}
+		stored, err := repo.Add(context.Background(), action)
+		assert.NoError(t, err)
+		assert.Equal(t, 7, stored.ID)
+
+		result, err := repo.GetByUserID(context.Background(), 3)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Action{stored}, result)
+	})
+
+	t.Run("add and reload bump version", func(t *testing.T) {
+		repo, err := NewActionRepository(filePath, actiontypes.Default())
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded := repo.Status(context.Background())
+		assert.Equal(t, uint64(1), loaded.Version)
+		assert.Equal(t, loaded.LoadedAt, loaded.ModifiedAt)
+
+		_, err = repo.Add(context.Background(), models.Action{Type: "WELCOME", UserID: 5, CreatedAt: time.Now()})
+		assert.NoError(t, err)
+		added := repo.Status(context.Background())
+		assert.Equal(t, uint64(2), added.Version)
+		assert.Equal(t, loaded.Records+1, added.Records)
+		assert.False(t, added.ModifiedAt.Before(*loaded.ModifiedAt))
+
+		assert.NoError(t, repo.Load(context.Background()))
+		assert.Equal(t, uint64(3), repo.Status(context.Background()).Version)
+	})
+}
+
+func TestActionRepository_DeleteByUserID(t *testing.T) {
+	filePath, cleanup := setupActionTestFile(t)
+	defer cleanup()
+
+	repo, err := NewActionRepository(filePath, actiontypes.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	t.Run("referrals are reattributed to a placeholder", func(t *testing.T) {
+		deleted, anonymised, err := repo.DeleteByUserID(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		assert.Equal(t, 3, anonymised)
+
+		actions, err := repo.GetByUserID(ctx, 2)
+		assert.NoError(t, err)
+		assert.Empty(t, actions)
+		referrals, err := repo.GetReferrals(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, map[int][]int{1: {-1}, -1: {3, 0}}, referrals)
+		assert.Equal(t, uint64(2), repo.Status(ctx).Version)
+	})
+
+	t.Run("each erasure gets its own placeholder", func(t *testing.T) {
+		deleted, anonymised, err := repo.DeleteByUserID(ctx, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, deleted)
+		assert.Equal(t, 1, anonymised)
+
+		referrals, err := repo.GetReferrals(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, map[int][]int{1: {-1}, -1: {-2, 0}}, referrals)
+	})
+
+	t.Run("user without actions", func(t *testing.T) {
+		deleted, anonymised, err := repo.DeleteByUserID(ctx, 9)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, deleted)
+		assert.Equal(t, 0, anonymised)
+		assert.Equal(t, uint64(3), repo.Status(ctx).Version)
+	})
+
+	t.Run("placeholder ID", func(t *testing.T) {
+		_, _, err := repo.DeleteByUserID(ctx, -1)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+}
+
+func TestActionRepository_ObservesActionTypes(t *testing.T) {
+	filePath, cleanup := setupActionTestFile(t)
+	defer cleanup()
+
+	types := actiontypes.Default()
+	_, err := NewActionRepository(filePath, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login, found := types.Lookup("LOGIN")
+	assert.True(t, found)
+	assert.Equal(t, actiontypes.UncategorizedCategory, login.Category)
+	assert.False(t, types.TargetsUser("LOGIN"))
+	assert.True(t, types.TargetsUser("REFER_USER"))
+}
+
+func TestActionRepository_Flush(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "actions.json")
+	if err := os.WriteFile(filePath, []byte(`[
+		{"id": 1, "type": "LOGIN", "userId": 1, "createdAt": "2024-03-11T20:00:00Z"}
+	]`), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := NewActionRepository(filePath, actiontypes.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("nothing to flush", func(t *testing.T) {
+		before, _ := os.ReadFile(filePath)
+		assert.NoError(t, repo.Flush(context.Background()))
+		after, _ := os.ReadFile(filePath)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("added actions are persisted", func(t *testing.T) {
+		stored, err := repo.Add(context.Background(), models.Action{
+			Type:       "REFER_USER",
+			UserID:     1,
+			TargetUser: models.UserRef(2),
+			CreatedAt:  time.Date(2024, 3, 11, 20, 1, 0, 0, time.UTC),
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Flush(context.Background()))
+
+		reloaded, err := NewActionRepository(filePath, actiontypes.Default())
+		assert.NoError(t, err)
+		result, err := reloaded.GetAll(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Action{
+			{ID: 1, Type: "LOGIN", UserID: 1, CreatedAt: time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)},
+			stored,
+		}, result)
+
+		info, err := os.Stat(filePath)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	})
+}
+
+func TestActionRepository_Canceled(t *testing.T) {
+	filePath, cleanup := setupActionTestFile(t)
+	defer cleanup()
+
+	repo, err := NewActionRepository(filePath, actiontypes.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name  string
+		query func() error
+	}{
+		{"GetByUserID", func() error { _, err := repo.GetByUserID(ctx, 1); return err }},
+		{"CountByUserIDs", func() error { _, err := repo.CountByUserIDs(ctx, []int{1}); return err }},
+		{"GetAll", func() error { _, err := repo.GetAll(ctx); return err }},
+		{"GetNextActions", func() error { _, _, err := repo.GetNextActions(ctx, "LOGIN"); return err }},
+		{"GetReferrals", func() error { _, err := repo.GetReferrals(ctx); return err }},
+		{"DeleteByUserID", func() error { _, _, err := repo.DeleteByUserID(ctx, 1); return err }},
+		{"Load", func() error { return repo.Load(ctx) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.query(), context.Canceled)
+		})
+	}
+}
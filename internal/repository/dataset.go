@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"surfe/internal/apperrors"
+	"surfe/internal/logging"
+	"surfe/internal/models"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordsScannedKey annotates query spans with how many records the query
+// examined, which is what its cost grows with.
+const recordsScannedKey = attribute.Key("surfe.records_scanned")
+
+func recordScanned(ctx context.Context, records int) {
+	trace.SpanFromContext(ctx).SetAttributes(recordsScannedKey.Int(records))
+}
+
+// datasetState tracks whether a repository's data has been loaded and how
+// often it has changed since. Callers hold the repository lock around every
+// method.
+type datasetState struct {
+	name       string
+	source     string
+	loaded     bool
+	loadedAt   time.Time
+	modifiedAt time.Time
+	version    uint64
+	checksum   string
+	records    int
+	loadErr    error
+}
+
+func (d *datasetState) markLoaded(ctx context.Context, checksum string, records int) {
+	d.loaded = true
+	d.loadedAt = time.Now().UTC()
+	d.modifiedAt = d.loadedAt
+	d.version++
+	d.checksum = checksum
+	d.records = records
+	d.loadErr = nil
+
+	logging.FromContext(ctx).Info("dataset loaded",
+		slog.String("dataset", d.name), slog.String("source", d.source),
+		slog.Int("records", records), slog.String("checksum", checksum))
+}
+
+// markModified records a change to the loaded data, which now holds records
+// records.
+func (d *datasetState) markModified(records int) {
+	d.modifiedAt = time.Now().UTC()
+	d.version++
+	d.records = records
+}
+
+func (d *datasetState) checkLoaded() error {
+	if !d.loaded {
+		return fmt.Errorf("%w: %s dataset not loaded", apperrors.ErrUnavailable, d.name)
+	}
+	return nil
+}
+
+func (d *datasetState) status() models.DatasetStatus {
+	status := models.DatasetStatus{
+		Name:     d.name,
+		Source:   d.source,
+		Loaded:   d.loaded,
+		Records:  d.records,
+		Checksum: d.checksum,
+		Version:  d.version,
+	}
+	if d.loaded {
+		loadedAt, modifiedAt := d.loadedAt, d.modifiedAt
+		status.LoadedAt = &loadedAt
+		status.ModifiedAt = &modifiedAt
+	}
+	if d.loadErr != nil {
+		status.Error = d.loadErr.Error()
+	}
+	return status
+}
+
+// LoadWhenAvailable retries loading every dataset that is not loaded yet,
+// once per interval, until all of them are loaded or ctx is done.
+func LoadWhenAvailable(ctx context.Context, interval time.Duration, datasets ...Dataset) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pending := false
+		for _, dataset := range datasets {
+			if dataset.Status(ctx).Loaded {
+				continue
+			}
+			if err := dataset.Load(ctx); err != nil {
+				logging.FromContext(ctx).Debug("dataset not loaded, retrying",
+					slog.Duration("interval", interval), slog.Any("error", err))
+				pending = true
+			}
+		}
+		if !pending {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"surfe/internal/actiontypes"
+	"surfe/internal/apperrors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenUserRepository_LoadsWhenFileArrives(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "users.json")
+	repo := OpenUserRepository(filePath)
+
+	_, err := repo.GetAll(context.Background())
+	assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+	assert.Error(t, repo.Load(context.Background()))
+
+	status := repo.Status(context.Background())
+	assert.Equal(t, "users", status.Name)
+	assert.Equal(t, filePath, status.Source)
+	assert.False(t, status.Loaded)
+	assert.Nil(t, status.LoadedAt)
+	assert.NotEmpty(t, status.Error)
+
+	if err := os.WriteFile(filePath, []byte(`[{"id": 1, "name": "John Doe", "createdAt": "2024-03-11T20:00:00Z"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, repo.Load(context.Background()))
+
+	status = repo.Status(context.Background())
+	assert.True(t, status.Loaded)
+	assert.NotNil(t, status.LoadedAt)
+	assert.Equal(t, 1, status.Records)
+	assert.Equal(t, "sha256:249d37bb3b9e962583b2f07250ed1ab23c82a7615a98678e083203a2eebe0ea5", status.Checksum)
+	assert.Empty(t, status.Error)
+
+	users, err := repo.GetAll(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+}
+
+func TestLoadWhenAvailable(t *testing.T) {
+	dir := t.TempDir()
+	usersPath := filepath.Join(dir, "users.json")
+	actionsPath := filepath.Join(dir, "actions.json")
+	if err := os.WriteFile(usersPath, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := OpenUserRepository(usersPath)
+	actionRepo := OpenActionRepository(actionsPath, actiontypes.Default())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		LoadWhenAvailable(ctx, 10*time.Millisecond, userRepo, actionRepo)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return userRepo.Status(context.Background()).Loaded }, time.Second, 5*time.Millisecond)
+	assert.False(t, actionRepo.Status(context.Background()).Loaded)
+
+	if err := os.WriteFile(actionsPath, []byte(`[{"id": 1, "type": "WELCOME", "userId": 1, "createdAt": "2024-03-11T20:00:00Z"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("LoadWhenAvailable did not return after all datasets were loaded")
+	}
+	assert.True(t, actionRepo.Status(context.Background()).Loaded)
+	assert.Equal(t, 1, actionRepo.Status(context.Background()).Records)
+}
+
+func TestLoadWhenAvailable_StopsOnCancel(t *testing.T) {
+	repo := OpenUserRepository(filepath.Join(t.TempDir(), "users.json"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Returns immediately even though the dataset never becomes available.
+	LoadWhenAvailable(ctx, time.Hour, repo)
+	assert.False(t, repo.Status(context.Background()).Loaded)
+}
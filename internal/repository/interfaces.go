@@ -1,15 +1,48 @@
 package repository
 
-import "surfe/internal/models"
+import (
+	"context"
+	"surfe/internal/models"
+)
+
+// Dataset is the lifecycle of a repository's backing data.
+type Dataset interface {
+	// Load reads the data from its source, replacing anything loaded before.
+	Load(ctx context.Context) error
+	// Status reports whether the data is loaded and describes its source.
+	// Its Version increases every time the data is loaded or changed.
+	Status(ctx context.Context) models.DatasetStatus
+}
 
 type ActionRepository interface {
-	GetByUserID(userID int) ([]models.Action, error)
-	GetAll() ([]models.Action, error)
-	GetNextActions(actionType string) (map[string]int, int, error)
-	GetReferrals() (map[int][]int, error)
+	Dataset
+	GetByUserID(ctx context.Context, userID int) ([]models.Action, error)
+	// CountByUserIDs counts the actions of each of the given users in a
+	// single pass. Every requested user has an entry, zero if they have no
+	// actions.
+	CountByUserIDs(ctx context.Context, userIDs []int) (map[int]int, error)
+	GetAll(ctx context.Context) ([]models.Action, error)
+	GetNextActions(ctx context.Context, actionType string) (map[string]int, int, error)
+	GetReferrals(ctx context.Context) (map[int][]int, error)
+	Add(ctx context.Context, action models.Action) (models.Action, error)
+	// DeleteByUserID deletes a user's actions, except that their place in
+	// the referral graph is taken by a new anonymous placeholder: referrals
+	// made by or to the user are kept and reattributed to it. It returns
+	// how many actions were deleted and how many were reattributed.
+	DeleteByUserID(ctx context.Context, userID int) (deleted, anonymised int, err error)
+	// Flush persists any pending changes.
+	Flush(ctx context.Context) error
 }
 
 type UserRepository interface {
-	GetByID(id int) (*models.User, error)
-	GetAll() ([]models.User, error)
+	Dataset
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	// GetByIDs returns the users with the given IDs in a single pass, keyed
+	// by ID. IDs without a user, including negative ones, are left out.
+	GetByIDs(ctx context.Context, ids []int) (map[int]models.User, error)
+	GetAll(ctx context.Context) ([]models.User, error)
+	// Delete removes a user, or returns apperrors.ErrNotFound.
+	Delete(ctx context.Context, id int) error
+	// Flush persists any pending changes.
+	Flush(ctx context.Context) error
 }
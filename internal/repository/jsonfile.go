@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"surfe/internal/apperrors"
+)
+
+// readJSONFile decodes the JSON document in filePath into v and returns the
+// SHA-256 checksum of the file contents.
+func readJSONFile(filePath string, v interface{}) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", apperrors.ErrUnavailable, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if err := json.NewDecoder(io.TeeReader(file, hash)).Decode(v); err != nil {
+		return "", fmt.Errorf("%w: invalid JSON data in %s: %v", apperrors.ErrUnavailable, filePath, err)
+	}
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("%w: %v", apperrors.ErrUnavailable, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeJSONFile replaces filePath with the JSON encoding of v. The data is
+// written to a temporary file in the same directory first and renamed into
+// place, so readers never observe a partially written file.
+func writeJSONFile(filePath string, v interface{}) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrUnavailable, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	encoder := json.NewEncoder(tmp)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(v); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%w: %v", apperrors.ErrUnavailable, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrUnavailable, err)
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrUnavailable, err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrUnavailable, err)
+	}
+	return nil
+}
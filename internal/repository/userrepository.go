@@ -1,52 +1,167 @@
 package repository
 
 import (
-	"encoding/json"
-	"errors"
-	"os"
+	"context"
+	"fmt"
+	"log/slog"
+	"surfe/internal/apperrors"
+	"surfe/internal/ctxcheck"
+	"surfe/internal/logging"
 	"surfe/internal/models"
+	"sync"
 )
 
 type userRepository struct {
-	users []models.User
+	mu      sync.RWMutex
+	dataset datasetState
+	users   []models.User
+	dirty   bool
 }
 
+// NewUserRepository loads users from a JSON file and fails if the file
+// cannot be read.
 func NewUserRepository(filePath string) (UserRepository, error) {
-	repo := &userRepository{}
-	if err := repo.loadData(filePath); err != nil {
+	repo := OpenUserRepository(filePath)
+	if err := repo.Load(context.Background()); err != nil {
 		return nil, err
 	}
 	return repo, nil
 }
 
-func (r *userRepository) loadData(filePath string) error {
-	file, err := os.Open(filePath)
-	if err != nil {
+// OpenUserRepository returns a repository backed by a JSON file without
+// loading it. Queries fail with apperrors.ErrUnavailable until Load succeeds.
+func OpenUserRepository(filePath string) UserRepository {
+	return &userRepository{
+		dataset: datasetState{name: "users", source: filePath},
+	}
+}
+
+func (r *userRepository) Load(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	defer file.Close()
 
-	if err := json.NewDecoder(file).Decode(&r.users); err != nil {
-		return errors.New("invalid JSON data")
+	var users []models.User
+	checksum, err := readJSONFile(r.dataset.source, &users)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.dataset.loadErr = err
+		return err
 	}
+	r.users = users
+	r.dirty = false
+	r.dataset.markLoaded(ctx, checksum, len(users))
 	return nil
 }
 
-func (r *userRepository) GetByID(id int) (*models.User, error) {
-	if id <= 0 {
-		return nil, errors.New("invalid user ID")
+func (r *userRepository) Status(ctx context.Context) models.DatasetStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.dataset.status()
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return nil, err
 	}
+	if id < 0 {
+		return nil, fmt.Errorf("%w: user ID %d", apperrors.ErrInvalidArgument, id)
+	}
+	recordScanned(ctx, len(r.users))
 
-	for _, user := range r.users {
+	for i, user := range r.users {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return nil, err
+		}
 		if user.ID == id {
 			return &user, nil
 		}
 	}
-	return nil, errors.New("user not found")
+	return nil, fmt.Errorf("user %d: %w", id, apperrors.ErrNotFound)
 }
 
-func (r *userRepository) GetAll() ([]models.User, error) {
+func (r *userRepository) GetByIDs(ctx context.Context, ids []int) (map[int]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return nil, err
+	}
+	wanted := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	recordScanned(ctx, len(r.users))
+
+	users := make(map[int]models.User, len(ids))
+	for i, user := range r.users {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		if wanted[user.ID] {
+			users[user.ID] = user
+		}
+	}
+	return users, nil
+}
+
+func (r *userRepository) GetAll(ctx context.Context) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	recordScanned(ctx, len(r.users))
+
 	users := make([]models.User, len(r.users))
 	copy(users, r.users)
 	return users, nil
 }
+
+func (r *userRepository) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.dataset.checkLoaded(); err != nil {
+		return err
+	}
+	for i, user := range r.users {
+		if user.ID == id {
+			r.users = append(r.users[:i:i], r.users[i+1:]...)
+			r.dirty = true
+			r.dataset.markModified(len(r.users))
+			return nil
+		}
+	}
+	return fmt.Errorf("user %d: %w", id, apperrors.ErrNotFound)
+}
+
+func (r *userRepository) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := writeJSONFile(r.dataset.source, r.users); err != nil {
+		return err
+	}
+	r.dirty = false
+	logging.FromContext(ctx).Info("dataset flushed",
+		slog.String("dataset", r.dataset.name), slog.Int("records", len(r.users)))
+	return nil
+}
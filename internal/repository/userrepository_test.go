@@ -1,11 +1,14 @@
 package repository
 
 import (
+	"context"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"surfe/internal/apperrors"
 	"surfe/internal/models"
 
 	"github.com/stretchr/testify/assert"
@@ -18,6 +21,7 @@ func setupUserTestFile(t *testing.T) (string, func()) {
 	}
 
 	if err := os.WriteFile(tmpFile.Name(), []byte(`[
+		{"id": 0, "name": "Allyson", "createdAt": "2024-03-11T20:00:00Z"},
 		{"id": 1, "name": "John Doe", "createdAt": "2024-03-11T20:00:00Z"},
 		{"id": 2, "name": "Jane Smith", "createdAt": "2024-03-11T20:00:00Z"}
 	]`), 0644); err != nil {
@@ -62,7 +66,7 @@ func TestUserRepository_GetByID(t *testing.T) {
 		name          string
 		userID        int
 		expected      *models.User
-		expectedError bool
+		expectedError error
 	}{
 		{
 			name:   "user found",
@@ -72,13 +76,29 @@ func TestUserRepository_GetByID(t *testing.T) {
 				Name:      "John Doe",
 				CreatedAt: time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC),
 			},
-			expectedError: false,
+			expectedError: nil,
+		},
+		{
+			name:   "user zero found",
+			userID: 0,
+			expected: &models.User{
+				ID:        0,
+				Name:      "Allyson",
+				CreatedAt: time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC),
+			},
+			expectedError: nil,
 		},
 		{
 			name:          "user not found",
 			userID:        999,
 			expected:      nil,
-			expectedError: false,
+			expectedError: apperrors.ErrNotFound,
+		},
+		{
+			name:          "negative user ID",
+			userID:        -1,
+			expected:      nil,
+			expectedError: apperrors.ErrInvalidArgument,
 		},
 	}
 
@@ -89,10 +109,64 @@ func TestUserRepository_GetByID(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			result, err := repo.GetByID(tt.userID)
+			result, err := repo.GetByID(context.Background(), tt.userID)
 
-			if tt.expectedError {
-				assert.Error(t, err)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestUserRepository_GetByIDs(t *testing.T) {
+	filePath, cleanup := setupUserTestFile(t)
+	defer cleanup()
+
+	createdAt := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		ids           []int
+		expected      map[int]models.User
+		expectedError error
+	}{
+		{
+			name: "some users found",
+			ids:  []int{2, 999, 0},
+			expected: map[int]models.User{
+				0: {ID: 0, Name: "Allyson", CreatedAt: createdAt},
+				2: {ID: 2, Name: "Jane Smith", CreatedAt: createdAt},
+			},
+		},
+		{
+			name:     "no users found",
+			ids:      []int{999},
+			expected: map[int]models.User{},
+		},
+		{
+			name: "negative user ID",
+			ids:  []int{1, -1},
+			expected: map[int]models.User{
+				1: {ID: 1, Name: "John Doe", CreatedAt: createdAt},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := NewUserRepository(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := repo.GetByIDs(context.Background(), tt.ids)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, result)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expected, result)
@@ -106,6 +180,11 @@ func TestUserRepository_GetAll(t *testing.T) {
 	defer cleanup()
 
 	expectedUsers := []models.User{
+		{
+			ID:        0,
+			Name:      "Allyson",
+			CreatedAt: time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC),
+		},
 		{
 			ID:        1,
 			Name:      "John Doe",
@@ -124,8 +203,76 @@ func TestUserRepository_GetAll(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		result, err := repo.GetAll()
+		result, err := repo.GetAll(context.Background())
 		assert.NoError(t, err)
 		assert.Equal(t, expectedUsers, result)
 	})
 }
+
+func TestNewUserRepository_Unavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents *string
+	}{
+		{
+			name:     "missing file",
+			contents: nil,
+		},
+		{
+			name:     "invalid JSON",
+			contents: func() *string { s := "{not json"; return &s }(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "users.json")
+			if tt.contents != nil {
+				if err := os.WriteFile(filePath, []byte(*tt.contents), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			repo, err := NewUserRepository(filePath)
+			assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+			assert.Nil(t, repo)
+		})
+	}
+}
+
+func TestUserRepository_Delete(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(filePath, []byte(`[
+		{"id": 1, "name": "John Doe", "createdAt": "2024-03-11T20:00:00Z"},
+		{"id": 2, "name": "Jane Smith", "createdAt": "2024-03-11T20:00:00Z"}
+	]`), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := NewUserRepository(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	t.Run("deleted user is persisted on flush", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(ctx, 1))
+		_, err := repo.GetByID(ctx, 1)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, repo.Flush(ctx))
+
+		reloaded, err := NewUserRepository(filePath)
+		assert.NoError(t, err)
+		result, err := reloaded.GetAll(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.User{
+			{ID: 2, Name: "Jane Smith", CreatedAt: time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)},
+		}, result)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		err := repo.Delete(ctx, 1)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.EqualError(t, err, "user 1: not found")
+	})
+}
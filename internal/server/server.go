@@ -0,0 +1,345 @@
+// Package server assembles the API from its configuration: the repositories
+// and services, their tracing, metrics and caching decorators, the HTTP
+// middleware and the routes, for the server's own dataset and for each
+// workspace.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"surfe/internal/actiontypes"
+	"surfe/internal/audit"
+	"surfe/internal/cache"
+	"surfe/internal/config"
+	"surfe/internal/handlers"
+	"surfe/internal/logging"
+	"surfe/internal/metrics"
+	"surfe/internal/openapi"
+	"surfe/internal/repository"
+	"surfe/internal/services"
+	"surfe/internal/tracing"
+	"surfe/internal/workspace"
+	"sync"
+
+	_ "surfe/docs" // This will be generated
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	echoSwagger "github.com/swaggo/echo-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Server is an assembled API together with the repositories it serves.
+type Server struct {
+	Echo    *echo.Echo
+	Users   repository.UserRepository
+	Actions repository.ActionRepository
+	// Workspaces holds the workspaces served under /api/v1/workspaces/:ws
+	// and /api/v2/workspaces/:ws; it is nil unless workspaces are
+	// configured.
+	Workspaces *workspace.Registry
+
+	cfg      *config.Config
+	audit    *audit.Log
+	tracer   *tracing.Tracer
+	metrics  *metrics.Metrics
+	provider *sdktrace.TracerProvider
+}
+
+// New builds the server described by cfg and loads its data. The data keeps
+// being retried in the background until ctx is done. Close must be called
+// once the server has stopped.
+func New(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*Server, error) {
+	s := &Server{
+		Echo: echo.New(),
+		cfg:  cfg,
+	}
+	e := s.Echo
+
+	// The tracing middleware goes first so the request span covers every
+	// other middleware.
+	if cfg.Tracing.Exporter != config.TracingNone {
+		provider, err := tracing.NewProvider(ctx, cfg.Tracing)
+		if err != nil {
+			return nil, err
+		}
+		s.provider = provider
+
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+		e.Use(otelecho.Middleware(tracing.ServiceName, otelecho.WithTracerProvider(provider)))
+		s.tracer = tracing.New(provider)
+	}
+
+	e.Use(logging.Middleware(logger))
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			logging.FromContext(c.Request().Context()).Error("panic recovered",
+				slog.Any("error", err), slog.String("stack", string(stack)))
+			return err
+		},
+	}))
+
+	e.HideBanner = true
+	e.HidePort = true
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout
+
+	// Swagger documentation endpoint
+	if cfg.Features.Swagger {
+		e.GET("/swagger/*", echoSwagger.WrapHandler)
+	}
+
+	if cfg.Features.Metrics {
+		s.metrics = metrics.New()
+		e.Use(s.metrics.Middleware())
+		e.GET("/metrics", echo.WrapHandler(s.metrics.Handler()))
+	}
+
+	if cfg.Audit.LogPath != "" {
+		s.audit = audit.NewLog(cfg.Audit.LogPath)
+	}
+
+	actionTypes, err := loadActionTypes(cfg.Data.ActionTypesPath)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to load action types: %v", err)
+	}
+
+	// Missing or invalid data files do not stop the server: it reports not
+	// ready on /readyz and keeps retrying until the data arrives.
+	d := s.dataset(actionTypes, cfg.Data.UsersPath, cfg.Data.ActionsPath, cfg.Cache.Size, "")
+	if d.cache != nil && s.metrics != nil {
+		s.metrics.Cache(d.cache)
+	}
+	s.Users, s.Actions = d.users, d.actions
+	for _, dataset := range []repository.Dataset{d.users, d.actions} {
+		if err := dataset.Load(ctx); err != nil {
+			logger.Warn("dataset not ready", slog.Any("error", err))
+		}
+	}
+	go repository.LoadWhenAvailable(ctx, cfg.Data.ReloadInterval, d.users, d.actions)
+
+	healthHandler := handlers.NewHealthHandler(services.NewHealthService(d.users, d.actions))
+	e.GET("/healthz", healthHandler.Liveness)
+	e.GET("/readyz", healthHandler.Readiness)
+
+	api := e.Group("/api")
+	if cfg.Features.RequestValidation {
+		spec, err := openapi.Default()
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to load the OpenAPI description: %v", err)
+		}
+		api.Use(openapi.ValidateRequests(spec))
+	}
+	// GET responses are tagged with the data version so clients can poll
+	// with conditional requests.
+	s.routes(api.Group("/v1", handlers.Conditional(d.version)), d, cfg.Features.ActionRecording)
+	s.routesV2(api.Group("/v2", handlers.Conditional(d.version)), d, cfg.Features.ActionRecording)
+
+	if cfg.Workspaces.Dir != "" {
+		s.Workspaces = workspace.NewRegistry(workspace.Options{
+			Dir:         cfg.Workspaces.Dir,
+			MaxLoaded:   cfg.Workspaces.MaxLoaded,
+			IdleTimeout: cfg.Workspaces.IdleTimeout,
+			Defaults:    workspace.DefaultConfig(cfg),
+		}, s.openWorkspace)
+		go s.Workspaces.EvictIdle(ctx)
+		if s.metrics != nil {
+			s.metrics.Workspaces(s.Workspaces)
+		}
+		workspaceHandler := handlers.NewWorkspaceHandler(s.Workspaces)
+		api.Any("/v1/workspaces/:ws/*", workspaceHandler.Serve)
+		api.Any("/v2/workspaces/:ws/*", workspaceHandler.Serve)
+	}
+
+	return s, nil
+}
+
+// dataset holds the repositories of one dataset and the handlers serving
+// it.
+type dataset struct {
+	users    repository.UserRepository
+	actions  repository.ActionRepository
+	cache    *cache.Cache
+	version  services.VersionService
+	user     *handlers.UserHandler
+	action   *handlers.ActionHandler
+	referral *handlers.ReferralHandler
+	// erasure is nil when user erasure is disabled.
+	erasure *handlers.ErasureHandler
+}
+
+// dataset opens the repositories of the given files, without loading them,
+// and assembles the services and handlers serving them. A cacheSize of 0
+// disables caching. workspaceID is empty for the server's own dataset, the
+// only one with repository gauges and cache metrics.
+func (s *Server) dataset(actionTypes *actiontypes.Registry, usersPath, actionsPath string, cacheSize int, workspaceID string) *dataset {
+	userRepo := repository.OpenUserRepository(usersPath)
+	actionsRepo := repository.OpenActionRepository(actionsPath, actionTypes)
+	if s.tracer != nil {
+		userRepo = s.tracer.UserRepository(userRepo)
+		actionsRepo = s.tracer.ActionRepository(actionsRepo)
+	}
+	switch {
+	case s.metrics == nil:
+	case workspaceID == "":
+		userRepo = s.metrics.UserRepository(userRepo)
+		actionsRepo = s.metrics.ActionRepository(actionsRepo)
+	default:
+		userRepo = s.metrics.WorkspaceUserRepository(userRepo)
+		actionsRepo = s.metrics.WorkspaceActionRepository(actionsRepo)
+	}
+	d := &dataset{
+		users:   userRepo,
+		actions: actionsRepo,
+		version: services.NewVersionService(userRepo, actionsRepo),
+	}
+
+	// Recording actions and erasing users take turns on the dataset's users.
+	usersMu := new(sync.RWMutex)
+	userService := services.NewUserService(userRepo, actionsRepo)
+	actionsService := services.NewActionService(userRepo, actionsRepo, actionTypes, usersMu)
+	referralService := services.NewReferralService(userRepo, actionsRepo, actionTypes)
+	// The cache sits under the tracing and metrics decorators, so their
+	// spans and timings show what callers see on hits as well as misses.
+	if cacheSize > 0 {
+		d.cache = cache.New(services.NewVersionService(actionsRepo), cacheSize)
+		actionsService = d.cache.ActionService(actionsService)
+	}
+	if s.tracer != nil {
+		userService = s.tracer.UserService(userService)
+		actionsService = s.tracer.ActionService(actionsService)
+		referralService = s.tracer.ReferralService(referralService)
+	}
+	if s.metrics != nil {
+		actionsService = s.metrics.ActionService(actionsService)
+	}
+
+	d.user = handlers.NewUserHandler(userService)
+	d.action = handlers.NewActionHandler(actionsService)
+	d.referral = handlers.NewReferralHandler(referralService)
+
+	// Every dataset records its erasures in the server's audit trail,
+	// workspaces under their own ID.
+	if s.audit != nil {
+		var trail services.AuditTrail = s.audit
+		if workspaceID != "" {
+			trail = s.audit.Workspace(workspaceID)
+		}
+		erasureService := services.NewErasureService(userRepo, actionsRepo, trail, usersMu)
+		if s.tracer != nil {
+			erasureService = s.tracer.ErasureService(erasureService)
+		}
+		d.erasure = handlers.NewErasureHandler(erasureService)
+	}
+	return d
+}
+
+// routes registers the API routes serving d on g.
+func (s *Server) routes(g *echo.Group, d *dataset, actionRecording bool) {
+	// Lookups get the request timeout; routes that scan every action get
+	// the longer report timeout.
+	lookup := handlers.Timeout(s.cfg.Server.RequestTimeout)
+	report := handlers.Timeout(s.cfg.Server.ReportTimeout)
+
+	g.GET("/users/:id", d.user.GetUserByID, lookup)
+	g.GET("/users/:id/actions/count", d.user.GetUserActionCount, lookup)
+	g.GET("/users/:id/actions/stats", d.user.GetUserActionStats, lookup)
+	g.POST("/users/batch", d.user.GetUsers, report)
+	if d.erasure != nil {
+		g.DELETE("/users/:id", d.erasure.EraseUser, report)
+	}
+	g.GET("/action-types", d.action.GetActionTypes, lookup)
+	g.GET("/action-types/:type", d.action.GetActionType, lookup)
+	g.GET("/actions/:type/next", d.action.GetNextActionProbabilities, report)
+	g.GET("/actions/referral", d.action.GetReferralIndex, report)
+	if actionRecording {
+		g.POST("/actions", d.action.RecordAction, lookup)
+	}
+	g.GET("/referrals/quality", d.action.GetReferralQuality, report)
+	g.GET("/referrals/stats", d.referral.GetReferralStats, report)
+	g.GET("/referrals/graph", d.referral.GetReferralGraph, report)
+}
+
+// routesV2 registers the /api/v2 routes serving d on g. They mirror the v1
+// routes with enveloped responses, except that the referral graph is only
+// available as JSON.
+func (s *Server) routesV2(g *echo.Group, d *dataset, actionRecording bool) {
+	lookup := handlers.Timeout(s.cfg.Server.RequestTimeout)
+	report := handlers.Timeout(s.cfg.Server.ReportTimeout)
+
+	g.GET("/users/:id", d.user.GetUserByIDV2, lookup)
+	g.GET("/users/:id/actions/count", d.user.GetUserActionCountV2, lookup)
+	g.GET("/users/:id/actions/stats", d.user.GetUserActionStatsV2, lookup)
+	g.POST("/users/batch", d.user.GetUsersV2, report)
+	if d.erasure != nil {
+		g.DELETE("/users/:id", d.erasure.EraseUserV2, report)
+	}
+	g.GET("/action-types", d.action.GetActionTypesV2, lookup)
+	g.GET("/action-types/:type", d.action.GetActionTypeV2, lookup)
+	g.GET("/actions/:type/next", d.action.GetNextActionsV2, report)
+	g.GET("/actions/referral", d.action.GetReferralIndexV2, report)
+	if actionRecording {
+		g.POST("/actions", d.action.RecordActionV2, lookup)
+	}
+	g.GET("/referrals/quality", d.action.GetReferralQualityV2, report)
+	g.GET("/referrals/stats", d.referral.GetReferralStatsV2, report)
+	g.GET("/referrals/graph", d.referral.GetReferralGraphV2, report)
+}
+
+// openWorkspace builds a workspace's dataset and loads it. Unlike the
+// server's own dataset, a workspace whose data cannot be loaded is not
+// kept; the next request for it tries again.
+func (s *Server) openWorkspace(ctx context.Context, id string, cfg *workspace.Config) (*workspace.Workspace, error) {
+	actionTypes, err := loadActionTypes(cfg.Data.ActionTypesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load action types: %v", err)
+	}
+	d := s.dataset(actionTypes, cfg.Data.UsersPath, cfg.Data.ActionsPath, cfg.Cache.Size, id)
+	for _, dataset := range []repository.Dataset{d.users, d.actions} {
+		if err := dataset.Load(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// The workspace gets its own router, behind the server's middleware,
+	// with the same routes as the server's dataset.
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	e.Use(handlers.WorkspaceRoute())
+	s.routes(e.Group("/api/v1/workspaces/:ws", handlers.Conditional(d.version)), d, cfg.Features.ActionRecording)
+	s.routesV2(e.Group("/api/v2/workspaces/:ws", handlers.Conditional(d.version)), d, cfg.Features.ActionRecording)
+
+	return &workspace.Workspace{Users: d.users, Actions: d.actions, Handler: e}, nil
+}
+
+// Close flushes the spans still buffered by the tracer, if tracing is
+// enabled.
+func (s *Server) Close() {
+	if s.provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.provider.Shutdown(ctx); err != nil {
+		slog.Error("failed to flush traces", slog.Any("error", err))
+	}
+}
+
+// loadActionTypes reads the action type registry from filePath, or returns
+// the built-in types when no path is configured.
+func loadActionTypes(filePath string) (*actiontypes.Registry, error) {
+	if filePath == "" {
+		return actiontypes.Default(), nil
+	}
+	return actiontypes.Load(filePath)
+}
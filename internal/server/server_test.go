@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"surfe/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDataset writes a dataset with a single user named name to dir.
+func writeDataset(t *testing.T, dir, name string) {
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	users := `[{"id": 1, "name": "` + name + `", "createdAt": "2024-01-01T00:00:00Z"}]`
+	actions := `[{"id": 1, "type": "WELCOME", "userId": 1, "createdAt": "2024-01-01T00:00:00Z"}]`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users.json"), []byte(users), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "actions.json"), []byte(actions), 0644))
+}
+
+// newTestServer starts a server without Swagger, which is closed when the
+// test ends.
+func newTestServer(t *testing.T, cfg *config.Config) *Server {
+	cfg.Features.Swagger = false
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	srv, err := New(ctx, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNew_V2(t *testing.T) {
+	dir := t.TempDir()
+	writeDataset(t, dir, "Ada")
+	writeDataset(t, filepath.Join(dir, "workspaces", "acme"), "Grace")
+
+	cfg := config.Default()
+	cfg.Data.UsersPath = filepath.Join(dir, "users.json")
+	cfg.Data.ActionsPath = filepath.Join(dir, "actions.json")
+	cfg.Workspaces.Dir = filepath.Join(dir, "workspaces")
+	srv := newTestServer(t, cfg)
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedBody string
+	}{
+		{
+			name:         "v1 unchanged",
+			path:         "/api/v1/users/1",
+			expectedBody: `{"id":1,"name":"Ada","createdAt":"2024-01-01T00:00:00Z"}`,
+		},
+		{
+			name: "v2 envelope",
+			path: "/api/v2/users/1",
+			expectedBody: `{"data":{"id":1,"name":"Ada","createdAt":"2024-01-01T00:00:00Z"},` +
+				`"meta":{"dataVersion":$etag},"links":{"self":"/api/v2/users/1"}}`,
+		},
+		{
+			name: "v2 workspace",
+			path: "/api/v2/workspaces/acme/users/1",
+			expectedBody: `{"data":{"id":1,"name":"Grace","createdAt":"2024-01-01T00:00:00Z"},` +
+				`"meta":{"dataVersion":$etag},"links":{"self":"/api/v2/workspaces/acme/users/1"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			srv.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			// The body carries the same data version as the ETag.
+			etag := rec.Header().Get("ETag")
+			require.NotEmpty(t, etag)
+			assert.JSONEq(t, strings.ReplaceAll(tt.expectedBody, "$etag", etag), rec.Body.String())
+		})
+	}
+}
+
+func TestNew_Workspaces(t *testing.T) {
+	dir := t.TempDir()
+	writeDataset(t, dir, "Ada")
+	writeDataset(t, filepath.Join(dir, "workspaces", "acme"), "Grace")
+	writeDataset(t, filepath.Join(dir, "workspaces", "globex"), "Linus")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "workspaces", "globex", "workspace.yaml"),
+		[]byte("features:\n  actionRecording: false\n"), 0644))
+
+	cfg := config.Default()
+	cfg.Data.UsersPath = filepath.Join(dir, "users.json")
+	cfg.Data.ActionsPath = filepath.Join(dir, "actions.json")
+	cfg.Workspaces.Dir = filepath.Join(dir, "workspaces")
+	srv := newTestServer(t, cfg)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "server dataset",
+			method:         http.MethodGet,
+			path:           "/api/v1/users/1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"name":"Ada"`,
+		},
+		{
+			name:           "workspace dataset",
+			method:         http.MethodGet,
+			path:           "/api/v1/workspaces/acme/users/1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"name":"Grace"`,
+		},
+		{
+			name:           "another workspace",
+			method:         http.MethodGet,
+			path:           "/api/v1/workspaces/globex/users/1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"name":"Linus"`,
+		},
+		{
+			name:           "unknown workspace",
+			method:         http.MethodGet,
+			path:           "/api/v1/workspaces/hooli/users/1",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `workspace \"hooli\"`,
+		},
+		{
+			name:           "action recording disabled by the workspace",
+			method:         http.MethodPost,
+			path:           "/api/v1/workspaces/globex/actions",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "workspace metrics",
+			method:         http.MethodGet,
+			path:           "/metrics",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `surfe_http_requests_total{method="GET",route="/api/v1/workspaces/:ws/users/:id",status="200"} 2`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			srv.Echo.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.expectedBody)
+		})
+	}
+	assert.Equal(t, []string{"globex", "acme"}, srv.Workspaces.Loaded())
+}
+
+func TestNew_Erasure(t *testing.T) {
+	dir := t.TempDir()
+	writeDataset(t, dir, "Ada")
+	writeDataset(t, filepath.Join(dir, "workspaces", "acme"), "Grace")
+
+	cfg := config.Default()
+	cfg.Data.UsersPath = filepath.Join(dir, "users.json")
+	cfg.Data.ActionsPath = filepath.Join(dir, "actions.json")
+	cfg.Workspaces.Dir = filepath.Join(dir, "workspaces")
+	cfg.Audit.LogPath = filepath.Join(dir, "audit.jsonl")
+	srv := newTestServer(t, cfg)
+
+	for _, path := range []string{"/api/v1/users/1", "/api/v2/workspaces/acme/users/1"} {
+		rec := httptest.NewRecorder()
+		srv.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	// Erasures are on disk before the response is sent.
+	for _, path := range []string{cfg.Data.UsersPath, filepath.Join(dir, "workspaces", "acme", "users.json")} {
+		users, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[]`, string(users))
+	}
+	audit, err := os.ReadFile(cfg.Audit.LogPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(audit)), "\n")
+	require.Len(t, lines, 2)
+	assert.NotContains(t, lines[0], `"workspace"`)
+	assert.Contains(t, lines[1], `"workspace":"acme"`)
+}
+
+func TestNew_ErasureDisabled(t *testing.T) {
+	dir := t.TempDir()
+	writeDataset(t, dir, "Ada")
+
+	cfg := config.Default()
+	cfg.Data.UsersPath = filepath.Join(dir, "users.json")
+	cfg.Data.ActionsPath = filepath.Join(dir, "actions.json")
+	srv := newTestServer(t, cfg)
+
+	rec := httptest.NewRecorder()
+	srv.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/users/1", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -1,84 +1,331 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"math"
+	"sort"
+	"surfe/internal/actiontypes"
+	"surfe/internal/apperrors"
+	"surfe/internal/ctxcheck"
+	"surfe/internal/logging"
 	"surfe/internal/models"
 	"surfe/internal/repository"
+	"surfe/internal/validate"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type actionService struct {
+	userRepo   repository.UserRepository
 	actionRepo repository.ActionRepository
+	types      *actiontypes.Registry
+	// usersMu is shared with the dataset's erasure service. Recording holds
+	// it for reading, so no action is stored for a user being erased.
+	usersMu *sync.RWMutex
+
+	indexMu       sync.Mutex
+	referralIndex *referralIndex
+	// indexVersion is the version of the actions dataset the referral index
+	// was built from or brought up to.
+	indexVersion uint64
 }
 
 type ReferralGraph map[int][]int
 
-func NewActionService(actionRepo repository.ActionRepository) ActionService {
+func NewActionService(userRepo repository.UserRepository, actionRepo repository.ActionRepository, types *actiontypes.Registry, usersMu *sync.RWMutex) ActionService {
 	return &actionService{
+		userRepo:   userRepo,
 		actionRepo: actionRepo,
+		types:      types,
+		usersMu:    usersMu,
 	}
 }
 
-func (s *actionService) GetNextActionProbabilities(actionType string) (map[string]float64, error) {
-	nextActions, total, err := s.actionRepo.GetNextActions(actionType)
+func (s *actionService) GetActionTypes(ctx context.Context) ([]models.ActionType, error) {
+	return s.types.All(), nil
+}
+
+func (s *actionService) GetActionType(ctx context.Context, name string) (*models.ActionType, error) {
+	actionType, found := s.types.Lookup(name)
+	if !found {
+		return nil, fmt.Errorf("action type %s: %w", name, apperrors.ErrNotFound)
+	}
+	return &actionType, nil
+}
+
+// GetNextActionProbabilities returns the share of each action type among the
+// actions that followed actionType, rounded to two decimals.
+func (s *actionService) GetNextActionProbabilities(ctx context.Context, actionType string) (map[string]float64, error) {
+	nextActions, err := s.GetNextActions(ctx, actionType)
 	if err != nil {
 		return nil, err
 	}
 
 	nextActionProbabilities := make(map[string]float64)
 
-	for actionType, count := range nextActions {
-		probability := float64(count) / float64(total)
-		nextActionProbabilities[actionType] = math.Round(probability*100) / 100
+	for _, next := range nextActions.Next {
+		nextActionProbabilities[next.Type] = math.Round(next.Probability*100) / 100
 	}
 
 	return nextActionProbabilities, nil
 }
 
-func (s *actionService) GetReferralIndex() (map[int]int, error) {
-	actions, err := s.actionRepo.GetAll()
+// GetNextActions returns how often each action type followed actionType,
+// with unrounded probabilities, most frequent first and by name among
+// equals.
+func (s *actionService) GetNextActions(ctx context.Context, actionType string) (*models.NextActions, error) {
+	if _, found := s.types.Lookup(actionType); !found {
+		return nil, fmt.Errorf("action type %s: %w", actionType, apperrors.ErrNotFound)
+	}
+
+	counts, total, err := s.actionRepo.GetNextActions(ctx, actionType)
 	if err != nil {
 		return nil, err
 	}
-	graph := buildReferralGraph(actions)
-	referralIndex := make(map[int]int)
-	processed := make(map[int]bool)
 
-	var dfs func(userID int) int
-	dfs = func(userID int) int {
-		if count, found := referralIndex[userID]; found {
-			return count
+	nextActions := &models.NextActions{
+		ActionType: actionType,
+		Total:      total,
+		Next:       make([]models.NextAction, 0, len(counts)),
+	}
+	for nextType, count := range counts {
+		nextActions.Next = append(nextActions.Next, models.NextAction{
+			Type:        nextType,
+			Count:       count,
+			Probability: float64(count) / float64(total),
+		})
+	}
+	sort.Slice(nextActions.Next, func(i, j int) bool {
+		a, b := nextActions.Next[i], nextActions.Next[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
 		}
+		return a.Type < b.Type
+	})
 
-		count := 0
-		processed[userID] = true
+	return nextActions, nil
+}
 
-		for _, referredUser := range graph[userID] {
-			if !processed[referredUser] {
-				count += 1 + dfs(referredUser)
-				continue
-			}
-			count += 1 + referralIndex[referredUser]
+// GetReferralIndex returns how many users each user has referred, directly or
+// indirectly. The index is built from the repository on first use and kept up
+// to date by RecordAction afterwards. Any other change to the actions, such
+// as a reload or a user erasure, has it rebuilt.
+func (s *actionService) GetReferralIndex(ctx context.Context) (map[int]int, error) {
+	idx, err := s.loadReferralIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return idx.Snapshot(), nil
+}
+
+// RecordAction stores a new action and, for referrals, updates the referral
+// index of the referrer and all of its ancestors.
+func (s *actionService) RecordAction(ctx context.Context, action models.Action) (models.Action, error) {
+	if _, found := s.types.Lookup(action.Type); !found {
+		return models.Action{}, fmt.Errorf("%w: unknown action type %s", apperrors.ErrInvalidArgument, action.Type)
+	}
+
+	// The users are checked and the action stored without an erasure in
+	// between, or the action could outlive its user.
+	s.usersMu.RLock()
+	defer s.usersMu.RUnlock()
+	if err := s.checkAction(ctx, action); err != nil {
+		return models.Action{}, err
+	}
+
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	before := s.actionRepo.Status(ctx).Version
+	stored, err := s.actionRepo.Add(ctx, action)
+	if err != nil {
+		return models.Action{}, err
+	}
+	// The index is only updated in place when this action is the sole
+	// change since it was current; otherwise it is rebuilt on next use.
+	if after := s.actionRepo.Status(ctx).Version; s.referralIndex != nil && s.indexVersion == before && after == before+1 {
+		if target, found := stored.Target(); found && s.types.TargetsUser(stored.Type) {
+			s.referralIndex.Add(stored.UserID, target)
 		}
+		s.indexVersion = after
+	}
+	logging.FromContext(ctx).Info("action recorded",
+		slog.Int("action_id", stored.ID), slog.String("action_type", stored.Type))
+	return stored, nil
+}
 
-		referralIndex[userID] = count
-		return count
+// checkAction reports apperrors.ErrInvalidArgument unless the action's user
+// and target user exist and the action passes the rules `surfe validate`
+// applies to stored actions.
+func (s *actionService) checkAction(ctx context.Context, action models.Action) error {
+	ids := []int{action.UserID}
+	if target, found := action.Target(); found {
+		ids = append(ids, target)
 	}
+	users, err := s.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, found := users[id]; !found {
+			return fmt.Errorf("%w: unknown user %d", apperrors.ErrInvalidArgument, id)
+		}
+	}
+	for _, finding := range validate.Action(action, users, s.types, time.Now()) {
+		if finding.Severity == validate.SeverityError {
+			return fmt.Errorf("%w: %s", apperrors.ErrInvalidArgument, finding.Message)
+		}
+	}
+	return nil
+}
+
+func (s *actionService) loadReferralIndex(ctx context.Context) (*referralIndex, error) {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	version := s.actionRepo.Status(ctx).Version
+	if s.referralIndex != nil && s.indexVersion == version {
+		return s.referralIndex, nil
+	}
+
+	start := time.Now()
+	actions, err := s.actionRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := newReferralIndex(ctx, actions, s.types)
+	if err != nil {
+		return nil, err
+	}
+	s.referralIndex = idx
+	s.indexVersion = version
+	// Only the request after a change pays for building the index; mark it
+	// so slow traces can be told apart from the rest.
+	trace.SpanFromContext(ctx).AddEvent("referral index built",
+		trace.WithAttributes(attribute.Int("surfe.records_scanned", len(actions))))
+	logging.FromContext(ctx).Info("referral index built",
+		slog.Int("actions", len(actions)), slog.Duration("duration", time.Since(start)))
+	return s.referralIndex, nil
+}
+
+// GetReferralQuality reports, for each referrer, how many of the users they
+// referred went on to perform one of the activation action types, and the
+// median time between the referral and the referred user's first activation.
+func (s *actionService) GetReferralQuality(ctx context.Context, activationTypes []string) ([]models.ReferralQuality, error) {
+	for _, actionType := range activationTypes {
+		if _, found := s.types.Lookup(actionType); !found {
+			return nil, fmt.Errorf("%w: unknown activation type %s", apperrors.ErrInvalidArgument, actionType)
+		}
+	}
+
+	actions, err := s.actionRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	graph := buildReferralGraph(actions, s.types)
+	referredAt := buildReferralTimes(actions, s.types)
+
+	activation := make(map[string]bool, len(activationTypes))
+	for _, actionType := range activationTypes {
+		activation[actionType] = true
+	}
+
+	history := make(map[int][]models.Action)
+	for i, action := range actions {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		if activation[action.Type] {
+			history[action.UserID] = append(history[action.UserID], action)
+		}
+	}
+
+	referrers := make([]int, 0, len(graph))
 	for userID := range graph {
-		if !processed[userID] {
-			dfs(userID)
+		referrers = append(referrers, userID)
+	}
+	sort.Ints(referrers)
+
+	quality := make([]models.ReferralQuality, 0, len(referrers))
+	for i, referrerID := range referrers {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return nil, err
 		}
+		referred := graph[referrerID]
+		var delays []time.Duration
+
+		for _, referredUser := range referred {
+			if delay, ok := firstActivationDelay(history[referredUser], referredAt[referredUser]); ok {
+				delays = append(delays, delay)
+			}
+		}
+
+		q := models.ReferralQuality{
+			ReferrerID: referrerID,
+			Referred:   len(referred),
+			Activated:  len(delays),
+		}
+		if len(referred) > 0 {
+			q.ActivationRate = roundRatio(len(delays), len(referred))
+		}
+		if len(delays) > 0 {
+			median := medianDuration(delays).Seconds()
+			q.MedianActivationSeconds = &median
+		}
+		quality = append(quality, q)
+	}
+
+	return quality, nil
+}
+
+func firstActivationDelay(activations []models.Action, referredAt time.Time) (time.Duration, bool) {
+	var first *models.Action
+	for i, action := range activations {
+		if action.CreatedAt.Before(referredAt) {
+			continue
+		}
+		if first == nil || action.CreatedAt.Before(first.CreatedAt) {
+			first = &activations[i]
+		}
+	}
+	if first == nil {
+		return 0, false
+	}
+	return first.CreatedAt.Sub(referredAt), true
+}
+
+func medianDuration(durations []time.Duration) time.Duration {
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	mid := len(durations) / 2
+	if len(durations)%2 == 0 {
+		return (durations[mid-1] + durations[mid]) / 2
 	}
+	return durations[mid]
+}
 
-	return referralIndex, nil
+func buildReferralTimes(actions []models.Action, types *actiontypes.Registry) map[int]time.Time {
+	referredAt := make(map[int]time.Time)
+	for _, action := range actions {
+		if target, found := action.Target(); found && types.TargetsUser(action.Type) {
+			referredAt[target] = action.CreatedAt
+		}
+	}
+	return referredAt
 }
 
-func buildReferralGraph(actions []models.Action) ReferralGraph {
+func buildReferralGraph(actions []models.Action, types *actiontypes.Registry) ReferralGraph {
 	graph := make(ReferralGraph)
 	for _, action := range actions {
-		if action.Type == "REFER_USER" {
-			graph[action.UserID] = append(graph[action.UserID], action.TargetUser)
+		if target, found := action.Target(); found && types.TargetsUser(action.Type) {
+			graph[action.UserID] = append(graph[action.UserID], target)
 		}
 	}
 	return graph
 }
-
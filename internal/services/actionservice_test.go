@@ -1,9 +1,13 @@
 package services
 
 import (
+	"context"
+	"sync"
 	"testing"
 	"time"
 
+	"surfe/internal/actiontypes"
+	"surfe/internal/apperrors"
 	"surfe/internal/models"
 
 	"github.com/stretchr/testify/assert"
@@ -15,26 +19,66 @@ type MockActionRepository struct {
 	mock.Mock
 }
 
-func (m *MockActionRepository) GetByUserID(userID int) ([]models.Action, error) {
+// newTestActionTypes returns the built-in action types plus the ad hoc types
+// used by the tests in this package.
+func newTestActionTypes() *actiontypes.Registry {
+	types := actiontypes.Default()
+	for _, name := range []string{"LOGIN", "VIEW_PROFILE", "LOGOUT"} {
+		types.Observe(name)
+	}
+	return types
+}
+
+func (m *MockActionRepository) GetByUserID(ctx context.Context, userID int) ([]models.Action, error) {
 	args := m.Called(userID)
 	return args.Get(0).([]models.Action), args.Error(1)
 }
 
-func (m *MockActionRepository) GetAll() ([]models.Action, error) {
+func (m *MockActionRepository) CountByUserIDs(ctx context.Context, userIDs []int) (map[int]int, error) {
+	args := m.Called(userIDs)
+	return args.Get(0).(map[int]int), args.Error(1)
+}
+
+func (m *MockActionRepository) GetAll(ctx context.Context) ([]models.Action, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Action), args.Error(1)
 }
 
-func (m *MockActionRepository) GetNextActions(actionType string) (map[string]int, int, error) {
+func (m *MockActionRepository) GetNextActions(ctx context.Context, actionType string) (map[string]int, int, error) {
 	args := m.Called(actionType)
 	return args.Get(0).(map[string]int), args.Get(1).(int), args.Error(2)
 }
 
-func (m *MockActionRepository) GetReferrals() (map[int][]int, error) {
+func (m *MockActionRepository) GetReferrals(ctx context.Context) (map[int][]int, error) {
 	args := m.Called()
 	return args.Get(0).(map[int][]int), args.Error(1)
 }
 
+func (m *MockActionRepository) Add(ctx context.Context, action models.Action) (models.Action, error) {
+	args := m.Called(action)
+	return args.Get(0).(models.Action), args.Error(1)
+}
+
+func (m *MockActionRepository) DeleteByUserID(ctx context.Context, userID int) (int, int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Int(1), args.Error(2)
+}
+
+func (m *MockActionRepository) Load(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockActionRepository) Status(ctx context.Context) models.DatasetStatus {
+	args := m.Called()
+	return args.Get(0).(models.DatasetStatus)
+}
+
+func (m *MockActionRepository) Flush(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func TestGetNextActionProbabilities(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -66,18 +110,91 @@ func TestGetNextActionProbabilities(t *testing.T) {
 			expected:      map[string]float64{},
 			expectedError: false,
 		},
+		{
+			name:          "unknown action type",
+			actionType:    "LOGUOT",
+			expected:      nil,
+			expectedError: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(MockActionRepository)
-			mockRepo.On("GetNextActions", tt.actionType).Return(tt.nextActions, tt.total, nil)
+			if !tt.expectedError {
+				mockRepo.On("GetNextActions", tt.actionType).Return(tt.nextActions, tt.total, nil)
+			}
 
-			service := NewActionService(mockRepo)
-			result, err := service.GetNextActionProbabilities(tt.actionType)
+			service := NewActionService(new(MockUserRepository), mockRepo, newTestActionTypes(), new(sync.RWMutex))
+			result, err := service.GetNextActionProbabilities(context.Background(), tt.actionType)
 
 			if tt.expectedError {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, apperrors.ErrNotFound)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetNextActions(t *testing.T) {
+	tests := []struct {
+		name          string
+		actionType    string
+		nextActions   map[string]int
+		total         int
+		expected      *models.NextActions
+		expectedError bool
+	}{
+		{
+			name:       "most frequent first, ties by name",
+			actionType: "LOGIN",
+			nextActions: map[string]int{
+				"VIEW_PROFILE": 1,
+				"LOGOUT":       1,
+				"REFER_USER":   4,
+			},
+			total: 6,
+			expected: &models.NextActions{
+				ActionType: "LOGIN",
+				Total:      6,
+				Next: []models.NextAction{
+					{Type: "REFER_USER", Count: 4, Probability: 4.0 / 6},
+					{Type: "LOGOUT", Count: 1, Probability: 1.0 / 6},
+					{Type: "VIEW_PROFILE", Count: 1, Probability: 1.0 / 6},
+				},
+			},
+		},
+		{
+			name:        "empty next actions",
+			actionType:  "LOGOUT",
+			nextActions: map[string]int{},
+			expected: &models.NextActions{
+				ActionType: "LOGOUT",
+				Next:       []models.NextAction{},
+			},
+		},
+		{
+			name:          "unknown action type",
+			actionType:    "LOGUOT",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockActionRepository)
+			if !tt.expectedError {
+				mockRepo.On("GetNextActions", tt.actionType).Return(tt.nextActions, tt.total, nil)
+			}
+
+			service := NewActionService(new(MockUserRepository), mockRepo, newTestActionTypes(), new(sync.RWMutex))
+			result, err := service.GetNextActions(context.Background(), tt.actionType)
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, apperrors.ErrNotFound)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expected, result)
@@ -98,9 +215,9 @@ func TestGetReferralIndex(t *testing.T) {
 		{
 			name: "simple referral chain",
 			actions: []models.Action{
-				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: 2, CreatedAt: now},
-				{ID: 2, Type: "REFER_USER", UserID: 2, TargetUser: 3, CreatedAt: now},
-				{ID: 3, Type: "REFER_USER", UserID: 3, TargetUser: 4, CreatedAt: now},
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: now},
+				{ID: 2, Type: "REFER_USER", UserID: 2, TargetUser: models.UserRef(3), CreatedAt: now},
+				{ID: 3, Type: "REFER_USER", UserID: 3, TargetUser: models.UserRef(4), CreatedAt: now},
 			},
 			expected: map[int]int{
 				1: 3,
@@ -113,9 +230,9 @@ func TestGetReferralIndex(t *testing.T) {
 		{
 			name: "multiple referrals from same user",
 			actions: []models.Action{
-				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: 2, CreatedAt: now},
-				{ID: 2, Type: "REFER_USER", UserID: 1, TargetUser: 3, CreatedAt: now},
-				{ID: 3, Type: "REFER_USER", UserID: 1, TargetUser: 4, CreatedAt: now},
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: now},
+				{ID: 2, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(3), CreatedAt: now},
+				{ID: 3, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(4), CreatedAt: now},
 			},
 			expected: map[int]int{
 				1: 3,
@@ -125,6 +242,18 @@ func TestGetReferralIndex(t *testing.T) {
 			},
 			expectedError: false,
 		},
+		{
+			name: "self-referral",
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: now},
+				{ID: 2, Type: "REFER_USER", UserID: 2, TargetUser: models.UserRef(2), CreatedAt: now},
+			},
+			expected: map[int]int{
+				1: 2,
+				2: 1,
+			},
+			expectedError: false,
+		},
 		{
 			name:          "no referrals",
 			actions:       []models.Action{},
@@ -133,16 +262,258 @@ func TestGetReferralIndex(t *testing.T) {
 		},
 	}
 
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockActionRepository)
+			mockRepo.On("Status").Return(models.DatasetStatus{Loaded: true, Version: 1})
+			mockRepo.On("GetAll").Return(tt.actions, nil)
+
+			service := NewActionService(new(MockUserRepository), mockRepo, newTestActionTypes(), new(sync.RWMutex))
+			result, err := service.GetReferralIndex(context.Background())
+
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetReferralQuality(t *testing.T) {
+	base := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	hour := 3600.0
+	twoHours := 7200.0
+	tests := []struct {
+		name            string
+		activationTypes []string
+		actions         []models.Action
+		expected        []models.ReferralQuality
+		expectedError   bool
+	}{
+		{
+			name:            "activated and inactive referrals",
+			activationTypes: []string{"CONNECT_CRM"},
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: base},
+				{ID: 2, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(3), CreatedAt: base},
+				{ID: 3, Type: "REFER_USER", UserID: 2, TargetUser: models.UserRef(4), CreatedAt: base},
+				{ID: 4, Type: "CONNECT_CRM", UserID: 2, CreatedAt: base.Add(time.Hour)},
+				{ID: 5, Type: "CONNECT_CRM", UserID: 2, CreatedAt: base.Add(3 * time.Hour)},
+				{ID: 6, Type: "ADD_CONTACT", UserID: 3, CreatedAt: base.Add(time.Hour)},
+			},
+			expected: []models.ReferralQuality{
+				{ReferrerID: 1, Referred: 2, Activated: 1, ActivationRate: 0.5, MedianActivationSeconds: &hour},
+				{ReferrerID: 2, Referred: 1, Activated: 0, ActivationRate: 0},
+			},
+			expectedError: false,
+		},
+		{
+			name:            "multiple activation types",
+			activationTypes: []string{"CONNECT_CRM", "ADD_CONTACT"},
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: base},
+				{ID: 2, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(3), CreatedAt: base},
+				{ID: 3, Type: "CONNECT_CRM", UserID: 2, CreatedAt: base.Add(time.Hour)},
+				{ID: 4, Type: "ADD_CONTACT", UserID: 3, CreatedAt: base.Add(3 * time.Hour)},
+			},
+			expected: []models.ReferralQuality{
+				{ReferrerID: 1, Referred: 2, Activated: 2, ActivationRate: 1, MedianActivationSeconds: &twoHours},
+			},
+			expectedError: false,
+		},
+		{
+			name:            "activation before referral is ignored",
+			activationTypes: []string{"CONNECT_CRM"},
+			actions: []models.Action{
+				{ID: 1, Type: "CONNECT_CRM", UserID: 2, CreatedAt: base.Add(-time.Hour)},
+				{ID: 2, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: base},
+			},
+			expected: []models.ReferralQuality{
+				{ReferrerID: 1, Referred: 1, Activated: 0, ActivationRate: 0},
+			},
+			expectedError: false,
+		},
+		{
+			name:            "no referrals",
+			activationTypes: []string{"CONNECT_CRM"},
+			actions:         []models.Action{},
+			expected:        []models.ReferralQuality{},
+			expectedError:   false,
+		},
+	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(MockActionRepository)
 			mockRepo.On("GetAll").Return(tt.actions, nil)
 
-			service := NewActionService(mockRepo)
-			result, err := service.GetReferralIndex()
+			service := NewActionService(new(MockUserRepository), mockRepo, newTestActionTypes(), new(sync.RWMutex))
+			result, err := service.GetReferralQuality(context.Background(), tt.activationTypes)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecordAction(t *testing.T) {
+	now := time.Now()
+	initial := []models.Action{
+		{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: now},
+		{ID: 2, Type: "REFER_USER", UserID: 2, TargetUser: models.UserRef(3), CreatedAt: now},
+	}
+	tests := []struct {
+		name          string
+		action        models.Action
+		expected      map[int]int
+		expectedError bool
+	}{
+		{
+			name:   "referral updates ancestors",
+			action: models.Action{Type: "REFER_USER", UserID: 3, TargetUser: models.UserRef(4), CreatedAt: now},
+			expected: map[int]int{
+				1: 3,
+				2: 2,
+				3: 1,
+				4: 0,
+			},
+			expectedError: false,
+		},
+		{
+			name:   "referral of an existing subtree",
+			action: models.Action{Type: "REFER_USER", UserID: 5, TargetUser: models.UserRef(1), CreatedAt: now},
+			expected: map[int]int{
+				5: 3,
+				1: 2,
+				2: 1,
+				3: 0,
+			},
+			expectedError: false,
+		},
+		{
+			name:   "non-referral leaves index unchanged",
+			action: models.Action{Type: "LOGIN", UserID: 3, CreatedAt: now},
+			expected: map[int]int{
+				1: 2,
+				2: 1,
+				3: 0,
+			},
+			expectedError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := tt.action
+			stored.ID = 3
+
+			userIDs := []int{tt.action.UserID}
+			if target, found := tt.action.Target(); found {
+				userIDs = append(userIDs, target)
+			}
+			users := make(map[int]models.User)
+			for _, id := range userIDs {
+				users[id] = models.User{ID: id}
+			}
+			mockUsers := new(MockUserRepository)
+			mockUsers.On("GetByIDs", userIDs).Return(users, nil)
+			mockRepo := new(MockActionRepository)
+			mockRepo.On("Status").Return(models.DatasetStatus{Loaded: true, Version: 1}).Twice()
+			mockRepo.On("Status").Return(models.DatasetStatus{Loaded: true, Version: 2})
+			mockRepo.On("GetAll").Return(initial, nil).Once()
+			mockRepo.On("Add", tt.action).Return(stored, nil)
 
+			service := NewActionService(mockUsers, mockRepo, newTestActionTypes(), new(sync.RWMutex))
+			_, err := service.GetReferralIndex(context.Background())
+			assert.NoError(t, err)
+
+			result, err := service.RecordAction(context.Background(), tt.action)
 			if tt.expectedError {
 				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, stored, result)
+
+				index, err := service.GetReferralIndex(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, index)
+			}
+			mockUsers.AssertExpectations(t)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetReferralIndex_RebuiltAfterChange(t *testing.T) {
+	now := time.Now()
+	mockRepo := new(MockActionRepository)
+	mockRepo.On("Status").Return(models.DatasetStatus{Loaded: true, Version: 1}).Twice()
+	mockRepo.On("GetAll").Return([]models.Action{
+		{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: now},
+		{ID: 2, Type: "REFER_USER", UserID: 2, TargetUser: models.UserRef(3), CreatedAt: now},
+	}, nil).Once()
+	// User 2 was erased and replaced by the placeholder -1.
+	mockRepo.On("Status").Return(models.DatasetStatus{Loaded: true, Version: 2})
+	mockRepo.On("GetAll").Return([]models.Action{
+		{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(-1), CreatedAt: now},
+		{ID: 2, Type: "REFER_USER", UserID: -1, TargetUser: models.UserRef(3), CreatedAt: now},
+	}, nil).Once()
+
+	service := NewActionService(new(MockUserRepository), mockRepo, newTestActionTypes(), new(sync.RWMutex))
+	for range 2 {
+		_, err := service.GetReferralIndex(context.Background())
+		assert.NoError(t, err)
+	}
+	index, err := service.GetReferralIndex(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{1: 2, -1: 1, 3: 0}, index)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetActionType(t *testing.T) {
+	tests := []struct {
+		name          string
+		actionType    string
+		expected      *models.ActionType
+		expectedError bool
+	}{
+		{
+			name:       "known type",
+			actionType: "REFER_USER",
+			expected: &models.ActionType{
+				Name:        "REFER_USER",
+				Description: "User referred the target user",
+				Category:    "growth",
+				TargetsUser: true,
+			},
+			expectedError: false,
+		},
+		{
+			name:          "unknown type",
+			actionType:    "REFER_USERS",
+			expected:      nil,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockActionRepository)
+
+			service := NewActionService(new(MockUserRepository), mockRepo, actiontypes.Default(), new(sync.RWMutex))
+			result, err := service.GetActionType(context.Background(), tt.actionType)
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, apperrors.ErrNotFound)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expected, result)
@@ -151,3 +522,125 @@ func TestGetReferralIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordAction_UnknownType(t *testing.T) {
+	mockRepo := new(MockActionRepository)
+
+	service := NewActionService(new(MockUserRepository), mockRepo, actiontypes.Default(), new(sync.RWMutex))
+	_, err := service.RecordAction(context.Background(), models.Action{Type: "REFER_USERS", UserID: 1, TargetUser: models.UserRef(2)})
+
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRecordAction_UnknownUser(t *testing.T) {
+	tests := []struct {
+		name          string
+		action        models.Action
+		userIDs       []int
+		users         map[int]models.User
+		usersError    error
+		expectedError error
+		expectedMsg   string
+	}{
+		{
+			name:          "unknown user",
+			action:        models.Action{Type: "LOGIN", UserID: 9},
+			userIDs:       []int{9},
+			users:         map[int]models.User{},
+			expectedError: apperrors.ErrInvalidArgument,
+			expectedMsg:   "invalid argument: unknown user 9",
+		},
+		{
+			name:          "unknown target user",
+			action:        models.Action{Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(9)},
+			userIDs:       []int{1, 9},
+			users:         map[int]models.User{1: {ID: 1}},
+			expectedError: apperrors.ErrInvalidArgument,
+			expectedMsg:   "invalid argument: unknown user 9",
+		},
+		{
+			name:          "users unavailable",
+			action:        models.Action{Type: "LOGIN", UserID: 1},
+			userIDs:       []int{1},
+			usersError:    apperrors.ErrUnavailable,
+			expectedError: apperrors.ErrUnavailable,
+			expectedMsg:   "unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsers := new(MockUserRepository)
+			mockUsers.On("GetByIDs", tt.userIDs).Return(tt.users, tt.usersError)
+			mockRepo := new(MockActionRepository)
+
+			service := NewActionService(mockUsers, mockRepo, newTestActionTypes(), new(sync.RWMutex))
+			_, err := service.RecordAction(context.Background(), tt.action)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.EqualError(t, err, tt.expectedMsg)
+			mockUsers.AssertExpectations(t)
+			// Nothing is recorded.
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecordAction_Invalid(t *testing.T) {
+	jan1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jan2 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	future := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	users := map[int]models.User{
+		1: {ID: 1, CreatedAt: jan1},
+		2: {ID: 2, CreatedAt: jan2},
+	}
+	tests := []struct {
+		name        string
+		action      models.Action
+		userIDs     []int
+		expectedMsg string
+	}{
+		{
+			name:        "referral without target",
+			action:      models.Action{Type: "REFER_USER", UserID: 1, CreatedAt: jan2},
+			userIDs:     []int{1},
+			expectedMsg: "invalid argument: REFER_USER action has no targetUser",
+		},
+		{
+			name:        "target on an action without one",
+			action:      models.Action{Type: "WELCOME", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: jan2},
+			userIDs:     []int{1, 2},
+			expectedMsg: "invalid argument: targetUser 2 is set on a WELCOME action",
+		},
+		{
+			name:        "future timestamp",
+			action:      models.Action{Type: "LOGIN", UserID: 1, CreatedAt: future},
+			userIDs:     []int{1},
+			expectedMsg: "invalid argument: createdAt " + future.Format(time.RFC3339) + " is in the future",
+		},
+		{
+			name:        "before user created",
+			action:      models.Action{Type: "LOGIN", UserID: 2, CreatedAt: jan1},
+			userIDs:     []int{2},
+			expectedMsg: "invalid argument: createdAt 2024-01-01T00:00:00Z is before user 2 was created at 2024-01-02T00:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsers := new(MockUserRepository)
+			mockUsers.On("GetByIDs", tt.userIDs).Return(users, nil)
+			mockRepo := new(MockActionRepository)
+
+			service := NewActionService(mockUsers, mockRepo, newTestActionTypes(), new(sync.RWMutex))
+			_, err := service.RecordAction(context.Background(), tt.action)
+
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+			assert.EqualError(t, err, tt.expectedMsg)
+			mockUsers.AssertExpectations(t)
+			// Nothing is recorded.
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"surfe/internal/logging"
+	"surfe/internal/models"
+	"surfe/internal/repository"
+	"sync"
+	"time"
+)
+
+// EventUserErased is the audit event recorded for every erasure.
+const EventUserErased = "user.erased"
+
+type erasureService struct {
+	userRepo   repository.UserRepository
+	actionRepo repository.ActionRepository
+	audit      AuditTrail
+	// usersMu is shared with the dataset's action service, which cannot
+	// record actions while an erasure holds it.
+	usersMu *sync.RWMutex
+	now     func() time.Time
+}
+
+func NewErasureService(userRepo repository.UserRepository, actionRepo repository.ActionRepository, audit AuditTrail, usersMu *sync.RWMutex) ErasureService {
+	return &erasureService{
+		userRepo:   userRepo,
+		actionRepo: actionRepo,
+		audit:      audit,
+		usersMu:    usersMu,
+		now:        time.Now,
+	}
+}
+
+// EraseUser removes the user's actions before the user, so an erasure that
+// fails half way can be retried. Once the actions are gone the erasure is
+// carried through even if the request is abandoned. No actions are recorded
+// until the erasure is done.
+func (s *erasureService) EraseUser(ctx context.Context, userID int) (*models.Erasure, error) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	deleted, anonymised, err := s.actionRepo.DeleteByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
+		return nil, err
+	}
+	// Erasures are written out at once rather than on shutdown, so a crash
+	// cannot bring the user's data back.
+	if err := errors.Join(s.actionRepo.Flush(ctx), s.userRepo.Flush(ctx)); err != nil {
+		return nil, fmt.Errorf("user %d erased but not persisted: %w", userID, err)
+	}
+
+	erasure := &models.Erasure{
+		UserID:            userID,
+		ErasedAt:          s.now().UTC(),
+		ActionsDeleted:    deleted,
+		ActionsAnonymised: anonymised,
+	}
+	if err := s.audit.Record(ctx, models.AuditEvent{Time: erasure.ErasedAt, Event: EventUserErased, Erasure: erasure}); err != nil {
+		return nil, fmt.Errorf("user %d erased but not audited: %w", userID, err)
+	}
+	logging.FromContext(ctx).Info("user erased",
+		slog.Int("actions_deleted", deleted), slog.Int("actions_anonymised", anonymised))
+	return erasure, nil
+}
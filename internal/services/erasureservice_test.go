@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditTrail is a mock implementation of AuditTrail
+type MockAuditTrail struct {
+	mock.Mock
+}
+
+func (m *MockAuditTrail) Record(ctx context.Context, event models.AuditEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func TestEraseUser(t *testing.T) {
+	erasedAt := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	erasure := &models.Erasure{UserID: 2, ErasedAt: erasedAt, ActionsDeleted: 4, ActionsAnonymised: 2}
+
+	tests := []struct {
+		name          string
+		setup         func(users *MockUserRepository, actions *MockActionRepository, audit *MockAuditTrail)
+		expected      *models.Erasure
+		expectedError error
+		expectedMsg   string
+	}{
+		{
+			name: "user erased",
+			setup: func(users *MockUserRepository, actions *MockActionRepository, audit *MockAuditTrail) {
+				users.On("GetByID", 2).Return(&models.User{ID: 2}, nil)
+				actions.On("DeleteByUserID", 2).Return(4, 2, nil)
+				users.On("Delete", 2).Return(nil)
+				actions.On("Flush").Return(nil)
+				users.On("Flush").Return(nil)
+				audit.On("Record", models.AuditEvent{Time: erasedAt, Event: EventUserErased, Erasure: erasure}).Return(nil)
+			},
+			expected: erasure,
+		},
+		{
+			name: "user not found",
+			setup: func(users *MockUserRepository, actions *MockActionRepository, audit *MockAuditTrail) {
+				users.On("GetByID", 2).Return(nil, fmt.Errorf("user 2: %w", apperrors.ErrNotFound))
+			},
+			expectedError: apperrors.ErrNotFound,
+			expectedMsg:   "user 2: not found",
+		},
+		{
+			name: "flush fails",
+			setup: func(users *MockUserRepository, actions *MockActionRepository, audit *MockAuditTrail) {
+				users.On("GetByID", 2).Return(&models.User{ID: 2}, nil)
+				actions.On("DeleteByUserID", 2).Return(4, 2, nil)
+				users.On("Delete", 2).Return(nil)
+				actions.On("Flush").Return(fmt.Errorf("%w: disk full", apperrors.ErrUnavailable))
+				users.On("Flush").Return(nil)
+			},
+			expectedError: apperrors.ErrUnavailable,
+			expectedMsg:   "user 2 erased but not persisted: unavailable: disk full",
+		},
+		{
+			name: "audit fails",
+			setup: func(users *MockUserRepository, actions *MockActionRepository, audit *MockAuditTrail) {
+				users.On("GetByID", 2).Return(&models.User{ID: 2}, nil)
+				actions.On("DeleteByUserID", 2).Return(4, 2, nil)
+				users.On("Delete", 2).Return(nil)
+				actions.On("Flush").Return(nil)
+				users.On("Flush").Return(nil)
+				audit.On("Record", mock.Anything).Return(assert.AnError)
+			},
+			expectedError: assert.AnError,
+			expectedMsg:   "user 2 erased but not audited",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := new(MockUserRepository)
+			actions := new(MockActionRepository)
+			audit := new(MockAuditTrail)
+			tt.setup(users, actions, audit)
+
+			service := NewErasureService(users, actions, audit, new(sync.RWMutex)).(*erasureService)
+			service.now = func() time.Time { return erasedAt }
+			result, err := service.EraseUser(context.Background(), 2)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.ErrorContains(t, err, tt.expectedMsg)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			users.AssertExpectations(t)
+			actions.AssertExpectations(t)
+			audit.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEraseUser_RecordActionWaits(t *testing.T) {
+	users := new(MockUserRepository)
+	actions := new(MockActionRepository)
+	audit := new(MockAuditTrail)
+
+	deleting := make(chan struct{})
+	release := make(chan struct{})
+	var checked atomic.Bool
+	users.On("GetByID", 2).Return(&models.User{ID: 2}, nil)
+	actions.On("DeleteByUserID", 2).Return(1, 0, nil)
+	users.On("Delete", 2).Run(func(mock.Arguments) {
+		close(deleting)
+		<-release
+	}).Return(nil)
+	actions.On("Flush").Return(nil)
+	users.On("Flush").Return(nil)
+	audit.On("Record", mock.Anything).Return(nil)
+	// By the time the recording may look the user up, it has been erased.
+	users.On("GetByIDs", []int{2}).Run(func(mock.Arguments) {
+		checked.Store(true)
+	}).Return(map[int]models.User{}, nil)
+
+	usersMu := new(sync.RWMutex)
+	erasures := NewErasureService(users, actions, audit, usersMu)
+	recordings := NewActionService(users, actions, newTestActionTypes(), usersMu)
+
+	erased := make(chan error)
+	go func() {
+		_, err := erasures.EraseUser(context.Background(), 2)
+		erased <- err
+	}()
+	<-deleting
+
+	// The user's actions are gone but the user is not yet; an action
+	// recorded now would survive the erasure.
+	recorded := make(chan error)
+	go func() {
+		_, err := recordings.RecordAction(context.Background(), models.Action{Type: "LOGIN", UserID: 2, CreatedAt: time.Now()})
+		recorded <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, checked.Load(), "action recorded during the erasure")
+
+	close(release)
+	assert.NoError(t, <-erased)
+	assert.ErrorIs(t, <-recorded, apperrors.ErrInvalidArgument)
+	users.AssertExpectations(t)
+	// Nothing is recorded.
+	actions.AssertExpectations(t)
+}
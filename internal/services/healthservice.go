@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"surfe/internal/models"
+	"surfe/internal/repository"
+)
+
+type healthService struct {
+	datasets []repository.Dataset
+}
+
+func NewHealthService(datasets ...repository.Dataset) HealthService {
+	return &healthService{
+		datasets: datasets,
+	}
+}
+
+// Readiness reports the status of every dataset. The service is ready once
+// all of them are loaded.
+func (s *healthService) Readiness(ctx context.Context) *models.Readiness {
+	readiness := &models.Readiness{
+		Ready:    true,
+		Datasets: make([]models.DatasetStatus, 0, len(s.datasets)),
+	}
+	for _, dataset := range s.datasets {
+		status := dataset.Status(ctx)
+		readiness.Ready = readiness.Ready && status.Loaded
+		readiness.Datasets = append(readiness.Datasets, status)
+	}
+	return readiness
+}
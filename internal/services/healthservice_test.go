@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"surfe/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthService_Readiness(t *testing.T) {
+	loadedAt := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	users := models.DatasetStatus{Name: "users", Source: "users.json", Loaded: true, LoadedAt: &loadedAt, Records: 2, Checksum: "sha256:abc"}
+	actions := models.DatasetStatus{Name: "actions", Source: "actions.json", Loaded: true, LoadedAt: &loadedAt, Records: 5, Checksum: "sha256:def"}
+	missing := models.DatasetStatus{Name: "actions", Source: "actions.json", Error: "open actions.json: no such file or directory"}
+
+	tests := []struct {
+		name          string
+		userStatus    models.DatasetStatus
+		actionStatus  models.DatasetStatus
+		expectedReady bool
+	}{
+		{
+			name:          "all datasets loaded",
+			userStatus:    users,
+			actionStatus:  actions,
+			expectedReady: true,
+		},
+		{
+			name:          "dataset missing",
+			userStatus:    users,
+			actionStatus:  missing,
+			expectedReady: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := new(MockUserRepository)
+			actionRepo := new(MockActionRepository)
+			userRepo.On("Status").Return(tt.userStatus)
+			actionRepo.On("Status").Return(tt.actionStatus)
+
+			service := NewHealthService(userRepo, actionRepo)
+			readiness := service.Readiness(context.Background())
+
+			assert.Equal(t, tt.expectedReady, readiness.Ready)
+			assert.Equal(t, []models.DatasetStatus{tt.userStatus, tt.actionStatus}, readiness.Datasets)
+			userRepo.AssertExpectations(t)
+			actionRepo.AssertExpectations(t)
+		})
+	}
+}
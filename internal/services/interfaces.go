@@ -1,13 +1,56 @@
 package services
 
-import "surfe/internal/models"
+import (
+	"context"
+	"surfe/internal/models"
+	"time"
+)
 
 type UserService interface {
-	GetUserByID(id int) (*models.User, error)
-	GetUserActionCount(userID int) (int, error)
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	GetUserActionCount(ctx context.Context, userID int) (int, error)
+	// GetUserActionStats breaks a user's actions down by type and time,
+	// overall and, when from or to is set, within [from, to).
+	GetUserActionStats(ctx context.Context, userID int, from, to *time.Time) (*models.UserActionStats, error)
+	// GetUsers looks up several users and their action counts at once,
+	// answering each distinct ID once, in request order.
+	GetUsers(ctx context.Context, ids []int) ([]models.UserBatchItem, error)
+}
+
+// ErasureService erases users on request, as data protection law requires.
+type ErasureService interface {
+	// EraseUser deletes a user and their actions, keeping their place in
+	// the referral graph as an anonymous placeholder, persists the change
+	// and records it in the audit trail.
+	EraseUser(ctx context.Context, userID int) (*models.Erasure, error)
+}
+
+// AuditTrail keeps a durable record of changes that must be accounted for.
+type AuditTrail interface {
+	Record(ctx context.Context, event models.AuditEvent) error
 }
 
 type ActionService interface {
-	GetNextActionProbabilities(actionType string) (map[string]float64, error)
-	GetReferralIndex() (map[int]int, error)
+	GetActionTypes(ctx context.Context) ([]models.ActionType, error)
+	GetActionType(ctx context.Context, name string) (*models.ActionType, error)
+	GetNextActionProbabilities(ctx context.Context, actionType string) (map[string]float64, error)
+	GetNextActions(ctx context.Context, actionType string) (*models.NextActions, error)
+	GetReferralIndex(ctx context.Context) (map[int]int, error)
+	GetReferralQuality(ctx context.Context, activationTypes []string) ([]models.ReferralQuality, error)
+	RecordAction(ctx context.Context, action models.Action) (models.Action, error)
+}
+
+type ReferralService interface {
+	GetReferralStats(ctx context.Context, top int) (*models.ReferralStats, error)
+	GetReferralNetwork(ctx context.Context, root *int, maxDepth int) (*models.ReferralNetwork, error)
+}
+
+type HealthService interface {
+	Readiness(ctx context.Context) *models.Readiness
+}
+
+type VersionService interface {
+	// Version returns the current data version, or nil while any dataset
+	// is not loaded.
+	Version(ctx context.Context) *models.DataVersion
 }
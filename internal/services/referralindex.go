@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"surfe/internal/actiontypes"
+	"surfe/internal/ctxcheck"
+	"surfe/internal/models"
+	"sync"
+)
+
+// referralIndex maintains the number of users each user has referred,
+// directly or indirectly. Recording a referral walks up the referrer's
+// ancestors, so updates cost O(depth) rather than a full rebuild.
+type referralIndex struct {
+	mu       sync.RWMutex
+	parents  map[int][]int
+	counts   map[int]int
+	snapshot map[int]int
+}
+
+// newReferralIndex builds the index from every referral in actions. Each
+// referral walks up its referrer's ancestors, so the build checks ctx as it
+// goes and gives up once ctx is done.
+func newReferralIndex(ctx context.Context, actions []models.Action, types *actiontypes.Registry) (*referralIndex, error) {
+	idx := &referralIndex{
+		parents: make(map[int][]int),
+		counts:  make(map[int]int),
+	}
+	for i, action := range actions {
+		if err := ctxcheck.Canceled(ctx, i); err != nil {
+			return nil, err
+		}
+		if target, found := action.Target(); found && types.TargetsUser(action.Type) {
+			idx.add(action.UserID, target)
+		}
+	}
+	return idx, nil
+}
+
+// Add records that referrer referred the referred user.
+func (idx *referralIndex) Add(referrer, referred int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.add(referrer, referred)
+	idx.snapshot = nil
+}
+
+func (idx *referralIndex) add(referrer, referred int) {
+	if _, found := idx.counts[referred]; !found {
+		idx.counts[referred] = 0
+	}
+
+	// A self-referral counts once for the user and their ancestors, as it
+	// always has, without making the user their own ancestor.
+	delta := 1
+	visited := make(map[int]bool)
+	if referrer != referred {
+		idx.parents[referred] = append(idx.parents[referred], referrer)
+		delta += idx.counts[referred]
+		visited[referred] = true
+	}
+
+	var propagate func(userID int)
+	propagate = func(userID int) {
+		if visited[userID] {
+			return
+		}
+		visited[userID] = true
+		idx.counts[userID] += delta
+		for _, parent := range idx.parents[userID] {
+			propagate(parent)
+		}
+		visited[userID] = false
+	}
+	propagate(referrer)
+}
+
+// Snapshot returns the current counts. The returned map is shared between
+// callers until the next Add and must not be modified.
+func (idx *referralIndex) Snapshot() map[int]int {
+	idx.mu.RLock()
+	snapshot := idx.snapshot
+	idx.mu.RUnlock()
+	if snapshot != nil {
+		return snapshot
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.snapshot == nil {
+		idx.snapshot = make(map[int]int, len(idx.counts))
+		for userID, count := range idx.counts {
+			idx.snapshot[userID] = count
+		}
+	}
+	return idx.snapshot
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"surfe/internal/actiontypes"
+	"surfe/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferralIndex_Add(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		actions   []models.Action
+		referrals [][2]int
+		expected  map[int]int
+	}{
+		{
+			name: "extend chain",
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: now},
+			},
+			referrals: [][2]int{{2, 3}, {3, 4}},
+			expected: map[int]int{
+				1: 3,
+				2: 2,
+				3: 1,
+				4: 0,
+			},
+		},
+		{
+			name: "attach referred subtree",
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 2, TargetUser: models.UserRef(3), CreatedAt: now},
+				{ID: 2, Type: "REFER_USER", UserID: 3, TargetUser: models.UserRef(4), CreatedAt: now},
+			},
+			referrals: [][2]int{{1, 2}},
+			expected: map[int]int{
+				1: 3,
+				2: 2,
+				3: 1,
+				4: 0,
+			},
+		},
+		{
+			name: "self-referral counts once",
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: now},
+				{ID: 2, Type: "REFER_USER", UserID: 2, TargetUser: models.UserRef(2), CreatedAt: now},
+			},
+			referrals: [][2]int{{2, 3}, {3, 3}},
+			expected: map[int]int{
+				1: 4,
+				2: 3,
+				3: 1,
+			},
+		},
+		{
+			name: "cycle terminates",
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: now},
+			},
+			referrals: [][2]int{{2, 1}},
+			expected: map[int]int{
+				1: 1,
+				2: 2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, err := newReferralIndex(context.Background(), tt.actions, actiontypes.Default())
+			assert.NoError(t, err)
+			before := idx.Snapshot()
+
+			for _, referral := range tt.referrals {
+				idx.Add(referral[0], referral[1])
+			}
+
+			assert.Equal(t, tt.expected, idx.Snapshot())
+			assert.NotEqual(t, before, idx.Snapshot())
+		})
+	}
+}
+
+func TestNewReferralIndex_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	idx, err := newReferralIndex(ctx, []models.Action{
+		{Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2)},
+	}, actiontypes.Default())
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, idx)
+}
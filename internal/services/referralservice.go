@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"surfe/internal/actiontypes"
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+	"surfe/internal/repository"
+)
+
+const cohortLayout = "2006-01"
+
+type referralService struct {
+	userRepo   repository.UserRepository
+	actionRepo repository.ActionRepository
+	types      *actiontypes.Registry
+}
+
+func NewReferralService(userRepo repository.UserRepository, actionRepo repository.ActionRepository, types *actiontypes.Registry) ReferralService {
+	return &referralService{
+		userRepo:   userRepo,
+		actionRepo: actionRepo,
+		types:      types,
+	}
+}
+
+// GetReferralStats describes the referral forest as a whole: its trees and
+// their shape, how users were acquired and the viral coefficient of each
+// monthly signup cohort. Only the top largest trees are listed.
+func (s *referralService) GetReferralStats(ctx context.Context, top int) (*models.ReferralStats, error) {
+	users, err := s.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := s.actionRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	graph := buildReferralGraph(actions, s.types)
+
+	referred := make(map[int]bool)
+	for _, targets := range graph {
+		for _, target := range targets {
+			referred[target] = true
+		}
+	}
+
+	roots := make([]int, 0)
+	for userID := range graph {
+		if !referred[userID] {
+			roots = append(roots, userID)
+		}
+	}
+	sort.Ints(roots)
+
+	trees := make([]models.ReferralTree, 0, len(roots))
+	depths := make(map[int]int)
+	visited := make(map[int]bool)
+	for _, root := range roots {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		tree := models.ReferralTree{RootID: root}
+
+		var walk func(userID, depth int)
+		walk = func(userID, depth int) {
+			if visited[userID] {
+				return
+			}
+			visited[userID] = true
+			tree.Size++
+			if depth > tree.Depth {
+				tree.Depth = depth
+			}
+			depths[depth]++
+			for _, target := range graph[userID] {
+				walk(target, depth+1)
+			}
+		}
+		walk(root, 0)
+		trees = append(trees, tree)
+	}
+
+	stats := &models.ReferralStats{
+		Trees:             len(trees),
+		LargestTrees:      largestTrees(trees, top),
+		DepthDistribution: depthDistribution(depths),
+		Acquisition:       acquisition(users, referred),
+		Cohorts:           cohortVirality(users, graph),
+	}
+	return stats, nil
+}
+
+// GetReferralNetwork returns the referral graph with user details on the
+// nodes and referral times on the edges. When root is set only the tree below
+// that user is returned, otherwise every tree is walked from its root. A
+// negative maxDepth means no depth limit. It returns apperrors.ErrNotFound if
+// root is set but is neither a known user nor part of any referral.
+func (s *referralService) GetReferralNetwork(ctx context.Context, root *int, maxDepth int) (*models.ReferralNetwork, error) {
+	users, err := s.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := s.actionRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	graph := buildReferralGraph(actions, s.types)
+	referredAt := buildReferralTimes(actions, s.types)
+
+	usersByID := make(map[int]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	var starts []int
+	if root != nil {
+		_, isUser := usersByID[*root]
+		_, isReferred := referredAt[*root]
+		if !isUser && !isReferred && len(graph[*root]) == 0 {
+			return nil, fmt.Errorf("user %d: %w", *root, apperrors.ErrNotFound)
+		}
+		starts = []int{*root}
+	} else {
+		for userID := range graph {
+			if _, isReferred := referredAt[userID]; !isReferred {
+				starts = append(starts, userID)
+			}
+		}
+	}
+
+	network := &models.ReferralNetwork{
+		Nodes: make([]models.ReferralNode, 0),
+		Edges: make([]models.ReferralEdge, 0),
+	}
+	visited := make(map[int]bool)
+	addNode := func(userID int) {
+		if visited[userID] {
+			return
+		}
+		visited[userID] = true
+		user := usersByID[userID]
+		network.Nodes = append(network.Nodes, models.ReferralNode{
+			ID:        userID,
+			Name:      user.Name,
+			CreatedAt: user.CreatedAt,
+		})
+	}
+
+	type queued struct {
+		userID int
+		depth  int
+	}
+	for _, start := range starts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if visited[start] {
+			continue
+		}
+		addNode(start)
+		queue := []queued{{userID: start}}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			if maxDepth >= 0 && current.depth >= maxDepth {
+				continue
+			}
+			for _, target := range graph[current.userID] {
+				network.Edges = append(network.Edges, models.ReferralEdge{
+					Source:     current.userID,
+					Target:     target,
+					ReferredAt: referredAt[target],
+				})
+				if !visited[target] {
+					addNode(target)
+					queue = append(queue, queued{userID: target, depth: current.depth + 1})
+				}
+			}
+		}
+	}
+
+	sort.Slice(network.Nodes, func(i, j int) bool {
+		return network.Nodes[i].ID < network.Nodes[j].ID
+	})
+	sort.Slice(network.Edges, func(i, j int) bool {
+		if network.Edges[i].Source != network.Edges[j].Source {
+			return network.Edges[i].Source < network.Edges[j].Source
+		}
+		return network.Edges[i].Target < network.Edges[j].Target
+	})
+	return network, nil
+}
+
+func largestTrees(trees []models.ReferralTree, top int) []models.ReferralTree {
+	sort.SliceStable(trees, func(i, j int) bool {
+		if trees[i].Size != trees[j].Size {
+			return trees[i].Size > trees[j].Size
+		}
+		return trees[i].Depth > trees[j].Depth
+	})
+	if top < len(trees) {
+		trees = trees[:top]
+	}
+	return trees
+}
+
+func depthDistribution(depths map[int]int) []models.DepthCount {
+	distribution := make([]models.DepthCount, 0, len(depths))
+	for depth, users := range depths {
+		distribution = append(distribution, models.DepthCount{Depth: depth, Users: users})
+	}
+	sort.Slice(distribution, func(i, j int) bool {
+		return distribution[i].Depth < distribution[j].Depth
+	})
+	return distribution
+}
+
+func acquisition(users []models.User, referred map[int]bool) models.Acquisition {
+	a := models.Acquisition{Users: len(users)}
+	for _, user := range users {
+		if referred[user.ID] {
+			a.Referred++
+		}
+	}
+	a.Organic = a.Users - a.Referred
+	if a.Users > 0 {
+		a.ReferredShare = roundRatio(a.Referred, a.Users)
+		a.OrganicShare = roundRatio(a.Organic, a.Users)
+	}
+	return a
+}
+
+// cohortVirality groups users by signup month. A cohort's K-factor is the
+// number of users its members referred divided by the size of the cohort;
+// every referral in the data is a completed signup, so the conversion rate
+// is one.
+func cohortVirality(users []models.User, graph ReferralGraph) []models.CohortVirality {
+	byCohort := make(map[string]*models.CohortVirality)
+	for _, user := range users {
+		cohort := user.CreatedAt.UTC().Format(cohortLayout)
+		c, found := byCohort[cohort]
+		if !found {
+			c = &models.CohortVirality{Cohort: cohort}
+			byCohort[cohort] = c
+		}
+		c.Users++
+		c.Referrals += len(graph[user.ID])
+	}
+
+	cohorts := make([]models.CohortVirality, 0, len(byCohort))
+	for _, c := range byCohort {
+		c.KFactor = roundRatio(c.Referrals, c.Users)
+		cohorts = append(cohorts, *c)
+	}
+	sort.Slice(cohorts, func(i, j int) bool {
+		return cohorts[i].Cohort < cohorts[j].Cohort
+	})
+	return cohorts
+}
+
+func roundRatio(n, d int) float64 {
+	return math.Round(float64(n)/float64(d)*100) / 100
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"surfe/internal/apperrors"
+	"surfe/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReferralStats(t *testing.T) {
+	march := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	april := time.Date(2024, 4, 2, 9, 0, 0, 0, time.UTC)
+	users := []models.User{
+		{ID: 1, Name: "Ann", CreatedAt: march},
+		{ID: 2, Name: "Bob", CreatedAt: march},
+		{ID: 3, Name: "Cid", CreatedAt: april},
+		{ID: 4, Name: "Dee", CreatedAt: april},
+		{ID: 5, Name: "Eve", CreatedAt: april},
+		{ID: 6, Name: "Fay", CreatedAt: april},
+	}
+	tests := []struct {
+		name          string
+		top           int
+		actions       []models.Action
+		expected      *models.ReferralStats
+		expectedError bool
+	}{
+		{
+			name: "two trees",
+			top:  1,
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(3), CreatedAt: april},
+				{ID: 2, Type: "REFER_USER", UserID: 3, TargetUser: models.UserRef(4), CreatedAt: april},
+				{ID: 3, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(5), CreatedAt: april},
+				{ID: 4, Type: "REFER_USER", UserID: 2, TargetUser: models.UserRef(6), CreatedAt: april},
+				{ID: 5, Type: "LOGIN", UserID: 6, CreatedAt: april},
+			},
+			expected: &models.ReferralStats{
+				Trees: 2,
+				LargestTrees: []models.ReferralTree{
+					{RootID: 1, Size: 4, Depth: 2},
+				},
+				DepthDistribution: []models.DepthCount{
+					{Depth: 0, Users: 2},
+					{Depth: 1, Users: 3},
+					{Depth: 2, Users: 1},
+				},
+				Acquisition: models.Acquisition{
+					Users:         6,
+					Referred:      4,
+					Organic:       2,
+					ReferredShare: 0.67,
+					OrganicShare:  0.33,
+				},
+				Cohorts: []models.CohortVirality{
+					{Cohort: "2024-03", Users: 2, Referrals: 3, KFactor: 1.5},
+					{Cohort: "2024-04", Users: 4, Referrals: 1, KFactor: 0.25},
+				},
+			},
+			expectedError: false,
+		},
+		{
+			name:    "no referrals",
+			top:     5,
+			actions: []models.Action{},
+			expected: &models.ReferralStats{
+				Trees:             0,
+				LargestTrees:      []models.ReferralTree{},
+				DepthDistribution: []models.DepthCount{},
+				Acquisition: models.Acquisition{
+					Users:        6,
+					Organic:      6,
+					OrganicShare: 1,
+				},
+				Cohorts: []models.CohortVirality{
+					{Cohort: "2024-03", Users: 2},
+					{Cohort: "2024-04", Users: 4},
+				},
+			},
+			expectedError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockActionRepo := new(MockActionRepository)
+			mockUserRepo.On("GetAll").Return(users, nil)
+			mockActionRepo.On("GetAll").Return(tt.actions, nil)
+
+			service := NewReferralService(mockUserRepo, mockActionRepo, newTestActionTypes())
+			result, err := service.GetReferralStats(context.Background(), tt.top)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			mockUserRepo.AssertExpectations(t)
+			mockActionRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetReferralNetwork(t *testing.T) {
+	created := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	referred := created.Add(time.Hour)
+	users := []models.User{
+		{ID: 1, Name: "Ann", CreatedAt: created},
+		{ID: 2, Name: "Bob", CreatedAt: created},
+		{ID: 3, Name: "Cid", CreatedAt: created},
+		{ID: 4, Name: "Dee", CreatedAt: created},
+		{ID: 5, Name: "Eve", CreatedAt: created},
+	}
+	actions := []models.Action{
+		{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: referred},
+		{ID: 2, Type: "REFER_USER", UserID: 2, TargetUser: models.UserRef(3), CreatedAt: referred},
+		{ID: 3, Type: "REFER_USER", UserID: 4, TargetUser: models.UserRef(5), CreatedAt: referred},
+		{ID: 4, Type: "LOGIN", UserID: 1, CreatedAt: referred},
+	}
+	root := 1
+	unknown := 99
+	tests := []struct {
+		name          string
+		root          *int
+		maxDepth      int
+		expected      *models.ReferralNetwork
+		expectedError bool
+	}{
+		{
+			name:     "whole forest",
+			root:     nil,
+			maxDepth: -1,
+			expected: &models.ReferralNetwork{
+				Nodes: []models.ReferralNode{
+					{ID: 1, Name: "Ann", CreatedAt: created},
+					{ID: 2, Name: "Bob", CreatedAt: created},
+					{ID: 3, Name: "Cid", CreatedAt: created},
+					{ID: 4, Name: "Dee", CreatedAt: created},
+					{ID: 5, Name: "Eve", CreatedAt: created},
+				},
+				Edges: []models.ReferralEdge{
+					{Source: 1, Target: 2, ReferredAt: referred},
+					{Source: 2, Target: 3, ReferredAt: referred},
+					{Source: 4, Target: 5, ReferredAt: referred},
+				},
+			},
+			expectedError: false,
+		},
+		{
+			name:     "rooted with depth limit",
+			root:     &root,
+			maxDepth: 1,
+			expected: &models.ReferralNetwork{
+				Nodes: []models.ReferralNode{
+					{ID: 1, Name: "Ann", CreatedAt: created},
+					{ID: 2, Name: "Bob", CreatedAt: created},
+				},
+				Edges: []models.ReferralEdge{
+					{Source: 1, Target: 2, ReferredAt: referred},
+				},
+			},
+			expectedError: false,
+		},
+		{
+			name:          "unknown root",
+			root:          &unknown,
+			maxDepth:      -1,
+			expected:      nil,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockActionRepo := new(MockActionRepository)
+			mockUserRepo.On("GetAll").Return(users, nil)
+			mockActionRepo.On("GetAll").Return(actions, nil)
+
+			service := NewReferralService(mockUserRepo, mockActionRepo, newTestActionTypes())
+			result, err := service.GetReferralNetwork(context.Background(), tt.root, tt.maxDepth)
+
+			if tt.expectedError {
+				assert.ErrorIs(t, err, apperrors.ErrNotFound)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			mockUserRepo.AssertExpectations(t)
+			mockActionRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReferralService_Canceled(t *testing.T) {
+	users := []models.User{{ID: 1, Name: "Ann"}, {ID: 2, Name: "Bob"}}
+	actions := []models.Action{{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2)}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockUserRepo := new(MockUserRepository)
+	mockActionRepo := new(MockActionRepository)
+	mockUserRepo.On("GetAll").Return(users, nil)
+	mockActionRepo.On("GetAll").Return(actions, nil)
+	service := NewReferralService(mockUserRepo, mockActionRepo, newTestActionTypes())
+
+	_, err := service.GetReferralStats(ctx, 5)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = service.GetReferralNetwork(ctx, nil, -1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -1,8 +1,11 @@
 package services
 
 import (
+	"context"
+	"sort"
 	"surfe/internal/models"
 	"surfe/internal/repository"
+	"time"
 )
 
 type userService struct {
@@ -17,15 +20,110 @@ func NewUserService(userRepo repository.UserRepository, actionRepo repository.Ac
 	}
 }
 
-func (s *userService) GetUserByID(id int) (*models.User, error) {
-	return s.userRepo.GetByID(id)
+func (s *userService) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	return s.userRepo.GetByID(ctx, id)
 }
 
-func (s *userService) GetUserActionCount(userID int) (int, error) {
-	actions, err := s.actionRepo.GetByUserID(userID)
+func (s *userService) GetUserActionCount(ctx context.Context, userID int) (int, error) {
+	actions, err := s.actionRepo.GetByUserID(ctx, userID)
 	if err != nil {
 		return 0, err
 	}
 	return len(actions), nil
 }
 
+func (s *userService) GetUserActionStats(ctx context.Context, userID int, from, to *time.Time) (*models.UserActionStats, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	actions, err := s.actionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.UserActionStats{UserID: userID, ActionStats: summarizeActions(actions)}
+	if from != nil || to != nil {
+		var inWindow []models.Action
+		for _, action := range actions {
+			if from != nil && action.CreatedAt.Before(*from) {
+				continue
+			}
+			if to != nil && !action.CreatedAt.Before(*to) {
+				continue
+			}
+			inWindow = append(inWindow, action)
+		}
+		stats.Window = &models.ActionWindow{From: from, To: to, ActionStats: summarizeActions(inWindow)}
+	}
+	return stats, nil
+}
+
+// summarizeActions counts actions by type and UTC date and finds the first
+// and last of them.
+func summarizeActions(actions []models.Action) models.ActionStats {
+	stats := models.ActionStats{Total: len(actions), ByType: []models.ActionTypeCount{}}
+	byType := make(map[string]int)
+	days := make(map[string]bool)
+	for _, action := range actions {
+		byType[action.Type]++
+		days[action.CreatedAt.UTC().Format(time.DateOnly)] = true
+		if stats.FirstActionAt == nil || action.CreatedAt.Before(*stats.FirstActionAt) {
+			createdAt := action.CreatedAt
+			stats.FirstActionAt = &createdAt
+		}
+		if stats.LastActionAt == nil || action.CreatedAt.After(*stats.LastActionAt) {
+			createdAt := action.CreatedAt
+			stats.LastActionAt = &createdAt
+		}
+	}
+	stats.ActiveDays = len(days)
+
+	for actionType, count := range byType {
+		stats.ByType = append(stats.ByType, models.ActionTypeCount{Type: actionType, Count: count})
+	}
+	sort.Slice(stats.ByType, func(i, j int) bool {
+		if stats.ByType[i].Count != stats.ByType[j].Count {
+			return stats.ByType[i].Count > stats.ByType[j].Count
+		}
+		return stats.ByType[i].Type < stats.ByType[j].Type
+	})
+	return stats
+}
+
+func (s *userService) GetUsers(ctx context.Context, ids []int) ([]models.UserBatchItem, error) {
+	unique := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	users, err := s.userRepo.GetByIDs(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+	found := make([]int, 0, len(users))
+	for _, id := range unique {
+		if _, ok := users[id]; ok {
+			found = append(found, id)
+		}
+	}
+	counts, err := s.actionRepo.CountByUserIDs(ctx, found)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.UserBatchItem, len(unique))
+	for i, id := range unique {
+		items[i] = models.UserBatchItem{ID: id}
+		if user, ok := users[id]; ok {
+			count := counts[id]
+			items[i].Found = true
+			items[i].User = &user
+			items[i].ActionCount = &count
+		}
+	}
+	return items, nil
+}
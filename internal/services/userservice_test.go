@@ -1,9 +1,12 @@
 package services
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"surfe/internal/apperrors"
 	"surfe/internal/models"
 
 	"github.com/stretchr/testify/assert"
@@ -15,7 +18,7 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) GetByID(id int) (*models.User, error) {
+func (m *MockUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -23,17 +26,46 @@ func (m *MockUserRepository) GetByID(id int) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetAll() ([]models.User, error) {
+func (m *MockUserRepository) GetByIDs(ctx context.Context, ids []int) (map[int]models.User, error) {
+	args := m.Called(ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int]models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetAll(ctx context.Context) ([]models.User, error) {
 	args := m.Called()
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) Delete(ctx context.Context, id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Load(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Status(ctx context.Context) models.DatasetStatus {
+	args := m.Called()
+	return args.Get(0).(models.DatasetStatus)
+}
+
+func (m *MockUserRepository) Flush(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func TestGetUserByID(t *testing.T) {
 	now := time.Now()
 	tests := []struct {
 		name          string
 		userID        int
 		mockUser      *models.User
+		mockError     error
 		expected      *models.User
 		expectedError bool
 	}{
@@ -56,8 +88,9 @@ func TestGetUserByID(t *testing.T) {
 			name:          "user not found",
 			userID:        999,
 			mockUser:      nil,
+			mockError:     fmt.Errorf("user 999: %w", apperrors.ErrNotFound),
 			expected:      nil,
-			expectedError: false,
+			expectedError: true,
 		},
 	}
 
@@ -65,13 +98,13 @@ func TestGetUserByID(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockUserRepo := new(MockUserRepository)
 			mockActionRepo := new(MockActionRepository)
-			mockUserRepo.On("GetByID", tt.userID).Return(tt.mockUser, nil)
+			mockUserRepo.On("GetByID", tt.userID).Return(tt.mockUser, tt.mockError)
 
 			service := NewUserService(mockUserRepo, mockActionRepo)
-			result, err := service.GetUserByID(tt.userID)
+			result, err := service.GetUserByID(context.Background(), tt.userID)
 
 			if tt.expectedError {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, tt.mockError)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expected, result)
@@ -117,7 +150,7 @@ func TestGetUserActionCount(t *testing.T) {
 			mockActionRepo.On("GetByUserID", tt.userID).Return(tt.mockActions, nil)
 
 			service := NewUserService(mockUserRepo, mockActionRepo)
-			result, err := service.GetUserActionCount(tt.userID)
+			result, err := service.GetUserActionCount(context.Background(), tt.userID)
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -129,3 +162,183 @@ func TestGetUserActionCount(t *testing.T) {
 		})
 	}
 }
+
+func TestGetUserActionStats(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC) }
+	ptr := func(t time.Time) *time.Time { return &t }
+	actions := []models.Action{
+		{ID: 1, Type: "LOGIN", UserID: 1, CreatedAt: at(2, 9)},
+		{ID: 2, Type: "VIEW_PROFILE", UserID: 1, CreatedAt: at(2, 10)},
+		{ID: 3, Type: "LOGIN", UserID: 1, CreatedAt: at(5, 9)},
+		{ID: 4, Type: "REFER_USER", UserID: 1, CreatedAt: at(1, 23)},
+		{ID: 5, Type: "LOGIN", UserID: 1, CreatedAt: at(9, 8)},
+	}
+	allTime := models.ActionStats{
+		Total: 5,
+		ByType: []models.ActionTypeCount{
+			{Type: "LOGIN", Count: 3},
+			{Type: "REFER_USER", Count: 1},
+			{Type: "VIEW_PROFILE", Count: 1},
+		},
+		FirstActionAt: ptr(at(1, 23)),
+		LastActionAt:  ptr(at(9, 8)),
+		ActiveDays:    4,
+	}
+
+	tests := []struct {
+		name          string
+		from          *time.Time
+		to            *time.Time
+		mockError     error
+		expected      *models.UserActionStats
+		expectedError error
+	}{
+		{
+			name:     "whole history",
+			expected: &models.UserActionStats{UserID: 1, ActionStats: allTime},
+		},
+		{
+			name: "window",
+			from: ptr(at(2, 0)),
+			to:   ptr(at(9, 8)),
+			expected: &models.UserActionStats{UserID: 1, ActionStats: allTime, Window: &models.ActionWindow{
+				From: ptr(at(2, 0)),
+				To:   ptr(at(9, 8)),
+				ActionStats: models.ActionStats{
+					Total:         3,
+					ByType:        []models.ActionTypeCount{{Type: "LOGIN", Count: 2}, {Type: "VIEW_PROFILE", Count: 1}},
+					FirstActionAt: ptr(at(2, 9)),
+					LastActionAt:  ptr(at(5, 9)),
+					ActiveDays:    2,
+				},
+			}},
+		},
+		{
+			name: "window without actions",
+			from: ptr(at(10, 0)),
+			expected: &models.UserActionStats{UserID: 1, ActionStats: allTime, Window: &models.ActionWindow{
+				From:        ptr(at(10, 0)),
+				ActionStats: models.ActionStats{ByType: []models.ActionTypeCount{}},
+			}},
+		},
+		{
+			name:          "user not found",
+			mockError:     fmt.Errorf("user 1: %w", apperrors.ErrNotFound),
+			expectedError: apperrors.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockActionRepo := new(MockActionRepository)
+			if tt.mockError != nil {
+				mockUserRepo.On("GetByID", 1).Return(nil, tt.mockError)
+			} else {
+				mockUserRepo.On("GetByID", 1).Return(&models.User{ID: 1}, nil)
+				mockActionRepo.On("GetByUserID", 1).Return(actions, nil)
+			}
+
+			service := NewUserService(mockUserRepo, mockActionRepo)
+			result, err := service.GetUserActionStats(context.Background(), 1, tt.from, tt.to)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			mockUserRepo.AssertExpectations(t)
+			mockActionRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	now := time.Now()
+	john := models.User{ID: 1, Name: "John Doe", CreatedAt: now}
+	jane := models.User{ID: 2, Name: "Jane Smith", CreatedAt: now}
+	three, zero := 3, 0
+
+	tests := []struct {
+		name          string
+		ids           []int
+		lookedUp      []int
+		mockUsers     map[int]models.User
+		mockUserError error
+		counted       []int
+		mockCounts    map[int]int
+		expected      []models.UserBatchItem
+		expectedError error
+	}{
+		{
+			name:       "found and not found in request order",
+			ids:        []int{2, 999, 1},
+			lookedUp:   []int{2, 999, 1},
+			mockUsers:  map[int]models.User{1: john, 2: jane},
+			counted:    []int{2, 1},
+			mockCounts: map[int]int{1: 3, 2: 0},
+			expected: []models.UserBatchItem{
+				{ID: 2, Found: true, User: &jane, ActionCount: &zero},
+				{ID: 999},
+				{ID: 1, Found: true, User: &john, ActionCount: &three},
+			},
+		},
+		{
+			name:       "duplicate IDs answered once",
+			ids:        []int{1, 1},
+			lookedUp:   []int{1},
+			mockUsers:  map[int]models.User{1: john},
+			counted:    []int{1},
+			mockCounts: map[int]int{1: 3},
+			expected: []models.UserBatchItem{
+				{ID: 1, Found: true, User: &john, ActionCount: &three},
+			},
+		},
+		{
+			name:       "negative ID not found",
+			ids:        []int{0, 1, -1},
+			lookedUp:   []int{0, 1, -1},
+			mockUsers:  map[int]models.User{1: john},
+			counted:    []int{1},
+			mockCounts: map[int]int{1: 3},
+			expected: []models.UserBatchItem{
+				{ID: 0},
+				{ID: 1, Found: true, User: &john, ActionCount: &three},
+				{ID: -1},
+			},
+		},
+		{
+			name:          "user repository error",
+			ids:           []int{1},
+			lookedUp:      []int{1},
+			mockUserError: fmt.Errorf("%w: users dataset not loaded", apperrors.ErrUnavailable),
+			expectedError: apperrors.ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := new(MockUserRepository)
+			mockActionRepo := new(MockActionRepository)
+			mockUserRepo.On("GetByIDs", tt.lookedUp).Return(tt.mockUsers, tt.mockUserError)
+			if tt.counted != nil {
+				mockActionRepo.On("CountByUserIDs", tt.counted).Return(tt.mockCounts, nil)
+			}
+
+			service := NewUserService(mockUserRepo, mockActionRepo)
+			result, err := service.GetUsers(context.Background(), tt.ids)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+			mockUserRepo.AssertExpectations(t)
+			mockActionRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"surfe/internal/models"
+	"surfe/internal/repository"
+)
+
+type versionService struct {
+	datasets []repository.Dataset
+}
+
+func NewVersionService(datasets ...repository.Dataset) VersionService {
+	return &versionService{
+		datasets: datasets,
+	}
+}
+
+// Version combines the version of every dataset into one tag. Dataset
+// versions restart from zero with the process, so the tag also covers each
+// load time to stay unique across restarts.
+func (s *versionService) Version(ctx context.Context) *models.DataVersion {
+	hash := sha256.New()
+	version := &models.DataVersion{}
+	for _, dataset := range s.datasets {
+		status := dataset.Status(ctx)
+		if !status.Loaded {
+			return nil
+		}
+		fmt.Fprintf(hash, "%s:%d:%d\n", status.Name, status.Version, status.LoadedAt.UnixNano())
+		if status.ModifiedAt.After(version.ModifiedAt) {
+			version.ModifiedAt = *status.ModifiedAt
+		}
+	}
+	version.Tag = hex.EncodeToString(hash.Sum(nil)[:16])
+	return version
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"surfe/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionService_Version(t *testing.T) {
+	loadedAt := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	modifiedAt := loadedAt.Add(time.Minute)
+	users := models.DatasetStatus{Name: "users", Loaded: true, LoadedAt: &loadedAt, ModifiedAt: &loadedAt, Version: 1}
+	actions := models.DatasetStatus{Name: "actions", Loaded: true, LoadedAt: &loadedAt, ModifiedAt: &loadedAt, Version: 1}
+	modified := models.DatasetStatus{Name: "actions", Loaded: true, LoadedAt: &loadedAt, ModifiedAt: &modifiedAt, Version: 2}
+	reloadedAt := loadedAt.Add(time.Hour)
+	restarted := models.DatasetStatus{Name: "actions", Loaded: true, LoadedAt: &reloadedAt, ModifiedAt: &reloadedAt, Version: 1}
+	missing := models.DatasetStatus{Name: "actions", Error: "open actions.json: no such file or directory"}
+
+	version := func(actionStatus models.DatasetStatus) *models.DataVersion {
+		userRepo := new(MockUserRepository)
+		actionRepo := new(MockActionRepository)
+		userRepo.On("Status").Return(users)
+		actionRepo.On("Status").Return(actionStatus)
+		return NewVersionService(userRepo, actionRepo).Version(context.Background())
+	}
+
+	initial := version(actions)
+	require.NotNil(t, initial)
+	assert.Len(t, initial.Tag, 32)
+	assert.Equal(t, loadedAt, initial.ModifiedAt)
+	assert.Equal(t, initial, version(actions))
+
+	changed := version(modified)
+	require.NotNil(t, changed)
+	assert.NotEqual(t, initial.Tag, changed.Tag)
+	assert.Equal(t, modifiedAt, changed.ModifiedAt)
+
+	assert.NotEqual(t, initial.Tag, version(restarted).Tag)
+	assert.Nil(t, version(missing))
+}
@@ -0,0 +1,75 @@
+// Package tracing records OpenTelemetry spans for the service and repository
+// layers. Like the metrics package, it instruments them by wrapping their
+// interfaces; HTTP requests are traced by the otelecho middleware.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"surfe/internal/config"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// ServiceName identifies surfe in exported spans.
+const ServiceName = "surfe"
+
+// NewProvider creates a tracer provider that batches spans to the configured
+// exporter. The caller must shut it down to flush pending spans. It must not
+// be called with the none exporter.
+func NewProvider(ctx context.Context, cfg config.TracingConfig) (*sdktrace.TracerProvider, error) {
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s trace exporter: %v", cfg.Exporter, err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	), nil
+}
+
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case config.TracingOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case config.TracingStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case config.TracingFile:
+		file, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &fileExporter{SpanExporter: exporter, file: file}, nil
+	default:
+		return nil, fmt.Errorf("unknown exporter")
+	}
+}
+
+// fileExporter closes the file once the exporter has written its last span.
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *os.File
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"surfe/internal/models"
+	"surfe/internal/repository"
+)
+
+// actionRepository traces the queries of an action repository. Load, Status
+// and Flush are passed through to the embedded repository.
+type actionRepository struct {
+	repository.ActionRepository
+	tracer *Tracer
+}
+
+// ActionRepository traces every query to an action repository.
+func (t *Tracer) ActionRepository(next repository.ActionRepository) repository.ActionRepository {
+	return &actionRepository{ActionRepository: next, tracer: t}
+}
+
+func (r *actionRepository) GetByUserID(ctx context.Context, userID int) (actions []models.Action, err error) {
+	ctx, span := r.tracer.start(ctx, "ActionRepository.GetByUserID", UserIDKey.Int(userID))
+	defer func() { end(span, err) }()
+
+	actions, err = r.ActionRepository.GetByUserID(ctx, userID)
+	span.SetAttributes(RecordsKey.Int(len(actions)))
+	return actions, err
+}
+
+func (r *actionRepository) CountByUserIDs(ctx context.Context, userIDs []int) (counts map[int]int, err error) {
+	ctx, span := r.tracer.start(ctx, "ActionRepository.CountByUserIDs", UserIDsKey.Int(len(userIDs)))
+	defer func() { end(span, err) }()
+
+	counts, err = r.ActionRepository.CountByUserIDs(ctx, userIDs)
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	span.SetAttributes(RecordsKey.Int(total))
+	return counts, err
+}
+
+func (r *actionRepository) GetAll(ctx context.Context) (actions []models.Action, err error) {
+	ctx, span := r.tracer.start(ctx, "ActionRepository.GetAll")
+	defer func() { end(span, err) }()
+
+	actions, err = r.ActionRepository.GetAll(ctx)
+	span.SetAttributes(RecordsKey.Int(len(actions)))
+	return actions, err
+}
+
+func (r *actionRepository) GetNextActions(ctx context.Context, actionType string) (counts map[string]int, total int, err error) {
+	ctx, span := r.tracer.start(ctx, "ActionRepository.GetNextActions", ActionTypeKey.String(actionType))
+	defer func() { end(span, err) }()
+
+	counts, total, err = r.ActionRepository.GetNextActions(ctx, actionType)
+	span.SetAttributes(RecordsKey.Int(total))
+	return counts, total, err
+}
+
+func (r *actionRepository) GetReferrals(ctx context.Context) (referrals map[int][]int, err error) {
+	ctx, span := r.tracer.start(ctx, "ActionRepository.GetReferrals")
+	defer func() { end(span, err) }()
+
+	referrals, err = r.ActionRepository.GetReferrals(ctx)
+	span.SetAttributes(RecordsKey.Int(len(referrals)))
+	return referrals, err
+}
+
+func (r *actionRepository) Add(ctx context.Context, action models.Action) (_ models.Action, err error) {
+	ctx, span := r.tracer.start(ctx, "ActionRepository.Add",
+		ActionTypeKey.String(action.Type), UserIDKey.Int(action.UserID))
+	defer func() { end(span, err) }()
+
+	return r.ActionRepository.Add(ctx, action)
+}
+
+func (r *actionRepository) DeleteByUserID(ctx context.Context, userID int) (deleted, anonymised int, err error) {
+	ctx, span := r.tracer.start(ctx, "ActionRepository.DeleteByUserID", UserIDKey.Int(userID))
+	defer func() { end(span, err) }()
+
+	deleted, anonymised, err = r.ActionRepository.DeleteByUserID(ctx, userID)
+	span.SetAttributes(RecordsKey.Int(deleted + anonymised))
+	return deleted, anonymised, err
+}
+
+type userRepository struct {
+	repository.UserRepository
+	tracer *Tracer
+}
+
+// UserRepository traces every query to a user repository.
+func (t *Tracer) UserRepository(next repository.UserRepository) repository.UserRepository {
+	return &userRepository{UserRepository: next, tracer: t}
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (_ *models.User, err error) {
+	ctx, span := r.tracer.start(ctx, "UserRepository.GetByID", UserIDKey.Int(id))
+	defer func() { end(span, err) }()
+
+	return r.UserRepository.GetByID(ctx, id)
+}
+
+func (r *userRepository) GetByIDs(ctx context.Context, ids []int) (users map[int]models.User, err error) {
+	ctx, span := r.tracer.start(ctx, "UserRepository.GetByIDs", UserIDsKey.Int(len(ids)))
+	defer func() { end(span, err) }()
+
+	users, err = r.UserRepository.GetByIDs(ctx, ids)
+	span.SetAttributes(RecordsKey.Int(len(users)))
+	return users, err
+}
+
+func (r *userRepository) GetAll(ctx context.Context) (users []models.User, err error) {
+	ctx, span := r.tracer.start(ctx, "UserRepository.GetAll")
+	defer func() { end(span, err) }()
+
+	users, err = r.UserRepository.GetAll(ctx)
+	span.SetAttributes(RecordsKey.Int(len(users)))
+	return users, err
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, span := r.tracer.start(ctx, "UserRepository.Delete", UserIDKey.Int(id))
+	defer func() { end(span, err) }()
+
+	return r.UserRepository.Delete(ctx, id)
+}
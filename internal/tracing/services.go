@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"context"
+	"surfe/internal/models"
+	"surfe/internal/services"
+	"time"
+)
+
+type actionService struct {
+	next   services.ActionService
+	tracer *Tracer
+}
+
+// ActionService traces every call to an action service.
+func (t *Tracer) ActionService(next services.ActionService) services.ActionService {
+	return &actionService{next: next, tracer: t}
+}
+
+func (s *actionService) GetActionTypes(ctx context.Context) (types []models.ActionType, err error) {
+	ctx, span := s.tracer.start(ctx, "ActionService.GetActionTypes")
+	defer func() { end(span, err) }()
+
+	types, err = s.next.GetActionTypes(ctx)
+	span.SetAttributes(RecordsKey.Int(len(types)))
+	return types, err
+}
+
+func (s *actionService) GetActionType(ctx context.Context, name string) (_ *models.ActionType, err error) {
+	ctx, span := s.tracer.start(ctx, "ActionService.GetActionType", ActionTypeKey.String(name))
+	defer func() { end(span, err) }()
+
+	return s.next.GetActionType(ctx, name)
+}
+
+func (s *actionService) GetNextActionProbabilities(ctx context.Context, actionType string) (probabilities map[string]float64, err error) {
+	ctx, span := s.tracer.start(ctx, "ActionService.GetNextActionProbabilities", ActionTypeKey.String(actionType))
+	defer func() { end(span, err) }()
+
+	probabilities, err = s.next.GetNextActionProbabilities(ctx, actionType)
+	span.SetAttributes(RecordsKey.Int(len(probabilities)))
+	return probabilities, err
+}
+
+func (s *actionService) GetNextActions(ctx context.Context, actionType string) (nextActions *models.NextActions, err error) {
+	ctx, span := s.tracer.start(ctx, "ActionService.GetNextActions", ActionTypeKey.String(actionType))
+	defer func() { end(span, err) }()
+
+	nextActions, err = s.next.GetNextActions(ctx, actionType)
+	if nextActions != nil {
+		span.SetAttributes(RecordsKey.Int(len(nextActions.Next)))
+	}
+	return nextActions, err
+}
+
+func (s *actionService) GetReferralIndex(ctx context.Context) (index map[int]int, err error) {
+	ctx, span := s.tracer.start(ctx, "ActionService.GetReferralIndex")
+	defer func() { end(span, err) }()
+
+	index, err = s.next.GetReferralIndex(ctx)
+	span.SetAttributes(RecordsKey.Int(len(index)))
+	return index, err
+}
+
+func (s *actionService) GetReferralQuality(ctx context.Context, activationTypes []string) (quality []models.ReferralQuality, err error) {
+	ctx, span := s.tracer.start(ctx, "ActionService.GetReferralQuality", ActionTypeKey.StringSlice(activationTypes))
+	defer func() { end(span, err) }()
+
+	quality, err = s.next.GetReferralQuality(ctx, activationTypes)
+	span.SetAttributes(RecordsKey.Int(len(quality)))
+	return quality, err
+}
+
+func (s *actionService) RecordAction(ctx context.Context, action models.Action) (_ models.Action, err error) {
+	ctx, span := s.tracer.start(ctx, "ActionService.RecordAction",
+		ActionTypeKey.String(action.Type), UserIDKey.Int(action.UserID))
+	defer func() { end(span, err) }()
+
+	return s.next.RecordAction(ctx, action)
+}
+
+type userService struct {
+	next   services.UserService
+	tracer *Tracer
+}
+
+// UserService traces every call to a user service.
+func (t *Tracer) UserService(next services.UserService) services.UserService {
+	return &userService{next: next, tracer: t}
+}
+
+func (s *userService) GetUserByID(ctx context.Context, id int) (_ *models.User, err error) {
+	ctx, span := s.tracer.start(ctx, "UserService.GetUserByID", UserIDKey.Int(id))
+	defer func() { end(span, err) }()
+
+	return s.next.GetUserByID(ctx, id)
+}
+
+func (s *userService) GetUserActionCount(ctx context.Context, userID int) (_ int, err error) {
+	ctx, span := s.tracer.start(ctx, "UserService.GetUserActionCount", UserIDKey.Int(userID))
+	defer func() { end(span, err) }()
+
+	return s.next.GetUserActionCount(ctx, userID)
+}
+
+func (s *userService) GetUserActionStats(ctx context.Context, userID int, from, to *time.Time) (_ *models.UserActionStats, err error) {
+	ctx, span := s.tracer.start(ctx, "UserService.GetUserActionStats", UserIDKey.Int(userID))
+	defer func() { end(span, err) }()
+
+	return s.next.GetUserActionStats(ctx, userID, from, to)
+}
+
+func (s *userService) GetUsers(ctx context.Context, ids []int) (_ []models.UserBatchItem, err error) {
+	ctx, span := s.tracer.start(ctx, "UserService.GetUsers", UserIDsKey.Int(len(ids)))
+	defer func() { end(span, err) }()
+
+	return s.next.GetUsers(ctx, ids)
+}
+
+type erasureService struct {
+	next   services.ErasureService
+	tracer *Tracer
+}
+
+// ErasureService traces every call to an erasure service.
+func (t *Tracer) ErasureService(next services.ErasureService) services.ErasureService {
+	return &erasureService{next: next, tracer: t}
+}
+
+func (s *erasureService) EraseUser(ctx context.Context, userID int) (_ *models.Erasure, err error) {
+	ctx, span := s.tracer.start(ctx, "ErasureService.EraseUser", UserIDKey.Int(userID))
+	defer func() { end(span, err) }()
+
+	return s.next.EraseUser(ctx, userID)
+}
+
+type referralService struct {
+	next   services.ReferralService
+	tracer *Tracer
+}
+
+// ReferralService traces every call to a referral service.
+func (t *Tracer) ReferralService(next services.ReferralService) services.ReferralService {
+	return &referralService{next: next, tracer: t}
+}
+
+func (s *referralService) GetReferralStats(ctx context.Context, top int) (_ *models.ReferralStats, err error) {
+	ctx, span := s.tracer.start(ctx, "ReferralService.GetReferralStats")
+	defer func() { end(span, err) }()
+
+	return s.next.GetReferralStats(ctx, top)
+}
+
+func (s *referralService) GetReferralNetwork(ctx context.Context, root *int, maxDepth int) (network *models.ReferralNetwork, err error) {
+	ctx, span := s.tracer.start(ctx, "ReferralService.GetReferralNetwork")
+	if root != nil {
+		span.SetAttributes(UserIDKey.Int(*root))
+	}
+	defer func() { end(span, err) }()
+
+	network, err = s.next.GetReferralNetwork(ctx, root, maxDepth)
+	if network != nil {
+		span.SetAttributes(RecordsKey.Int(len(network.Nodes)))
+	}
+	return network, err
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes shared by the instrumented layers.
+const (
+	ActionTypeKey = attribute.Key("surfe.action_type")
+	UserIDKey     = attribute.Key("surfe.user_id")
+	RecordsKey    = attribute.Key("surfe.records")
+	// UserIDsKey counts the users a batch call asks for.
+	UserIDsKey = attribute.Key("surfe.user_ids")
+)
+
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New returns a Tracer that starts spans from provider.
+func New(provider trace.TracerProvider) *Tracer {
+	return &Tracer{
+		tracer: provider.Tracer("surfe/internal/tracing"),
+	}
+}
+
+func (t *Tracer) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// end records err on span, if any, and ends it.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
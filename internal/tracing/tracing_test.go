@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"surfe/internal/actiontypes"
+	"surfe/internal/apperrors"
+	"surfe/internal/config"
+	"surfe/internal/repository"
+	"surfe/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setupRepositories(t *testing.T) (repository.UserRepository, repository.ActionRepository) {
+	dir := t.TempDir()
+	usersPath := filepath.Join(dir, "users.json")
+	actionsPath := filepath.Join(dir, "actions.json")
+	if err := os.WriteFile(usersPath, []byte(`[
+		{"id": 1, "name": "John Doe", "createdAt": "2024-03-11T20:00:00Z"},
+		{"id": 2, "name": "Jane Smith", "createdAt": "2024-03-11T20:00:00Z"}
+	]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(actionsPath, []byte(`[
+		{"id": 1, "type": "WELCOME", "userId": 1, "createdAt": "2024-03-11T20:00:00Z"},
+		{"id": 2, "type": "REFER_USER", "userId": 1, "targetUser": 2, "createdAt": "2024-03-11T20:01:00Z"},
+		{"id": 3, "type": "WELCOME", "userId": 2, "createdAt": "2024-03-11T20:02:00Z"}
+	]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo, err := repository.NewUserRepository(usersPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actionRepo, err := repository.NewActionRepository(actionsPath, actiontypes.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userRepo, actionRepo
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracer_NestsServiceAndRepositorySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	userRepo, actionRepo := setupRepositories(t)
+	actionRepo = tracer.ActionRepository(actionRepo)
+	userService := tracer.UserService(services.NewUserService(tracer.UserRepository(userRepo), actionRepo))
+
+	count, err := userService.GetUserActionCount(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		repoSpan, serviceSpan := spans[0], spans[1]
+		assert.Equal(t, "ActionRepository.GetByUserID", repoSpan.Name())
+		assert.Equal(t, "UserService.GetUserActionCount", serviceSpan.Name())
+		assert.Equal(t, serviceSpan.SpanContext().SpanID(), repoSpan.Parent().SpanID())
+
+		attrs := spanAttributes(repoSpan)
+		assert.Equal(t, int64(1), attrs[UserIDKey].AsInt64())
+		assert.Equal(t, int64(2), attrs[RecordsKey].AsInt64())
+		assert.Equal(t, int64(3), attrs["surfe.records_scanned"].AsInt64())
+		assert.Equal(t, int64(1), spanAttributes(serviceSpan)[UserIDKey].AsInt64())
+	}
+}
+
+func TestTracer_RecordsErrors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	userRepo, actionRepo := setupRepositories(t)
+	actionService := tracer.ActionService(services.NewActionService(userRepo, actionRepo, actiontypes.Default(), new(sync.RWMutex)))
+
+	_, err := actionService.GetNextActionProbabilities(context.Background(), "UNKNOWN")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "ActionService.GetNextActionProbabilities", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, "UNKNOWN", spanAttributes(spans[0])[ActionTypeKey].AsString())
+	}
+}
+
+func TestTracer_MarksReferralIndexBuild(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	userRepo, actionRepo := setupRepositories(t)
+	actionService := tracer.ActionService(services.NewActionService(userRepo, actionRepo, actiontypes.Default(), new(sync.RWMutex)))
+
+	for i := 0; i < 2; i++ {
+		index, err := actionService.GetReferralIndex(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, map[int]int{1: 1, 2: 0}, index)
+	}
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Len(t, spans[0].Events(), 1)
+		assert.Equal(t, "referral index built", spans[0].Events()[0].Name)
+		assert.Empty(t, spans[1].Events())
+	}
+}
+
+func TestNewProvider_File(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "traces.jsonl")
+	provider, err := NewProvider(context.Background(), config.TracingConfig{
+		Exporter: config.TracingFile,
+		FilePath: filePath,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, span := New(provider).start(context.Background(), "test")
+	span.End()
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"test"`)
+	assert.Contains(t, string(data), `"Value":"surfe"`)
+}
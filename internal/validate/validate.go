@@ -0,0 +1,195 @@
+// Package validate checks users and actions data for records that are
+// inconsistent with each other, so bad exports can be rejected before the
+// API serves them.
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"surfe/internal/actiontypes"
+	"surfe/internal/models"
+	"time"
+)
+
+type Severity string
+
+const (
+	// SeverityError marks data the API would serve wrong answers from.
+	SeverityError Severity = "error"
+	// SeverityWarning marks data that is served but probably unintended.
+	SeverityWarning Severity = "warning"
+)
+
+// Dataset names.
+const (
+	DatasetUsers   = "users"
+	DatasetActions = "actions"
+)
+
+// Rules.
+const (
+	RuleDuplicateID       = "duplicate-id"
+	RuleFutureTimestamp   = "future-timestamp"
+	RuleUnknownUser       = "unknown-user"
+	RuleMissingTarget     = "missing-target"
+	RuleUnknownTarget     = "unknown-target"
+	RuleUnexpectedTarget  = "unexpected-target"
+	RuleBeforeUserCreated = "before-user-created"
+	RuleUnknownActionType = "unknown-action-type"
+)
+
+// Finding is a problem with one record.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Dataset  string   `json:"dataset"`
+	RecordID int      `json:"recordId"`
+	Message  string   `json:"message"`
+}
+
+type Report struct {
+	Users    int       `json:"users"`
+	Actions  int       `json:"actions"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+	Findings []Finding `json:"findings"`
+}
+
+// OK reports whether the data has no errors. Warnings are allowed.
+func (r *Report) OK() bool {
+	return r.Errors == 0
+}
+
+func (r *Report) add(severity Severity, rule, dataset string, recordID int, format string, args ...any) {
+	r.Findings = append(r.Findings, Finding{
+		Severity: severity,
+		Rule:     rule,
+		Dataset:  dataset,
+		RecordID: recordID,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	if severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// Files reads the users and actions files and checks them with Dataset. It
+// only returns an error if a file cannot be read or is not valid JSON.
+func Files(usersPath, actionsPath string, types *actiontypes.Registry, now time.Time) (*Report, error) {
+	var users []models.User
+	if err := readJSON(usersPath, &users); err != nil {
+		return nil, err
+	}
+	var actions []models.Action
+	if err := readJSON(actionsPath, &actions); err != nil {
+		return nil, err
+	}
+	return Dataset(users, actions, types, now), nil
+}
+
+func readJSON(filePath string, v any) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON data in %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// Dataset checks users and actions against each other. Action types that
+// refer to another user are taken from types; timestamps after now are
+// reported as being in the future. Findings are ordered by dataset, users
+// first, then by record ID.
+func Dataset(users []models.User, actions []models.Action, types *actiontypes.Registry, now time.Time) *Report {
+	report := &Report{
+		Users:    len(users),
+		Actions:  len(actions),
+		Findings: make([]Finding, 0),
+	}
+
+	// The first record with an ID is the one the others are checked
+	// against, as duplicates are reported on their own.
+	usersByID := make(map[int]models.User, len(users))
+	for _, user := range users {
+		if _, found := usersByID[user.ID]; found {
+			report.add(SeverityError, RuleDuplicateID, DatasetUsers, user.ID, "user ID %d is used more than once", user.ID)
+			continue
+		}
+		usersByID[user.ID] = user
+		if user.CreatedAt.After(now) {
+			report.add(SeverityError, RuleFutureTimestamp, DatasetUsers, user.ID, "createdAt %s is in the future", formatTime(user.CreatedAt))
+		}
+	}
+
+	seen := make(map[int]bool, len(actions))
+	for _, action := range actions {
+		if seen[action.ID] {
+			report.add(SeverityError, RuleDuplicateID, DatasetActions, action.ID, "action ID %d is used more than once", action.ID)
+			continue
+		}
+		seen[action.ID] = true
+		checkAction(report, action, usersByID, types, now)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Dataset != b.Dataset {
+			return a.Dataset == DatasetUsers
+		}
+		return a.RecordID < b.RecordID
+	})
+	return report
+}
+
+// Action checks a single action the way Dataset checks each of its actions,
+// so new actions can be held to the same rules. usersByID needs only the
+// action's user and target user.
+func Action(action models.Action, usersByID map[int]models.User, types *actiontypes.Registry, now time.Time) []Finding {
+	report := &Report{Actions: 1, Findings: make([]Finding, 0)}
+	checkAction(report, action, usersByID, types, now)
+	return report.Findings
+}
+
+func checkAction(report *Report, action models.Action, usersByID map[int]models.User, types *actiontypes.Registry, now time.Time) {
+	if _, found := types.Lookup(action.Type); !found {
+		report.add(SeverityWarning, RuleUnknownActionType, DatasetActions, action.ID, "action type %q is not a known action type", action.Type)
+	}
+	if action.CreatedAt.After(now) {
+		report.add(SeverityError, RuleFutureTimestamp, DatasetActions, action.ID, "createdAt %s is in the future", formatTime(action.CreatedAt))
+	}
+
+	// Erased users leave referrals behind under anonymous placeholder IDs,
+	// which have no user record.
+	user, found := usersByID[action.UserID]
+	switch {
+	case !found && !models.IsAnonymous(action.UserID):
+		report.add(SeverityError, RuleUnknownUser, DatasetActions, action.ID, "userId %d is not a known user", action.UserID)
+	case found && action.CreatedAt.Before(user.CreatedAt):
+		report.add(SeverityError, RuleBeforeUserCreated, DatasetActions, action.ID,
+			"createdAt %s is before user %d was created at %s", formatTime(action.CreatedAt), user.ID, formatTime(user.CreatedAt))
+	}
+
+	target, hasTarget := action.Target()
+	switch {
+	case types.TargetsUser(action.Type) && !hasTarget:
+		report.add(SeverityError, RuleMissingTarget, DatasetActions, action.ID, "%s action has no targetUser", action.Type)
+	case types.TargetsUser(action.Type):
+		if _, found := usersByID[target]; !found && !models.IsAnonymous(target) {
+			report.add(SeverityError, RuleUnknownTarget, DatasetActions, action.ID, "targetUser %d is not a known user", target)
+		}
+	case hasTarget:
+		report.add(SeverityError, RuleUnexpectedTarget, DatasetActions, action.ID, "targetUser %d is set on a %s action", target, action.Type)
+	}
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,175 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"surfe/internal/actiontypes"
+	"surfe/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	now      = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	jan1     = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jan2     = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	tomorrow = now.Add(24 * time.Hour)
+)
+
+var testUsers = []models.User{
+	{ID: 1, Name: "Ada", CreatedAt: jan1},
+	{ID: 2, Name: "Grace", CreatedAt: jan2},
+}
+
+func TestDataset(t *testing.T) {
+	tests := []struct {
+		name             string
+		users            []models.User
+		actions          []models.Action
+		expectedFindings []Finding
+	}{
+		{
+			name:  "valid",
+			users: testUsers,
+			actions: []models.Action{
+				{ID: 1, Type: "WELCOME", UserID: 1, CreatedAt: jan1},
+				{ID: 2, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: jan2},
+			},
+			expectedFindings: []Finding{},
+		},
+		{
+			name:  "duplicate IDs",
+			users: append(testUsers, models.User{ID: 2, Name: "Linus", CreatedAt: jan1}),
+			actions: []models.Action{
+				{ID: 1, Type: "WELCOME", UserID: 1, CreatedAt: jan1},
+				{ID: 1, Type: "WELCOME", UserID: 2, CreatedAt: jan2},
+			},
+			expectedFindings: []Finding{
+				{SeverityError, RuleDuplicateID, DatasetUsers, 2, "user ID 2 is used more than once"},
+				{SeverityError, RuleDuplicateID, DatasetActions, 1, "action ID 1 is used more than once"},
+			},
+		},
+		{
+			name:  "unknown user",
+			users: testUsers,
+			actions: []models.Action{
+				{ID: 1, Type: "WELCOME", UserID: 9, CreatedAt: jan1},
+			},
+			expectedFindings: []Finding{
+				{SeverityError, RuleUnknownUser, DatasetActions, 1, "userId 9 is not a known user"},
+			},
+		},
+		{
+			name:  "referral targets",
+			users: testUsers,
+			actions: []models.Action{
+				{ID: 3, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(9), CreatedAt: jan2},
+				{ID: 1, Type: "REFER_USER", UserID: 1, CreatedAt: jan2},
+				{ID: 2, Type: "ADD_CONTACT", UserID: 1, TargetUser: models.UserRef(2), CreatedAt: jan2},
+			},
+			expectedFindings: []Finding{
+				{SeverityError, RuleMissingTarget, DatasetActions, 1, "REFER_USER action has no targetUser"},
+				{SeverityError, RuleUnexpectedTarget, DatasetActions, 2, "targetUser 2 is set on a ADD_CONTACT action"},
+				{SeverityError, RuleUnknownTarget, DatasetActions, 3, "targetUser 9 is not a known user"},
+			},
+		},
+		{
+			name:  "user 0",
+			users: append(testUsers, models.User{ID: 0, Name: "Alan", CreatedAt: jan1}),
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(0), CreatedAt: jan2},
+				{ID: 2, Type: "WELCOME", UserID: 0, TargetUser: models.UserRef(0), CreatedAt: jan2},
+			},
+			expectedFindings: []Finding{
+				{SeverityError, RuleUnexpectedTarget, DatasetActions, 2, "targetUser 0 is set on a WELCOME action"},
+			},
+		},
+		{
+			name:  "erased users",
+			users: testUsers,
+			actions: []models.Action{
+				{ID: 1, Type: "REFER_USER", UserID: 1, TargetUser: models.UserRef(-1), CreatedAt: jan2},
+				{ID: 2, Type: "REFER_USER", UserID: -1, TargetUser: models.UserRef(2), CreatedAt: jan2},
+			},
+			expectedFindings: []Finding{},
+		},
+		{
+			name:  "timestamps",
+			users: []models.User{{ID: 1, Name: "Ada", CreatedAt: tomorrow}, {ID: 2, Name: "Grace", CreatedAt: jan2}},
+			actions: []models.Action{
+				{ID: 1, Type: "WELCOME", UserID: 2, CreatedAt: jan1},
+				{ID: 2, Type: "WELCOME", UserID: 2, CreatedAt: tomorrow},
+			},
+			expectedFindings: []Finding{
+				{SeverityError, RuleFutureTimestamp, DatasetUsers, 1, "createdAt 2024-06-02T00:00:00Z is in the future"},
+				{SeverityError, RuleBeforeUserCreated, DatasetActions, 1, "createdAt 2024-01-01T00:00:00Z is before user 2 was created at 2024-01-02T00:00:00Z"},
+				{SeverityError, RuleFutureTimestamp, DatasetActions, 2, "createdAt 2024-06-02T00:00:00Z is in the future"},
+			},
+		},
+		{
+			name:  "unknown action type",
+			users: testUsers,
+			actions: []models.Action{
+				{ID: 1, Type: "JUMP", UserID: 1, CreatedAt: jan1},
+			},
+			expectedFindings: []Finding{
+				{SeverityWarning, RuleUnknownActionType, DatasetActions, 1, `action type "JUMP" is not a known action type`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Dataset(tt.users, tt.actions, actiontypes.Default(), now)
+
+			assert.Equal(t, tt.expectedFindings, report.Findings)
+			assert.Equal(t, len(tt.users), report.Users)
+			assert.Equal(t, len(tt.actions), report.Actions)
+			errors := 0
+			for _, finding := range tt.expectedFindings {
+				if finding.Severity == SeverityError {
+					errors++
+				}
+			}
+			assert.Equal(t, errors, report.Errors)
+			assert.Equal(t, len(tt.expectedFindings)-errors, report.Warnings)
+			assert.Equal(t, errors == 0, report.OK())
+		})
+	}
+}
+
+func TestAction(t *testing.T) {
+	usersByID := map[int]models.User{1: testUsers[0]}
+
+	findings := Action(models.Action{Type: "REFER_USER", UserID: 1, CreatedAt: tomorrow}, usersByID, actiontypes.Default(), now)
+	assert.Equal(t, []Finding{
+		{SeverityError, RuleFutureTimestamp, DatasetActions, 0, "createdAt 2024-06-02T00:00:00Z is in the future"},
+		{SeverityError, RuleMissingTarget, DatasetActions, 0, "REFER_USER action has no targetUser"},
+	}, findings)
+
+	findings = Action(models.Action{Type: "WELCOME", UserID: 1, CreatedAt: jan2}, usersByID, actiontypes.Default(), now)
+	assert.Empty(t, findings)
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	usersPath := filepath.Join(dir, "users.json")
+	actionsPath := filepath.Join(dir, "actions.json")
+	require.NoError(t, os.WriteFile(usersPath, []byte(`[{"id": 1, "name": "Ada", "createdAt": "2024-01-01T00:00:00Z"}]`), 0644))
+	require.NoError(t, os.WriteFile(actionsPath, []byte(`[{"id": 1, "type": "WELCOME", "userId": 2, "createdAt": "2024-01-01T00:00:00Z"}]`), 0644))
+
+	report, err := Files(usersPath, actionsPath, actiontypes.Default(), now)
+	require.NoError(t, err)
+	assert.Equal(t, []Finding{{SeverityError, RuleUnknownUser, DatasetActions, 1, "userId 2 is not a known user"}}, report.Findings)
+
+	require.NoError(t, os.WriteFile(actionsPath, []byte(`{"id": 1}`), 0644))
+	_, err = Files(usersPath, actionsPath, actiontypes.Default(), now)
+	assert.ErrorContains(t, err, "invalid JSON data in "+actionsPath)
+
+	_, err = Files(filepath.Join(dir, "missing.json"), actionsPath, actiontypes.Default(), now)
+	assert.ErrorContains(t, err, "missing.json")
+}
@@ -0,0 +1,280 @@
+package workspace
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"surfe/internal/apperrors"
+	"surfe/internal/logging"
+	"sync"
+	"time"
+)
+
+// Builder opens a workspace's repositories and loads them, and assembles
+// the handler serving them. It fills in every exported field of Workspace
+// except ID and Config.
+type Builder func(ctx context.Context, id string, cfg *Config) (*Workspace, error)
+
+// Options configure a Registry.
+type Options struct {
+	// Dir holds one directory per workspace, named by its ID.
+	Dir string
+	// MaxLoaded bounds how many workspaces are loaded at once; the least
+	// recently used one is evicted to make room.
+	MaxLoaded int
+	// IdleTimeout is how long a workspace may go unused before EvictIdle
+	// evicts it. Zero keeps workspaces until they make room for others.
+	IdleTimeout time.Duration
+	// Defaults is the configuration of workspaces without a workspace.yaml.
+	Defaults Config
+}
+
+type Registry struct {
+	opts  Options
+	build Builder
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	loading map[string]*load
+	// retiring holds the evicted workspaces that are still being flushed,
+	// by ID. Each channel is closed once its workspace has been flushed.
+	retiring map[string]chan struct{}
+}
+
+// load is a workspace being opened, which later requests for it wait for
+// instead of opening it again.
+type load struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRegistry returns a registry of the workspaces in opts.Dir, opened with
+// build.
+func NewRegistry(opts Options, build Builder) *Registry {
+	return &Registry{
+		opts:     opts,
+		build:    build,
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		loading:  make(map[string]*load),
+		retiring: make(map[string]chan struct{}),
+	}
+}
+
+// Acquire returns the workspace id, opening it if it is not loaded, and a
+// release function the caller must call once it has finished with it; until
+// then the workspace is not flushed, even if it is evicted. A workspace
+// evicted but not yet flushed is only opened again once it has been, so no
+// write is lost between the two instances. Unknown
+// workspaces are reported as apperrors.ErrNotFound and workspaces that
+// cannot be opened as apperrors.ErrUnavailable.
+func (r *Registry) Acquire(ctx context.Context, id string) (*Workspace, func(), error) {
+	if !idPattern.MatchString(id) {
+		return nil, nil, fmt.Errorf("%w: workspace %q", apperrors.ErrNotFound, id)
+	}
+
+	for {
+		r.mu.Lock()
+		if element, found := r.entries[id]; found {
+			ws := element.Value.(*Workspace)
+			r.lru.MoveToFront(element)
+			ws.lastUsed = r.now()
+			ws.active.Add(1)
+			r.mu.Unlock()
+			return ws, ws.active.Done, nil
+		}
+		if l, found := r.loading[id]; found {
+			r.mu.Unlock()
+			select {
+			case <-l.done:
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+			if l.err != nil {
+				return nil, nil, l.err
+			}
+			continue
+		}
+		if retired, found := r.retiring[id]; found {
+			r.mu.Unlock()
+			select {
+			case <-retired:
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+			continue
+		}
+		l := &load{done: make(chan struct{})}
+		r.loading[id] = l
+		r.mu.Unlock()
+
+		ws, err := r.load(ctx, id, l)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ws, ws.active.Done, nil
+	}
+}
+
+// load opens workspace id on behalf of every request waiting on l and adds
+// it to the loaded workspaces, acquired once for the caller.
+func (r *Registry) load(ctx context.Context, id string, l *load) (ws *Workspace, err error) {
+	defer func() {
+		// A panic while opening must not leave the waiting requests
+		// blocked, so they are released with an error.
+		if ws == nil && err == nil {
+			err = fmt.Errorf("%w: workspace %q could not be opened", apperrors.ErrUnavailable, id)
+		}
+
+		r.mu.Lock()
+		delete(r.loading, id)
+		l.err = err
+		if err == nil {
+			ws.lastUsed = r.now()
+			ws.active.Add(1)
+			r.entries[id] = r.lru.PushFront(ws)
+			for r.lru.Len() > r.opts.MaxLoaded {
+				r.evict(ctx, r.lru.Back(), "capacity")
+			}
+		}
+		r.mu.Unlock()
+		close(l.done)
+	}()
+
+	// Other requests may be waiting for the workspace, so opening it is
+	// not abandoned when this request is.
+	return r.open(context.WithoutCancel(ctx), id)
+}
+
+func (r *Registry) open(ctx context.Context, id string) (*Workspace, error) {
+	dir := filepath.Join(r.opts.Dir, id)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%w: workspace %q", apperrors.ErrNotFound, id)
+	}
+	cfg, err := loadConfig(dir, r.opts.Defaults)
+	if err != nil {
+		return nil, fmt.Errorf("%w: workspace %q: %v", apperrors.ErrUnavailable, id, err)
+	}
+	ws, err := r.build(ctx, id, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: workspace %q: %v", apperrors.ErrUnavailable, id, err)
+	}
+	ws.ID = id
+	ws.Config = cfg
+
+	logging.FromContext(ctx).Info("workspace loaded", slog.String("workspace", id))
+	return ws, nil
+}
+
+// evict removes element's workspace and flushes it once the requests using
+// it have finished. The caller holds r.mu.
+func (r *Registry) evict(ctx context.Context, element *list.Element, reason string) {
+	ws := element.Value.(*Workspace)
+	r.lru.Remove(element)
+	delete(r.entries, ws.ID)
+
+	logger := logging.FromContext(ctx)
+	logger.Info("workspace evicted", slog.String("workspace", ws.ID), slog.String("reason", reason))
+	retired := make(chan struct{})
+	r.retiring[ws.ID] = retired
+	go func() {
+		ws.active.Wait()
+		if err := flush(context.WithoutCancel(ctx), ws); err != nil {
+			logger.Error("failed to flush evicted workspace", slog.String("workspace", ws.ID), slog.Any("error", err))
+		}
+		r.mu.Lock()
+		delete(r.retiring, ws.ID)
+		r.mu.Unlock()
+		close(retired)
+	}()
+}
+
+// EvictIdle evicts workspaces that have not been used for the idle timeout,
+// checking at a fraction of the timeout, until ctx is done. It returns at
+// once if there is no idle timeout.
+func (r *Registry) EvictIdle(ctx context.Context) {
+	if r.opts.IdleTimeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.opts.IdleTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		r.evictIdle(ctx)
+	}
+}
+
+func (r *Registry) evictIdle(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoff := r.now().Add(-r.opts.IdleTimeout)
+	// The list runs from most to least recently used, so the idle
+	// workspaces are at the back.
+	for element := r.lru.Back(); element != nil; element = r.lru.Back() {
+		if element.Value.(*Workspace).lastUsed.After(cutoff) {
+			return
+		}
+		r.evict(ctx, element, "idle")
+	}
+}
+
+// Loaded returns the IDs of the loaded workspaces, most recently used
+// first.
+func (r *Registry) Loaded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, r.lru.Len())
+	for element := r.lru.Front(); element != nil; element = element.Next() {
+		ids = append(ids, element.Value.(*Workspace).ID)
+	}
+	return ids
+}
+
+// Flush persists the pending writes of every loaded workspace, and waits
+// for evicted workspaces to finish flushing.
+func (r *Registry) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	workspaces := make([]*Workspace, 0, r.lru.Len())
+	for element := r.lru.Front(); element != nil; element = element.Next() {
+		workspaces = append(workspaces, element.Value.(*Workspace))
+	}
+	retiring := make([]chan struct{}, 0, len(r.retiring))
+	for _, retired := range r.retiring {
+		retiring = append(retiring, retired)
+	}
+	r.mu.Unlock()
+
+	var errs []error
+	for _, ws := range workspaces {
+		if err := flush(ctx, ws); err != nil {
+			errs = append(errs, fmt.Errorf("workspace %q: %w", ws.ID, err))
+		}
+	}
+
+	for _, retired := range retiring {
+		select {
+		case <-retired:
+		case <-ctx.Done():
+			return errors.Join(append(errs, fmt.Errorf("evicted workspaces: %w", ctx.Err()))...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func flush(ctx context.Context, ws *Workspace) error {
+	return errors.Join(ws.Actions.Flush(ctx), ws.Users.Flush(ctx))
+}
@@ -0,0 +1,378 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"surfe/internal/actiontypes"
+	"surfe/internal/apperrors"
+	"surfe/internal/config"
+	"surfe/internal/models"
+	"surfe/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubUserRepository and stubActionRepository count their flushes.
+type stubUserRepository struct {
+	repository.UserRepository
+	flushes atomic.Int32
+}
+
+func (r *stubUserRepository) Flush(ctx context.Context) error {
+	r.flushes.Add(1)
+	return nil
+}
+
+type stubActionRepository struct {
+	repository.ActionRepository
+	flushes atomic.Int32
+	err     error
+}
+
+func (r *stubActionRepository) Flush(ctx context.Context) error {
+	r.flushes.Add(1)
+	return r.err
+}
+
+// stubBuilder builds workspaces from stub repositories and records the
+// configurations it was given. When release is set, builds block until it
+// is closed.
+type stubBuilder struct {
+	mu      sync.Mutex
+	builds  map[string]int
+	configs map[string]*Config
+	actions map[string]*stubActionRepository
+	release chan struct{}
+	err     error
+}
+
+func newStubBuilder() *stubBuilder {
+	return &stubBuilder{
+		builds:  make(map[string]int),
+		configs: make(map[string]*Config),
+		actions: make(map[string]*stubActionRepository),
+	}
+}
+
+func (b *stubBuilder) build(ctx context.Context, id string, cfg *Config) (*Workspace, error) {
+	if b.release != nil {
+		<-b.release
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.builds[id]++
+	b.configs[id] = cfg
+	if b.err != nil {
+		return nil, b.err
+	}
+	actions := &stubActionRepository{}
+	b.actions[id] = actions
+	return &Workspace{Users: &stubUserRepository{}, Actions: actions}, nil
+}
+
+// workspaceDir creates a workspace directory for each id, with contents as
+// its workspace.yaml when it is not empty.
+func workspaceDir(t *testing.T, workspaces map[string]string) string {
+	dir := t.TempDir()
+	for id, contents := range workspaces {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, id), 0755))
+		if contents != "" {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, id, ConfigFile), []byte(contents), 0644))
+		}
+	}
+	return dir
+}
+
+func newTestRegistry(dir string, maxLoaded int, b *stubBuilder) *Registry {
+	return NewRegistry(Options{
+		Dir:       dir,
+		MaxLoaded: maxLoaded,
+		Defaults:  DefaultConfig(config.Default()),
+	}, b.build)
+}
+
+func TestRegistry_Acquire(t *testing.T) {
+	dir := workspaceDir(t, map[string]string{
+		"acme": "",
+		"globex": `
+data:
+  usersPath: export/users.json
+  actionsPath: /data/globex/actions.json
+  actionTypesPath: types.json
+cache:
+  size: 0
+features:
+  actionRecording: false
+`,
+		"initech":  "cache:\n  size: -1\n",
+		"umbrella": "data:\n  userPath: users.json\n",
+	})
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedConfig *Config
+		expectedError  error
+		expectedDetail string
+	}{
+		{
+			name: "default configuration",
+			id:   "acme",
+			expectedConfig: &Config{
+				Data: DataConfig{
+					UsersPath:   filepath.Join(dir, "acme", "users.json"),
+					ActionsPath: filepath.Join(dir, "acme", "actions.json"),
+				},
+				Cache:    config.CacheConfig{Size: 1024},
+				Features: FeatureConfig{ActionRecording: true},
+			},
+		},
+		{
+			name: "workspace.yaml",
+			id:   "globex",
+			expectedConfig: &Config{
+				Data: DataConfig{
+					UsersPath:       filepath.Join(dir, "globex", "export", "users.json"),
+					ActionsPath:     "/data/globex/actions.json",
+					ActionTypesPath: filepath.Join(dir, "globex", "types.json"),
+				},
+				Cache:    config.CacheConfig{Size: 0},
+				Features: FeatureConfig{ActionRecording: false},
+			},
+		},
+		{
+			name:           "unknown workspace",
+			id:             "hooli",
+			expectedError:  apperrors.ErrNotFound,
+			expectedDetail: `workspace "hooli"`,
+		},
+		{
+			name:           "invalid ID",
+			id:             "../acme",
+			expectedError:  apperrors.ErrNotFound,
+			expectedDetail: `workspace "../acme"`,
+		},
+		{
+			name:           "invalid configuration",
+			id:             "initech",
+			expectedError:  apperrors.ErrUnavailable,
+			expectedDetail: "cache.size: must not be negative, got -1",
+		},
+		{
+			name:           "unknown configuration key",
+			id:             "umbrella",
+			expectedError:  apperrors.ErrUnavailable,
+			expectedDetail: "field userPath not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newStubBuilder()
+			r := newTestRegistry(dir, 4, b)
+
+			ws, release, err := r.Acquire(context.Background(), tt.id)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.ErrorContains(t, err, tt.expectedDetail)
+				assert.Empty(t, r.Loaded())
+				return
+			}
+			require.NoError(t, err)
+			release()
+			assert.Equal(t, tt.id, ws.ID)
+			assert.Equal(t, tt.expectedConfig, ws.Config)
+			assert.Equal(t, tt.expectedConfig, b.configs[tt.id])
+			assert.Equal(t, []string{tt.id}, r.Loaded())
+		})
+	}
+}
+
+func TestRegistry_Acquire_OpensOnce(t *testing.T) {
+	b := newStubBuilder()
+	b.release = make(chan struct{})
+	r := newTestRegistry(workspaceDir(t, map[string]string{"acme": ""}), 4, b)
+
+	var wg sync.WaitGroup
+	workspaces := make([]*Workspace, 8)
+	for i := range workspaces {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ws, release, err := r.Acquire(context.Background(), "acme")
+			assert.NoError(t, err)
+			release()
+			workspaces[i] = ws
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(b.release)
+	wg.Wait()
+
+	assert.Equal(t, 1, b.builds["acme"])
+	for _, ws := range workspaces {
+		assert.Same(t, workspaces[0], ws)
+	}
+}
+
+func TestRegistry_Acquire_BuildError(t *testing.T) {
+	b := newStubBuilder()
+	b.err = errors.New("users dataset not loaded")
+	r := newTestRegistry(workspaceDir(t, map[string]string{"acme": ""}), 4, b)
+
+	for range 2 {
+		_, _, err := r.Acquire(context.Background(), "acme")
+
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+		assert.ErrorContains(t, err, "users dataset not loaded")
+	}
+	// Failures are not kept, so every request tries again.
+	assert.Equal(t, 2, b.builds["acme"])
+	assert.Empty(t, r.Loaded())
+}
+
+func TestRegistry_EvictsLeastRecentlyUsed(t *testing.T) {
+	b := newStubBuilder()
+	r := newTestRegistry(workspaceDir(t, map[string]string{"acme": "", "globex": "", "initech": ""}), 2, b)
+	acquire := func(id string) {
+		_, release, err := r.Acquire(context.Background(), id)
+		require.NoError(t, err)
+		release()
+	}
+
+	acquire("acme")
+	acquire("globex")
+	acquire("acme")
+	acquire("initech")
+	require.NoError(t, r.Flush(context.Background()))
+
+	assert.Equal(t, []string{"initech", "acme"}, r.Loaded())
+	// The evicted workspace was flushed on its way out.
+	assert.Equal(t, int32(1), b.actions["globex"].flushes.Load())
+
+	acquire("globex")
+
+	assert.Equal(t, []string{"globex", "initech"}, r.Loaded())
+	assert.Equal(t, 2, b.builds["globex"])
+}
+
+func TestRegistry_EvictionWaitsForRequests(t *testing.T) {
+	b := newStubBuilder()
+	r := newTestRegistry(workspaceDir(t, map[string]string{"acme": "", "globex": ""}), 1, b)
+
+	_, release, err := r.Acquire(context.Background(), "acme")
+	require.NoError(t, err)
+	_, releaseGlobex, err := r.Acquire(context.Background(), "globex")
+	require.NoError(t, err)
+	releaseGlobex()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Zero(t, b.actions["acme"].flushes.Load(), "flushed while a request was using it")
+
+	release()
+	require.NoError(t, r.Flush(context.Background()))
+	assert.Equal(t, int32(1), b.actions["acme"].flushes.Load())
+}
+
+func TestRegistry_ReopensAfterFlush(t *testing.T) {
+	dir := workspaceDir(t, map[string]string{"acme": "", "globex": ""})
+	for _, id := range []string{"acme", "globex"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, id, "users.json"),
+			[]byte(`[{"id": 1, "name": "Ada", "createdAt": "2024-01-01T00:00:00Z"}]`), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, id, "actions.json"), []byte(`[]`), 0644))
+	}
+	r := NewRegistry(Options{Dir: dir, MaxLoaded: 1, Defaults: DefaultConfig(config.Default())},
+		func(ctx context.Context, id string, cfg *Config) (*Workspace, error) {
+			users, err := repository.NewUserRepository(cfg.Data.UsersPath)
+			if err != nil {
+				return nil, err
+			}
+			actions, err := repository.NewActionRepository(cfg.Data.ActionsPath, actiontypes.Default())
+			if err != nil {
+				return nil, err
+			}
+			return &Workspace{Users: users, Actions: actions}, nil
+		})
+	ctx := context.Background()
+	record := func(ws *Workspace, actionType string) {
+		_, err := ws.Actions.Add(ctx, models.Action{Type: actionType, UserID: 1, CreatedAt: time.Now()})
+		require.NoError(t, err)
+	}
+
+	acme, release, err := r.Acquire(ctx, "acme")
+	require.NoError(t, err)
+	record(acme, "WELCOME")
+	// Loading globex evicts acme, which is flushed once it is released.
+	_, releaseGlobex, err := r.Acquire(ctx, "globex")
+	require.NoError(t, err)
+	releaseGlobex()
+
+	reopened := make(chan *Workspace)
+	go func() {
+		ws, release, err := r.Acquire(ctx, "acme")
+		assert.NoError(t, err)
+		release()
+		reopened <- ws
+	}()
+	time.Sleep(20 * time.Millisecond)
+	release()
+	ws := <-reopened
+
+	require.NotSame(t, acme, ws)
+	record(ws, "CONNECT_CRM")
+	require.NoError(t, r.Flush(ctx))
+
+	actions, err := repository.NewActionRepository(filepath.Join(dir, "acme", "actions.json"), actiontypes.Default())
+	require.NoError(t, err)
+	recorded, err := actions.GetByUserID(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, recorded, 2)
+	assert.Equal(t, "WELCOME", recorded[0].Type)
+	assert.Equal(t, "CONNECT_CRM", recorded[1].Type)
+}
+
+func TestRegistry_EvictIdle(t *testing.T) {
+	b := newStubBuilder()
+	r := newTestRegistry(workspaceDir(t, map[string]string{"acme": "", "globex": ""}), 4, b)
+	r.opts.IdleTimeout = time.Minute
+	now := time.Date(2024, 3, 11, 20, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	for _, id := range []string{"acme", "globex"} {
+		_, release, err := r.Acquire(context.Background(), id)
+		require.NoError(t, err)
+		release()
+		now = now.Add(30 * time.Second)
+	}
+	now = now.Add(15 * time.Second)
+
+	r.evictIdle(context.Background())
+
+	assert.Equal(t, []string{"globex"}, r.Loaded())
+}
+
+func TestRegistry_Flush(t *testing.T) {
+	b := newStubBuilder()
+	r := newTestRegistry(workspaceDir(t, map[string]string{"acme": "", "globex": ""}), 4, b)
+	for _, id := range []string{"acme", "globex"} {
+		_, release, err := r.Acquire(context.Background(), id)
+		require.NoError(t, err)
+		release()
+	}
+	b.actions["globex"].err = errors.New("disk full")
+
+	err := r.Flush(context.Background())
+
+	assert.EqualError(t, err, `workspace "globex": disk full`)
+	assert.Equal(t, int32(1), b.actions["acme"].flushes.Load())
+	assert.Equal(t, []string{"globex", "acme"}, r.Loaded())
+}
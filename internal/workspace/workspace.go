@@ -0,0 +1,128 @@
+// Package workspace serves several isolated datasets from one process. Each
+// workspace is a directory holding its own data files and, optionally, a
+// workspace.yaml configuration. The registry opens a workspace the first
+// time it is used and evicts it again when it is idle or when too many are
+// loaded, flushing pending writes first.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"surfe/internal/config"
+	"surfe/internal/repository"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the name of the optional configuration file in a workspace
+// directory.
+const ConfigFile = "workspace.yaml"
+
+// idPattern keeps workspace IDs to names that are safe as a single path
+// element.
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Config is a workspace's configuration. Relative paths are relative to the
+// workspace directory.
+type Config struct {
+	Data     DataConfig         `yaml:"data"`
+	Cache    config.CacheConfig `yaml:"cache"`
+	Features FeatureConfig      `yaml:"features"`
+}
+
+type DataConfig struct {
+	UsersPath   string `yaml:"usersPath"`
+	ActionsPath string `yaml:"actionsPath"`
+	// ActionTypesPath is optional; the built-in action types are used when
+	// it is empty.
+	ActionTypesPath string `yaml:"actionTypesPath"`
+}
+
+type FeatureConfig struct {
+	ActionRecording bool `yaml:"actionRecording"`
+}
+
+// DefaultConfig returns the configuration of a workspace without a
+// workspace.yaml, taking the cache size and features from the server's
+// configuration.
+func DefaultConfig(server *config.Config) Config {
+	return Config{
+		Data: DataConfig{
+			UsersPath:   "users.json",
+			ActionsPath: "actions.json",
+		},
+		Cache: server.Cache,
+		Features: FeatureConfig{
+			ActionRecording: server.Features.ActionRecording,
+		},
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Data.UsersPath == "" {
+		errs = append(errs, errors.New("data.usersPath: must be set"))
+	}
+	if c.Data.ActionsPath == "" {
+		errs = append(errs, errors.New("data.actionsPath: must be set"))
+	}
+	if c.Cache.Size < 0 {
+		errs = append(errs, fmt.Errorf("cache.size: must not be negative, got %d", c.Cache.Size))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// loadConfig reads dir's workspace.yaml over defaults, if there is one, and
+// resolves the data paths against dir.
+func loadConfig(dir string, defaults Config) (*Config, error) {
+	cfg := defaults
+	file, err := os.Open(filepath.Join(dir, ConfigFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("config file: %v", err)
+	default:
+		defer file.Close()
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("config file %s: %v", file.Name(), err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	for _, path := range []*string{&cfg.Data.UsersPath, &cfg.Data.ActionsPath, &cfg.Data.ActionTypesPath} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	return &cfg, nil
+}
+
+// Workspace is a loaded workspace.
+type Workspace struct {
+	ID      string
+	Config  *Config
+	Users   repository.UserRepository
+	Actions repository.ActionRepository
+	// Handler serves the workspace's API, with the same routes as the
+	// server's own dataset under /api/v1 and /api/v2.
+	Handler http.Handler
+
+	// active counts the requests using the workspace, which are waited for
+	// before an evicted workspace is flushed.
+	active   sync.WaitGroup
+	lastUsed time.Time
+}